@@ -0,0 +1,86 @@
+// Package crypto contains the signature primitives used by transaction processing and precompiles
+package crypto
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/holiman/uint256"
+	"golang.org/x/crypto/sha3"
+)
+
+// Errors
+var (
+	ErrInvalidSignature = errors.New("invalid signature values")
+	ErrRecoveryFailed   = errors.New("public key recovery failed")
+)
+
+var (
+	// secp256k1N is the order of the secp256k1 curve
+	secp256k1N, _ = uint256.FromHex("0xfffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")
+	// secp256k1HalfN is secp256k1N / 2, the upper bound of s since Homestead (EIP-2)
+	secp256k1HalfN = new(uint256.Int).Rsh(secp256k1N, 1)
+)
+
+// Keccak256 returns the legacy Keccak-256 hash of the concatenated inputs
+func Keccak256(data ...[]byte) [32]byte {
+	hasher := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hasher.Write(d)
+	}
+
+	var h [32]byte
+	hasher.Sum(h[:0])
+	return h
+}
+
+// ValidateSignatureValues reports whether r, s and the recovery id v (0 or 1) form a valid
+// signature. With lowS set, s must not exceed half the curve order as required by EIP-2.
+func ValidateSignatureValues(v byte, r, s *uint256.Int, lowS bool) bool {
+	if v > 1 || r.IsZero() || s.IsZero() {
+		return false
+	}
+	if lowS && s.Gt(secp256k1HalfN) {
+		return false
+	}
+	return r.Lt(secp256k1N) && s.Lt(secp256k1N)
+}
+
+// RecoverAddress returns the address of the key that produced the signature (v, r, s) over hash.
+// The caller is responsible for validating the signature values first.
+func RecoverAddress(hash [32]byte, v byte, r, s *uint256.Int) ([20]byte, error) {
+	if v > 1 {
+		return [20]byte{}, ErrInvalidSignature
+	}
+
+	// Compact signature format: <27 + recovery id><32-byte R><32-byte S>
+	var sig [65]byte
+	sig[0] = 27 + v
+	rBytes, sBytes := r.Bytes32(), s.Bytes32()
+	copy(sig[1:33], rBytes[:])
+	copy(sig[33:], sBytes[:])
+
+	pub, _, err := ecdsa.RecoverCompact(sig[:], hash[:])
+	if err != nil {
+		return [20]byte{}, fmt.Errorf("%w: %v", ErrRecoveryFailed, err)
+	}
+	return PubkeyToAddress(pub), nil
+}
+
+// PubkeyToAddress returns the Ethereum address of pub: the last 20 bytes of the Keccak-256
+// hash of its uncompressed encoding without the 0x04 prefix
+func PubkeyToAddress(pub *secp256k1.PublicKey) [20]byte {
+	h := Keccak256(pub.SerializeUncompressed()[1:])
+
+	var addr [20]byte
+	copy(addr[:], h[12:])
+	return addr
+}
+
+// Sign signs hash with key and returns the recovery id together with r and s
+func Sign(hash [32]byte, key *secp256k1.PrivateKey) (v byte, r, s *uint256.Int) {
+	sig := ecdsa.SignCompact(key, hash[:], false)
+	return sig[0] - 27, new(uint256.Int).SetBytes(sig[1:33]), new(uint256.Int).SetBytes(sig[33:])
+}
@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/holiman/uint256"
+)
+
+func TestPubkeyToAddress(t *testing.T) {
+	// Private key 1 controls 0x7e5f4552091a69125d5dfcb7b8c2659029395bdf
+	var one [32]byte
+	one[31] = 1
+	key := secp256k1.PrivKeyFromBytes(one[:])
+
+	addr := PubkeyToAddress(key.PubKey())
+	if got := hex.EncodeToString(addr[:]); got != "7e5f4552091a69125d5dfcb7b8c2659029395bdf" {
+		t.Fatalf("unexpected address %s", got)
+	}
+}
+
+func TestSignAndRecover(t *testing.T) {
+	var seed [32]byte
+	seed[31] = 0x42
+	key := secp256k1.PrivKeyFromBytes(seed[:])
+	hash := Keccak256([]byte("evmdbg"))
+
+	v, r, s := Sign(hash, key)
+	if !ValidateSignatureValues(v, r, s, true) {
+		t.Fatal("signature produced by Sign should be valid")
+	}
+
+	addr, err := RecoverAddress(hash, v, r, s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if addr != PubkeyToAddress(key.PubKey()) {
+		t.Fatalf("recovered %x, want %x", addr, PubkeyToAddress(key.PubKey()))
+	}
+
+	// Flipping the recovery id yields a different key
+	other, err := RecoverAddress(hash, v^1, r, s)
+	if err == nil && other == addr {
+		t.Fatal("wrong recovery id should not recover the signer")
+	}
+}
+
+func TestValidateSignatureValues(t *testing.T) {
+	one := uint256.NewInt(1)
+	highS := new(uint256.Int).AddUint64(secp256k1HalfN, 1)
+
+	tests := []struct {
+		name  string
+		v     byte
+		r, s  *uint256.Int
+		lowS  bool
+		valid bool
+	}{
+		{"minimal", 0, one, one, true, true},
+		{"recovery id 2", 2, one, one, false, false},
+		{"zero r", 1, uint256.NewInt(0), one, false, false},
+		{"zero s", 1, one, uint256.NewInt(0), false, false},
+		{"r equals N", 0, secp256k1N, one, false, false},
+		{"high s allowed", 0, one, highS, false, true},
+		{"high s rejected", 0, one, highS, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateSignatureValues(tt.v, tt.r, tt.s, tt.lowS); got != tt.valid {
+				t.Fatalf("got %v, want %v", got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestRecoverAddressInvalid(t *testing.T) {
+	hash := Keccak256([]byte("evmdbg"))
+	if _, err := RecoverAddress(hash, 0, uint256.NewInt(0), uint256.NewInt(1)); !errors.Is(err, ErrRecoveryFailed) {
+		t.Fatalf("expected ErrRecoveryFailed, got %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+)
+
+// P256Verify reports whether (r, s) is a valid secp256r1 signature of hash by the public key
+// (x, y). Public keys that are not on the curve, including the point at infinity, are rejected.
+func P256Verify(hash []byte, r, s, x, y *big.Int) bool {
+	curve := elliptic.P256()
+	if x.Cmp(curve.Params().P) >= 0 || y.Cmp(curve.Params().P) >= 0 || !curve.IsOnCurve(x, y) {
+		return false
+	}
+	pub := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	return ecdsa.Verify(pub, hash, r, s)
+}
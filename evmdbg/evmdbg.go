@@ -9,3 +9,23 @@ func CreateDebuggerVM(code []byte) *vm.DebuggerVM {
 	d := vm.NewDebuggerVM(code, opcode_handlers.GetHandler)
 	return d
 }
+
+// CreateSession creates a session executing txs in order against the given state
+func CreateSession(sp vm.StateProvider, block *vm.BlockContext, txs []vm.Transaction) *vm.Session {
+	return vm.NewSession(sp, block, opcode_handlers.GetHandler, txs)
+}
+
+// CreateDebuggerVMForFork creates a debugger that executes code under the rules of fork
+func CreateDebuggerVMForFork(code []byte, fork vm.Fork) *vm.DebuggerVM {
+	d := vm.NewDebuggerVM(code, opcode_handlers.GetHandlerForFork(fork))
+	d.ChainConfig = vm.ChainConfigForFork(fork)
+	return d
+}
+
+// CreateSessionWithConfig creates a session that executes txs under the rules config
+// activates for block
+func CreateSessionWithConfig(config *vm.ChainConfig, sp vm.StateProvider, block *vm.BlockContext, txs []vm.Transaction) *vm.Session {
+	s := vm.NewSession(sp, block, opcode_handlers.GetHandlerForFork(vm.ForkAt(config, block)), txs)
+	s.VM.ChainConfig = config
+	return s
+}
@@ -3,8 +3,15 @@ module github.com/daniellehrner/evmdbg
 go 1.24.5
 
 require (
+	github.com/consensys/gnark-crypto v0.18.1
+	github.com/crate-crypto/go-eth-kzg v1.5.0
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.1
 	github.com/holiman/uint256 v1.3.2
 	golang.org/x/crypto v0.40.0
 )
 
-require golang.org/x/sys v0.34.0 // indirect
+require (
+	github.com/bits-and-blooms/bitset v1.20.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+	golang.org/x/sys v0.34.0 // indirect
+)
@@ -0,0 +1,81 @@
+package optimism
+
+// FastLZCompressLen returns the length of data after FastLZ compression, as used by the Fjord
+// L1 data fee. It is a port of the op-geth implementation, which follows the FastLZ level 1
+// algorithm of Solady.
+func FastLZCompressLen(data []byte) uint32 {
+	n := uint32(0)
+	ht := make([]uint32, 8192)
+
+	u24 := func(i uint32) uint32 {
+		return uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16
+	}
+	cmp := func(p, q, e uint32) uint32 {
+		l := uint32(0)
+		for e -= q; l < e; l++ {
+			if data[p+l] != data[q+l] {
+				e = 0
+			}
+		}
+		return l
+	}
+	literals := func(r uint32) {
+		n += 0x21 * (r / 0x20)
+		r %= 0x20
+		if r != 0 {
+			n += r + 1
+		}
+	}
+	match := func(l uint32) {
+		l--
+		n += 3 * (l / 262)
+		if l%262 >= 6 {
+			n += 3
+		} else {
+			n += 2
+		}
+	}
+	hash := func(v uint32) uint32 {
+		return ((2654435769 * v) >> 19) & 0x1fff
+	}
+	setNextHash := func(ip uint32) uint32 {
+		ht[hash(u24(ip))] = ip
+		return ip + 1
+	}
+
+	a := uint32(0)
+	ipLimit := uint32(0)
+	if len(data) >= 13 {
+		ipLimit = uint32(len(data)) - 13
+	}
+	for ip := a + 2; ip < ipLimit; {
+		var r, d uint32
+		for {
+			s := u24(ip)
+			h := hash(s)
+			r = ht[h]
+			ht[h] = ip
+			d = ip - r
+			if ip >= ipLimit {
+				break
+			}
+			ip++
+			if d <= 0x1fff && s == u24(r) {
+				break
+			}
+		}
+		if ip >= ipLimit {
+			break
+		}
+		ip--
+		if ip > a {
+			literals(ip - a)
+		}
+		l := cmp(r+3, ip+3, ipLimit+9)
+		match(l)
+		ip = setNextHash(setNextHash(ip + l))
+		a = ip
+	}
+	literals(uint32(len(data)) - a)
+	return n
+}
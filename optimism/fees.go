@@ -0,0 +1,154 @@
+package optimism
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// Fjord L1 data fee parameters, scaled by 1e6
+var (
+	l1CostIntercept          = big.NewInt(-42_585_600)
+	l1CostFastLZCoef         = big.NewInt(836_500)
+	minTransactionSizeScaled = big.NewInt(100_000_000)
+)
+
+// RollupCostData summarizes an encoded transaction for the L1 data fee
+type RollupCostData struct {
+	Zeroes, Ones uint64
+	FastLZSize   uint64
+}
+
+// NewRollupCostData returns the cost data of an encoded transaction
+func NewRollupCostData(encoded []byte) RollupCostData {
+	var cd RollupCostData
+	for _, b := range encoded {
+		if b == 0 {
+			cd.Zeroes++
+		} else {
+			cd.Ones++
+		}
+	}
+	cd.FastLZSize = uint64(FastLZCompressLen(encoded))
+	return cd
+}
+
+// calldataGas returns the L1 calldata gas of the transaction
+func (cd RollupCostData) calldataGas() uint64 {
+	return cd.Zeroes*4 + cd.Ones*16
+}
+
+// L1FeeParams holds the fee parameters stored in the L1Block predeploy
+type L1FeeParams struct {
+	L1BaseFee     *uint256.Int
+	L1BlobBaseFee *uint256.Int
+
+	// Bedrock
+	Overhead *uint256.Int
+	Scalar   *uint256.Int
+
+	// Ecotone
+	BaseFeeScalar     uint32
+	BlobBaseFeeScalar uint32
+
+	// Isthmus
+	OperatorFeeScalar   uint32
+	OperatorFeeConstant uint64
+}
+
+// ReadL1FeeParams reads the fee parameters from the storage of the L1Block predeploy
+func ReadL1FeeParams(sp vm.StateProvider) *L1FeeParams {
+	slot := func(n uint64) [32]byte {
+		return sp.GetStorage(L1BlockAddress, uint256.NewInt(n)).Bytes32()
+	}
+	scalars := slot(L1FeeScalarsSlot)
+	operator := slot(OperatorFeeParamsSlot)
+
+	return &L1FeeParams{
+		L1BaseFee:           sp.GetStorage(L1BlockAddress, uint256.NewInt(L1BaseFeeSlot)),
+		L1BlobBaseFee:       sp.GetStorage(L1BlockAddress, uint256.NewInt(L1BlobBaseFeeSlot)),
+		Overhead:            sp.GetStorage(L1BlockAddress, uint256.NewInt(OverheadSlot)),
+		Scalar:              sp.GetStorage(L1BlockAddress, uint256.NewInt(ScalarSlot)),
+		BaseFeeScalar:       binary.BigEndian.Uint32(scalars[16:20]),
+		BlobBaseFeeScalar:   binary.BigEndian.Uint32(scalars[20:24]),
+		OperatorFeeScalar:   binary.BigEndian.Uint32(operator[20:24]),
+		OperatorFeeConstant: binary.BigEndian.Uint64(operator[24:32]),
+	}
+}
+
+// L1Cost returns the L1 data fee of a transaction at the given block time and the L1 gas
+// it is charged for
+func (c *Config) L1Cost(p *L1FeeParams, time uint64, cd RollupCostData) (*uint256.Int, uint64) {
+	if cd == (RollupCostData{}) {
+		return new(uint256.Int), 0
+	}
+
+	// The first Ecotone block still uses the Bedrock formula, the L1Block predeploy only
+	// stores the Ecotone parameters once it was upgraded
+	ecotoneParamsSet := !p.L1BlobBaseFee.IsZero() || p.BaseFeeScalar != 0 || p.BlobBaseFeeScalar != 0
+	if !c.IsEcotone(time) || !ecotoneParamsSet {
+		return c.bedrockL1Cost(p, time, cd)
+	}
+
+	// Cost of a compressed byte, scaled by 1e6: 16*baseFeeScalar*l1BaseFee + blobBaseFeeScalar*l1BlobBaseFee
+	perByte := new(big.Int).Mul(p.L1BaseFee.ToBig(), big.NewInt(16*int64(p.BaseFeeScalar)))
+	perByte.Add(perByte, new(big.Int).Mul(p.L1BlobBaseFee.ToBig(), big.NewInt(int64(p.BlobBaseFeeScalar))))
+
+	if !c.IsFjord(time) {
+		// The calldata gas divided by 16 estimates the compressed size
+		gas := cd.calldataGas()
+		fee := perByte.Mul(perByte, new(big.Int).SetUint64(gas))
+		fee.Div(fee, big.NewInt(16_000_000))
+		return toUint256(fee), gas
+	}
+
+	// Fjord estimates the compressed size from the FastLZ size by linear regression
+	size := new(big.Int).Mul(l1CostFastLZCoef, new(big.Int).SetUint64(cd.FastLZSize))
+	size.Add(size, l1CostIntercept)
+	if size.Cmp(minTransactionSizeScaled) < 0 {
+		size.Set(minTransactionSizeScaled)
+	}
+	fee := new(big.Int).Mul(size, perByte)
+	fee.Div(fee, big.NewInt(1_000_000_000_000))
+	gas := new(big.Int).Mul(size, big.NewInt(16))
+	gas.Div(gas, big.NewInt(1_000_000))
+	return toUint256(fee), gas.Uint64()
+}
+
+// bedrockL1Cost returns (calldataGas + overhead) * l1BaseFee * scalar / 1e6. Before Regolith
+// the calldata gas includes 68 non-zero bytes for the signature.
+func (c *Config) bedrockL1Cost(p *L1FeeParams, time uint64, cd RollupCostData) (*uint256.Int, uint64) {
+	gas := new(big.Int).SetUint64(cd.calldataGas())
+	if !c.IsRegolith(time) {
+		gas.Add(gas, big.NewInt(68*16))
+	}
+	gas.Add(gas, p.Overhead.ToBig())
+
+	fee := new(big.Int).Mul(gas, p.L1BaseFee.ToBig())
+	fee.Mul(fee, p.Scalar.ToBig())
+	fee.Div(fee, big.NewInt(1_000_000))
+	return toUint256(fee), gas.Uint64()
+}
+
+// OperatorCost returns the operator fee for gasUsed: gasUsed * operatorFeeScalar / 1e6 +
+// operatorFeeConstant. It is zero before Isthmus.
+func (c *Config) OperatorCost(p *L1FeeParams, time uint64, gasUsed uint64) *uint256.Int {
+	if !c.IsIsthmus(time) {
+		return new(uint256.Int)
+	}
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(gasUsed), big.NewInt(int64(p.OperatorFeeScalar)))
+	fee.Div(fee, big.NewInt(1_000_000))
+	fee.Add(fee, new(big.Int).SetUint64(p.OperatorFeeConstant))
+	return toUint256(fee)
+}
+
+// toUint256 converts a fee, saturating on overflow
+func toUint256(b *big.Int) *uint256.Int {
+	v, overflow := uint256.FromBig(b)
+	if overflow {
+		return new(uint256.Int).SetAllOne()
+	}
+	return v
+}
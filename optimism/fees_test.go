@@ -0,0 +1,151 @@
+package optimism
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/holiman/uint256"
+)
+
+// emptyTx is the encoding of an unsigned legacy transaction to
+// 0x095e7baea6a6c7c4c2dfeb977efac326af552d87 with all other fields zero
+var emptyTx, _ = hex.DecodeString("dd80808094095e7baea6a6c7c4c2dfeb977efac326af552d878080808080")
+
+func newUint64(v uint64) *uint64 {
+	return &v
+}
+
+// testParams returns the fee parameters of the op-geth cost function tests
+func testParams() *L1FeeParams {
+	return &L1FeeParams{
+		L1BaseFee:           uint256.NewInt(1000 * 1e6),
+		L1BlobBaseFee:       uint256.NewInt(10 * 1e6),
+		Overhead:            uint256.NewInt(50),
+		Scalar:              uint256.NewInt(7 * 1e6),
+		BaseFeeScalar:       2,
+		BlobBaseFeeScalar:   3,
+		OperatorFeeScalar:   1439103868,
+		OperatorFeeConstant: 1256417826609331460,
+	}
+}
+
+func TestFastLZCompressLen(t *testing.T) {
+	// https://optimistic.etherscan.io/tx/0x8eb9dd4eb6d33f4dc25fb015919e4b1e9f7542f9b0322bf6622e268cd116b594
+	contractCallTx, _ := hex.DecodeString("02f901550a758302df1483be21b88304743f94f8" +
+		"0e51afb613d764fa61751affd3313c190a86bb870151bd62fd12adb8" +
+		"e41ef24f3f0000000000000000000000000000000000000000000000" +
+		"00000000000000006e000000000000000000000000af88d065e77c8c" +
+		"c2239327c5edb3a432268e5831000000000000000000000000000000" +
+		"000000000000000000000000000003c1e50000000000000000000000" +
+		"00000000000000000000000000000000000000000000000000000000" +
+		"000000000000000000000000000000000000000000000000a0000000" +
+		"00000000000000000000000000000000000000000000000000000000" +
+		"148c89ed219d02f1a5be012c689b4f5b731827bebe00000000000000" +
+		"0000000000c001a033fd89cb37c31b2cba46b6466e040c61fc9b2a36" +
+		"75a7f5f493ebd5ad77c497f8a07cdf65680e238392693019b4092f61" +
+		"0222e71b7cec06449cb922b93b6a12744e")
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected uint32
+	}{
+		{"empty", nil, 0},
+		{"ones", bytes.Repeat([]byte{1}, 1000), 21},
+		{"zeroes", make([]byte, 1000), 21},
+		{"empty tx", emptyTx, 31},
+		{"contract call tx", contractCallTx, 202},
+	}
+	for _, tt := range tests {
+		if got := FastLZCompressLen(tt.input); got != tt.expected {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestL1Cost(t *testing.T) {
+	bedrock := &Config{}
+	regolith := &Config{RegolithTime: newUint64(0)}
+	ecotone := &Config{RegolithTime: newUint64(0), EcotoneTime: newUint64(0)}
+	fjord := &Config{RegolithTime: newUint64(0), EcotoneTime: newUint64(0), FjordTime: newUint64(0)}
+
+	parity := &L1FeeParams{
+		L1BaseFee:         uint256.NewInt(2 * 1e6),
+		L1BlobBaseFee:     uint256.NewInt(3 * 1e6),
+		BaseFeeScalar:     20,
+		BlobBaseFeeScalar: 15,
+	}
+
+	tests := []struct {
+		name   string
+		config *Config
+		params *L1FeeParams
+		cost   RollupCostData
+		fee    uint64
+		gas    uint64
+	}{
+		{"bedrock", bedrock, testParams(), NewRollupCostData(emptyTx), 11326000000000, 1618},
+		{"regolith", regolith, testParams(), NewRollupCostData(emptyTx), 3710000000000, 530},
+		{"ecotone", ecotone, testParams(), NewRollupCostData(emptyTx), 960900, 480},
+		// Below the minimum size of the Fjord regression
+		{"fjord minimum", fjord, testParams(), RollupCostData{FastLZSize: 170}, 3203000, 1600},
+		{"fjord", fjord, parity, RollupCostData{FastLZSize: 235}, 105484, 2463},
+		{"no cost data", fjord, testParams(), RollupCostData{}, 0, 0},
+	}
+	for _, tt := range tests {
+		fee, gas := tt.config.L1Cost(tt.params, 0, tt.cost)
+		if fee.Uint64() != tt.fee || gas != tt.gas {
+			t.Errorf("%s: expected fee %d and gas %d, got %d and %d", tt.name, tt.fee, tt.gas, fee.Uint64(), gas)
+		}
+	}
+
+	// The first Ecotone block uses the Bedrock formula until the L1Block predeploy is upgraded
+	unset := testParams()
+	unset.L1BlobBaseFee = new(uint256.Int)
+	unset.BaseFeeScalar, unset.BlobBaseFeeScalar = 0, 0
+	if fee, _ := ecotone.L1Cost(unset, 0, NewRollupCostData(emptyTx)); fee.Uint64() != 3710000000000 {
+		t.Errorf("expected the Bedrock fee in the first Ecotone block, got %d", fee.Uint64())
+	}
+}
+
+func TestOperatorCost(t *testing.T) {
+	isthmus := &Config{IsthmusTime: newUint64(100)}
+
+	if fee := isthmus.OperatorCost(testParams(), 99, 1618); !fee.IsZero() {
+		t.Fatalf("expected no operator fee before Isthmus, got %d", fee)
+	}
+	if fee := isthmus.OperatorCost(testParams(), 100, 1618); fee.Uint64() != 1256417826611659930 {
+		t.Fatalf("expected operator fee 1256417826611659930, got %d", fee)
+	}
+}
+
+func TestReadL1FeeParams(t *testing.T) {
+	sp := state.NewMemoryState()
+	set := func(slot uint64, value []byte) {
+		sp.SetStorage(L1BlockAddress, uint256.NewInt(slot), new(uint256.Int).SetBytes(value))
+	}
+
+	var scalars, operator [32]byte
+	scalars[19] = 0x02 // baseFeeScalar
+	scalars[23] = 0x03 // blobBaseFeeScalar
+	scalars[31] = 0x07 // sequence number
+	operator[23] = 0x04
+	operator[31] = 0x05
+	set(L1BaseFeeSlot, []byte{0x10})
+	set(L1FeeScalarsSlot, scalars[:])
+	set(L1BlobBaseFeeSlot, []byte{0x20})
+	set(OperatorFeeParamsSlot, operator[:])
+
+	p := ReadL1FeeParams(sp)
+	if p.L1BaseFee.Uint64() != 0x10 || p.L1BlobBaseFee.Uint64() != 0x20 {
+		t.Fatalf("unexpected base fees %d %d", p.L1BaseFee, p.L1BlobBaseFee)
+	}
+	if p.BaseFeeScalar != 2 || p.BlobBaseFeeScalar != 3 {
+		t.Fatalf("unexpected scalars %d %d", p.BaseFeeScalar, p.BlobBaseFeeScalar)
+	}
+	if p.OperatorFeeScalar != 4 || p.OperatorFeeConstant != 5 {
+		t.Fatalf("unexpected operator fee parameters %d %d", p.OperatorFeeScalar, p.OperatorFeeConstant)
+	}
+}
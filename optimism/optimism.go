@@ -0,0 +1,57 @@
+// Package optimism implements the OP Stack transaction and fee rules on top of vm.ChainRules:
+// deposit transactions and the L1 data and operator fees charged to other transactions.
+package optimism
+
+// DepositTxType is the EIP-2718 type of deposit transactions
+const DepositTxType = 0x7e
+
+// Predeploy addresses
+var (
+	L1BlockAddress          = [20]byte{0: 0x42, 19: 0x15}
+	L1FeeVaultAddress       = [20]byte{0: 0x42, 19: 0x1a}
+	OperatorFeeVaultAddress = [20]byte{0: 0x42, 19: 0x1b}
+)
+
+// Storage slots of the L1Block predeploy holding the fee parameters
+const (
+	L1BaseFeeSlot         = 1
+	L1FeeScalarsSlot      = 3 // Ecotone: baseFeeScalar in bytes [16:20], blobBaseFeeScalar in bytes [20:24]
+	OverheadSlot          = 5 // Bedrock
+	ScalarSlot            = 6 // Bedrock
+	L1BlobBaseFeeSlot     = 7
+	OperatorFeeParamsSlot = 8 // Isthmus: scalar in bytes [20:24], constant in bytes [24:32]
+)
+
+// Config holds the activation timestamps of the OP Stack upgrades that change transaction
+// and fee semantics. Bedrock is always active, a nil timestamp means the upgrade is not
+// scheduled.
+type Config struct {
+	RegolithTime *uint64
+	EcotoneTime  *uint64
+	FjordTime    *uint64
+	IsthmusTime  *uint64
+}
+
+func isActive(activation *uint64, time uint64) bool {
+	return activation != nil && *activation <= time
+}
+
+// IsRegolith reports whether Regolith is active at time
+func (c *Config) IsRegolith(time uint64) bool {
+	return isActive(c.RegolithTime, time)
+}
+
+// IsEcotone reports whether Ecotone is active at time
+func (c *Config) IsEcotone(time uint64) bool {
+	return isActive(c.EcotoneTime, time)
+}
+
+// IsFjord reports whether Fjord is active at time
+func (c *Config) IsFjord(time uint64) bool {
+	return isActive(c.FjordTime, time)
+}
+
+// IsIsthmus reports whether Isthmus is active at time
+func (c *Config) IsIsthmus(time uint64) bool {
+	return isActive(c.IsthmusTime, time)
+}
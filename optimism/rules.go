@@ -0,0 +1,175 @@
+package optimism
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// Errors
+var (
+	ErrSystemTxNotSupported     = errors.New("system transactions are not supported since Regolith")
+	ErrInsufficientFundsForFees = errors.New("insufficient funds for value, L1 data fee and operator fee")
+	ErrGasNotMetered            = errors.New("OP Stack rules require a gas schedule")
+)
+
+// Receipt holds the OP Stack receipt fields of a transaction, stored in
+// vm.TransactionResult.Extra
+type Receipt struct {
+	// DepositNonce is the sender nonce of a deposit transaction, nil for other transactions
+	DepositNonce *uint64
+
+	// L1 data fee and operator fee of other transactions
+	L1GasPrice          *uint256.Int
+	L1BlobBaseFee       *uint256.Int
+	L1GasUsed           uint64
+	L1Fee               *uint256.Int
+	L1BaseFeeScalar     uint32
+	L1BlobBaseFeeScalar uint32
+	OperatorFeeScalar   uint32
+	OperatorFeeConstant uint64
+	OperatorFee         *uint256.Int
+}
+
+// rules holds the state of the transaction being executed between the hooks
+type rules struct {
+	config *Config
+
+	nonce   uint64 // sender nonce before the transaction
+	params  *L1FeeParams
+	cost    RollupCostData
+	upfront *uint256.Int // fees charged before the transaction executes
+	invalid bool         // rejected by beforeTransaction
+}
+
+// NewChainRules returns chain rules implementing OP Stack deposit transactions and charging
+// the L1 data fee and the operator fee to the sender of other transactions. The fees are
+// computed from the L1Block predeploy and credited to the fee vaults. Fees paid for L2 gas
+// are not charged, as for Ethereum transactions. The operator fee and the gas used by
+// deposits depend on the metered gas, so transactions fail with ErrGasNotMetered unless the
+// VM has a GasSchedule.
+func NewChainRules(config *Config) *vm.ChainRules {
+	r := &rules{config: config}
+	return &vm.ChainRules{
+		Name:              "optimism",
+		BeforeTransaction: r.beforeTransaction,
+		AfterTransaction:  r.afterTransaction,
+	}
+}
+
+func blockTime(s *vm.Session) uint64 {
+	if s.Block == nil {
+		return 0
+	}
+	return s.Block.Timestamp
+}
+
+// beforeTransaction mints the deposited value of deposits and checks that other
+// transactions can pay their fees
+func (r *rules) beforeTransaction(s *vm.Session, tx *vm.Transaction) error {
+	sp := s.VM.StateProvider
+	if sp == nil {
+		return vm.ErrNoStateProvider
+	}
+	time := blockTime(s)
+	r.nonce = sp.GetNonce(tx.From)
+	r.upfront = nil
+	r.invalid = true
+	if s.VM.GasSchedule == nil {
+		return ErrGasNotMetered
+	}
+
+	if tx.Type == DepositTxType {
+		if tx.IsSystemTx && r.config.IsRegolith(time) {
+			return ErrSystemTxNotSupported
+		}
+		// The mint survives a failing deposit
+		if tx.Mint != nil && !tx.Mint.IsZero() {
+			s.VM.SetBalance(tx.From, new(uint256.Int).Add(sp.GetBalance(tx.From), tx.Mint))
+		}
+		r.invalid = false
+		return nil
+	}
+
+	r.params = ReadL1FeeParams(sp)
+	r.cost = NewRollupCostData(tx.Encoded)
+
+	// The fees for the full gas limit are charged up front, the unused part of the
+	// operator fee is refunded afterwards
+	l1Fee, _ := r.config.L1Cost(r.params, time, r.cost)
+	r.upfront = new(uint256.Int).Add(l1Fee, r.config.OperatorCost(r.params, time, tx.Gas))
+	required := new(uint256.Int).Set(r.upfront)
+	if tx.Value != nil {
+		required.Add(required, tx.Value)
+	}
+	balance := sp.GetBalance(tx.From)
+	if balance.Cmp(required) < 0 {
+		return fmt.Errorf("%w: have %s, want %s", ErrInsufficientFundsForFees, balance, required)
+	}
+	s.VM.SetBalance(tx.From, new(uint256.Int).Sub(balance, r.upfront))
+	r.invalid = false
+	return nil
+}
+
+// afterTransaction settles failed deposits and charges the fees of other transactions
+func (r *rules) afterTransaction(s *vm.Session, tx *vm.Transaction, result *vm.TransactionResult) {
+	sp := s.VM.StateProvider
+	if sp == nil || r.invalid {
+		return
+	}
+	time := blockTime(s)
+	// The session increments the nonce once the transaction passed validation
+	included := sp.GetNonce(tx.From) != r.nonce
+
+	if tx.Type == DepositTxType {
+		depositNonce := r.nonce
+		result.Extra = &Receipt{DepositNonce: &depositNonce}
+
+		switch {
+		case !included:
+			// Deposits cannot be skipped: a deposit failing validation still increments the
+			// nonce and uses all of its gas
+			s.VM.SetNonce(tx.From, r.nonce+1)
+			result.GasUsed = tx.Gas
+			if tx.IsSystemTx && !r.config.IsRegolith(time) {
+				result.GasUsed = 0
+			}
+		case !r.config.IsRegolith(time):
+			// Before Regolith deposits use all of their gas, system transactions none
+			result.GasUsed = tx.Gas
+			if tx.IsSystemTx {
+				result.GasUsed = 0
+			}
+		}
+		return
+	}
+
+	if !included {
+		// The transaction failed validation, it pays no fees
+		s.VM.SetBalance(tx.From, new(uint256.Int).Add(sp.GetBalance(tx.From), r.upfront))
+		return
+	}
+
+	l1Fee, l1GasUsed := r.config.L1Cost(r.params, time, r.cost)
+	operatorFee := r.config.OperatorCost(r.params, time, result.GasUsed)
+
+	refund := new(uint256.Int).Sub(r.upfront, l1Fee)
+	refund.Sub(refund, operatorFee)
+	s.VM.SetBalance(tx.From, new(uint256.Int).Add(sp.GetBalance(tx.From), refund))
+	s.VM.SetBalance(L1FeeVaultAddress, new(uint256.Int).Add(sp.GetBalance(L1FeeVaultAddress), l1Fee))
+	s.VM.SetBalance(OperatorFeeVaultAddress, new(uint256.Int).Add(sp.GetBalance(OperatorFeeVaultAddress), operatorFee))
+
+	result.Extra = &Receipt{
+		L1GasPrice:          r.params.L1BaseFee,
+		L1BlobBaseFee:       r.params.L1BlobBaseFee,
+		L1GasUsed:           l1GasUsed,
+		L1Fee:               l1Fee,
+		L1BaseFeeScalar:     r.params.BaseFeeScalar,
+		L1BlobBaseFeeScalar: r.params.BlobBaseFeeScalar,
+		OperatorFeeScalar:   r.params.OperatorFeeScalar,
+		OperatorFeeConstant: r.params.OperatorFeeConstant,
+		OperatorFee:         operatorFee,
+	}
+}
@@ -0,0 +1,154 @@
+package optimism
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/daniellehrner/evmdbg/vm/opcode_handlers"
+	"github.com/holiman/uint256"
+)
+
+func TestChainRulesDeposits(t *testing.T) {
+	sp := state.NewMemoryState()
+	depositor := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+
+	txs := []vm.Transaction{
+		{Type: DepositTxType, From: depositor, To: &target, Mint: uint256.NewInt(1000), Value: uint256.NewInt(400), Gas: 100_000},
+		// The value exceeds the balance after the mint, the deposit fails but is included
+		{Type: DepositTxType, From: depositor, To: &target, Mint: uint256.NewInt(50), Value: uint256.NewInt(10_000), Gas: 100_000},
+	}
+	config := &Config{RegolithTime: newUint64(0)}
+	s := vm.NewSession(sp, &vm.BlockContext{Timestamp: 1000}, opcode_handlers.GetHandler, txs)
+	s.VM.ChainRules = NewChainRules(config)
+	s.VM.GasSchedule = vm.LondonGasSchedule
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	if s.Results[0].Failed() {
+		t.Fatalf("expected the first deposit to succeed, got %v", s.Results[0].Err)
+	}
+	if s.Results[0].GasUsed != 21000 {
+		t.Fatalf("expected the first deposit to use 21000 gas, got %d", s.Results[0].GasUsed)
+	}
+	if !errors.Is(s.Results[1].Err, vm.ErrInsufficientBalance) {
+		t.Fatalf("expected the second deposit to fail, got %v", s.Results[1].Err)
+	}
+	if s.Results[1].GasUsed != 100_000 {
+		t.Fatalf("expected a failed deposit to use all of its gas, got %d", s.Results[1].GasUsed)
+	}
+
+	if got := sp.GetBalance(depositor).Uint64(); got != 650 {
+		t.Fatalf("expected depositor balance 650, got %d", got)
+	}
+	if got := sp.GetBalance(target).Uint64(); got != 400 {
+		t.Fatalf("expected target balance 400, got %d", got)
+	}
+	if got := sp.GetNonce(depositor); got != 2 {
+		t.Fatalf("expected depositor nonce 2, got %d", got)
+	}
+	receipt, ok := s.Results[1].Extra.(*Receipt)
+	if !ok || receipt.DepositNonce == nil || *receipt.DepositNonce != 1 {
+		t.Fatalf("expected deposit nonce 1 in the receipt, got %+v", s.Results[1].Extra)
+	}
+}
+
+func TestChainRulesL1AndOperatorFees(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	sp.AddAccount(sender, nil, uint256.NewInt(10_000_000))
+
+	// Ecotone scalars 2 and 3, an operator fee of one wei per gas plus 100
+	var scalars, operator [32]byte
+	binary.BigEndian.PutUint32(scalars[16:20], 2)
+	binary.BigEndian.PutUint32(scalars[20:24], 3)
+	binary.BigEndian.PutUint32(operator[20:24], 1_000_000)
+	binary.BigEndian.PutUint64(operator[24:32], 100)
+	sp.SetStorage(L1BlockAddress, uint256.NewInt(L1BaseFeeSlot), uint256.NewInt(1000*1e6))
+	sp.SetStorage(L1BlockAddress, uint256.NewInt(L1BlobBaseFeeSlot), uint256.NewInt(10*1e6))
+	sp.SetStorage(L1BlockAddress, uint256.NewInt(L1FeeScalarsSlot), new(uint256.Int).SetBytes(scalars[:]))
+	sp.SetStorage(L1BlockAddress, uint256.NewInt(OperatorFeeParamsSlot), new(uint256.Int).SetBytes(operator[:]))
+
+	txs := []vm.Transaction{
+		{From: sender, To: &target, Value: uint256.NewInt(1), Gas: 30_000, Encoded: emptyTx},
+		// Cannot pay the L1 fee for its data
+		{From: sender, To: &target, Gas: 30_000, Encoded: make([]byte, 100_000)},
+	}
+	config := &Config{
+		RegolithTime: newUint64(0),
+		EcotoneTime:  newUint64(0),
+		FjordTime:    newUint64(0),
+		IsthmusTime:  newUint64(0),
+	}
+	s := vm.NewSession(sp, &vm.BlockContext{Timestamp: 1000}, opcode_handlers.GetHandler, txs)
+	s.VM.ChainRules = NewChainRules(config)
+	s.VM.GasSchedule = vm.LondonGasSchedule
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	if s.Results[0].Failed() {
+		t.Fatalf("expected the transaction to succeed, got %v", s.Results[0].Err)
+	}
+	receipt, ok := s.Results[0].Extra.(*Receipt)
+	if !ok {
+		t.Fatalf("expected an OP Stack receipt, got %T", s.Results[0].Extra)
+	}
+	// The L1 fee of the minimum Fjord size of 100 bytes
+	if receipt.L1Fee.Uint64() != 3203000 || receipt.L1GasUsed != 1600 {
+		t.Fatalf("expected L1 fee 3203000 for 1600 gas, got %d for %d", receipt.L1Fee, receipt.L1GasUsed)
+	}
+	if receipt.OperatorFee.Uint64() != 21100 {
+		t.Fatalf("expected operator fee 21100, got %d", receipt.OperatorFee)
+	}
+
+	if got := sp.GetBalance(sender).Uint64(); got != 10_000_000-1-3203000-21100 {
+		t.Fatalf("expected the fees to be charged to the sender, balance %d", got)
+	}
+	if got := sp.GetBalance(L1FeeVaultAddress).Uint64(); got != 3203000 {
+		t.Fatalf("expected L1 fee vault balance 3203000, got %d", got)
+	}
+	if got := sp.GetBalance(OperatorFeeVaultAddress).Uint64(); got != 21100 {
+		t.Fatalf("expected operator fee vault balance 21100, got %d", got)
+	}
+
+	if !errors.Is(s.Results[1].Err, ErrInsufficientFundsForFees) {
+		t.Fatalf("expected ErrInsufficientFundsForFees, got %v", s.Results[1].Err)
+	}
+	if got := sp.GetNonce(sender); got != 1 {
+		t.Fatalf("expected the rejected transaction to keep the nonce, got %d", got)
+	}
+}
+
+func TestChainRulesRequireGasSchedule(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	sp.AddAccount(sender, nil, uint256.NewInt(10_000_000))
+
+	txs := []vm.Transaction{
+		{Type: DepositTxType, From: sender, To: &target, Mint: uint256.NewInt(5), Gas: 30_000},
+		{From: sender, To: &target, Gas: 30_000, Encoded: emptyTx},
+	}
+	config := &Config{RegolithTime: newUint64(0), EcotoneTime: newUint64(0), FjordTime: newUint64(0), IsthmusTime: newUint64(0)}
+	s := vm.NewSession(sp, &vm.BlockContext{Timestamp: 1000}, opcode_handlers.GetHandler, txs)
+	s.VM.ChainRules = NewChainRules(config)
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	// Without metered gas the operator fee cannot be computed, nothing is charged or minted
+	for i, result := range s.Results {
+		if !errors.Is(result.Err, ErrGasNotMetered) {
+			t.Errorf("transaction %d: expected ErrGasNotMetered, got %v", i, result.Err)
+		}
+	}
+	if got := sp.GetBalance(sender).Uint64(); got != 10_000_000 {
+		t.Errorf("expected the balance to be unchanged, got %d", got)
+	}
+}
@@ -0,0 +1,134 @@
+package precompiles
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// blake2FInputLength is the size of rounds || h || m || t || f (EIP-152)
+const blake2FInputLength = 213
+
+// Errors
+var (
+	ErrBlake2FInvalidInputLength = errors.New("blake2f: input length is not 213 bytes")
+	ErrBlake2FInvalidFinalFlag   = errors.New("blake2f: final block flag is not 0 or 1")
+)
+
+// Blake2F runs the BLAKE2b compression function F (EIP-152), active since Istanbul
+type Blake2F struct{}
+
+// RequiredGas returns the gas needed to execute the precompile: one per round
+func (*Blake2F) RequiredGas(input []byte) uint64 {
+	if len(input) != blake2FInputLength {
+		return 0
+	}
+	return uint64(binary.BigEndian.Uint32(input[0:4]))
+}
+
+// Run returns the state vector h after the compression, encoded as 8 little-endian words
+func (*Blake2F) Run(input []byte) ([]byte, error) {
+	if len(input) != blake2FInputLength {
+		return nil, ErrBlake2FInvalidInputLength
+	}
+	if input[212] > 1 {
+		return nil, ErrBlake2FInvalidFinalFlag
+	}
+
+	rounds := binary.BigEndian.Uint32(input[0:4])
+	var h [8]uint64
+	var m [16]uint64
+	for i := range h {
+		h[i] = binary.LittleEndian.Uint64(input[4+i*8:])
+	}
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(input[68+i*8:])
+	}
+	t := [2]uint64{
+		binary.LittleEndian.Uint64(input[196:]),
+		binary.LittleEndian.Uint64(input[204:]),
+	}
+
+	Blake2bF(&h, m, t, input[212] == 1, rounds)
+
+	out := make([]byte, 64)
+	for i, v := range h {
+		binary.LittleEndian.PutUint64(out[i*8:], v)
+	}
+	return out, nil
+}
+
+// blake2bIV is the BLAKE2b initialization vector
+var blake2bIV = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+// blake2bSigma is the message word permutation of each round, repeating every 10 rounds
+var blake2bSigma = [10][16]byte{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+}
+
+// Blake2bF is the BLAKE2b compression function F (RFC 7693) with a configurable number of
+// rounds. It compresses the message block m into the state h, with t the offset counter and
+// final set for the last block.
+func Blake2bF(h *[8]uint64, m [16]uint64, t [2]uint64, final bool, rounds uint32) {
+	var v [16]uint64
+	copy(v[:8], h[:])
+	copy(v[8:], blake2bIV[:])
+	v[12] ^= t[0]
+	v[13] ^= t[1]
+	if final {
+		v[14] = ^v[14]
+	}
+
+	g := func(a, b, c, d int, x, y uint64) {
+		v[a] += v[b] + x
+		v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+		v[c] += v[d]
+		v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+		v[a] += v[b] + y
+		v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+		v[c] += v[d]
+		v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+	}
+
+	for i := uint32(0); i < rounds; i++ {
+		s := &blake2bSigma[i%10]
+		// Mix the columns, then the diagonals
+		g(0, 4, 8, 12, m[s[0]], m[s[1]])
+		g(1, 5, 9, 13, m[s[2]], m[s[3]])
+		g(2, 6, 10, 14, m[s[4]], m[s[5]])
+		g(3, 7, 11, 15, m[s[6]], m[s[7]])
+		g(0, 5, 10, 15, m[s[8]], m[s[9]])
+		g(1, 6, 11, 12, m[s[10]], m[s[11]])
+		g(2, 7, 8, 13, m[s[12]], m[s[13]])
+		g(3, 4, 9, 14, m[s[14]], m[s[15]])
+	}
+
+	for i := range h {
+		h[i] ^= v[i] ^ v[i+8]
+	}
+}
+
+// Name returns the name of the precompile
+func (*Blake2F) Name() string {
+	return "BLAKE2F"
+}
+
+// DecodeInput returns the rounds, the state vector, the message block, the offset counters
+// and the final block flag
+func (*Blake2F) DecodeInput(input []byte) []InputField {
+	fields := splitFields(input, []string{"rounds", "h", "m", "t", "f"}, []uint64{4, 64, 128, 16, 1})
+	fields[0] = uintField("rounds", uint64(binary.BigEndian.Uint32(getData(input, 0, 4))))
+	return fields
+}
@@ -0,0 +1,137 @@
+package precompiles
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// blake2F vectors 4 to 7 from EIP-152
+var blake2FTests = []struct {
+	name     string
+	input    string
+	expected string
+	gas      uint64
+}{
+	{
+		name: "vector_4",
+		input: "0000000048c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f" +
+			"3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e13" +
+			"19cde05b61626300000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"000000000300000000000000000000000000000001",
+		expected: "08c9bcf367e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5" +
+			"d282e6ad7f520e511f6c3e2b8c68059b9442be0454267ce079217e1319cde05b",
+		gas: 0,
+	},
+	{
+		name: "vector_5",
+		input: "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f" +
+			"3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e13" +
+			"19cde05b61626300000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"000000000300000000000000000000000000000001",
+		expected: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d1" +
+			"7d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		gas: 12,
+	},
+	{
+		name: "vector_6",
+		input: "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f" +
+			"3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e13" +
+			"19cde05b61626300000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"000000000300000000000000000000000000000000",
+		expected: "75ab69d3190a562c51aef8d88f1c2775876944407270c42c9844252c26d28752" +
+			"98743e7f6d5ea2f2d3e8d226039cd31b4e426ac4f2d3d666a610c2116fde4735",
+		gas: 12,
+	},
+	{
+		name: "vector_7",
+		input: "0000000148c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f" +
+			"3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e13" +
+			"19cde05b61626300000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"000000000300000000000000000000000000000001",
+		expected: "b63a380cb2897d521994a85234ee2c181b5f844d2c624c002677e9703449d2fb" +
+			"a551b3a8333bcdf5f2f7e08993d53923de3d64fcc68c034e717b9293fed7a421",
+		gas: 1,
+	},
+}
+
+func TestBlake2F(t *testing.T) {
+	p := &Blake2F{}
+	for _, tt := range blake2FTests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := hex.DecodeString(tt.input)
+			expected, _ := hex.DecodeString(tt.expected)
+			if gas := p.RequiredGas(input); gas != tt.gas {
+				t.Fatalf("expected gas %d, got %d", tt.gas, gas)
+			}
+			out, err := p.Run(input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(out, expected) {
+				t.Fatalf("expected %x, got %x", expected, out)
+			}
+		})
+	}
+}
+
+func TestBlake2FInvalidInputs(t *testing.T) {
+	valid, _ := hex.DecodeString(blake2FTests[1].input)
+	badFlag := bytes.Clone(valid)
+	badFlag[212] = 2
+
+	tests := []struct {
+		name  string
+		input []byte
+		error error
+	}{
+		{"empty", nil, ErrBlake2FInvalidInputLength},
+		{"short", valid[:212], ErrBlake2FInvalidInputLength},
+		{"long", append(bytes.Clone(valid), 0x00), ErrBlake2FInvalidInputLength},
+		{"final flag", badFlag, ErrBlake2FInvalidFinalFlag},
+	}
+	for _, tt := range tests {
+		if _, err := (&Blake2F{}).Run(tt.input); !errors.Is(err, tt.error) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.error, err)
+		}
+	}
+}
+
+func TestBlake2bFMatchesBlake2b(t *testing.T) {
+	// A single block BLAKE2b-512 hash is one compression of the parameter block xor IV
+	msg := []byte("the quick brown fox jumps over the lazy dog")
+	h := blake2bIV
+	h[0] ^= 0x01010040
+
+	var block [128]byte
+	copy(block[:], msg)
+	var m [16]uint64
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(block[i*8:])
+	}
+	Blake2bF(&h, m, [2]uint64{uint64(len(msg)), 0}, true, 12)
+
+	var out [64]byte
+	for i, v := range h {
+		binary.LittleEndian.PutUint64(out[i*8:], v)
+	}
+	if expected := blake2b.Sum512(msg); out != expected {
+		t.Fatalf("expected %x, got %x", expected, out)
+	}
+}
@@ -0,0 +1,404 @@
+package precompiles
+
+import (
+	"errors"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+// Gas costs of the BLS12-381 precompiles (EIP-2537)
+const (
+	BLS12381G1AddGas          = 375
+	BLS12381G1MulGas          = 12000
+	BLS12381G2AddGas          = 600
+	BLS12381G2MulGas          = 22500
+	BLS12381PairingBaseGas    = 37700
+	BLS12381PairingPerPairGas = 32600
+	BLS12381MapG1Gas          = 5500
+	BLS12381MapG2Gas          = 23800
+)
+
+// Encoded sizes of BLS12-381 elements. Field elements are 64 bytes with the top 16 bytes zero.
+const (
+	bls12381FieldElementLength = 64
+	bls12381G1Length           = 2 * bls12381FieldElementLength
+	bls12381G2Length           = 4 * bls12381FieldElementLength
+	bls12381ScalarLength       = 32
+	bls12381G1MSMPairLength    = bls12381G1Length + bls12381ScalarLength
+	bls12381G2MSMPairLength    = bls12381G2Length + bls12381ScalarLength
+	bls12381PairingPairLength  = bls12381G1Length + bls12381G2Length
+)
+
+// Errors
+var (
+	ErrBLS12381InvalidInputLength  = errors.New("bls12-381: invalid input length")
+	ErrBLS12381InvalidFieldElement = errors.New("bls12-381: invalid field element")
+	ErrBLS12381NotOnCurve          = errors.New("bls12-381: point is not on the curve")
+	ErrBLS12381G1NotInSubgroup     = errors.New("bls12-381: G1 point is not in the subgroup")
+	ErrBLS12381G2NotInSubgroup     = errors.New("bls12-381: G2 point is not in the subgroup")
+)
+
+// BLS12381G1MSMDiscountTable is the per-pair discount in thousandths of a G1 multi-scalar
+// multiplication of k pairs, indexed by k-1 and capped at the last entry
+var BLS12381G1MSMDiscountTable = [128]uint64{1000, 949, 848, 797, 764, 750, 738, 728, 719, 712, 705, 698, 692, 687, 682, 677, 673, 669, 665, 661, 658, 654, 651, 648, 645, 642, 640, 637, 635, 632, 630, 627, 625, 623, 621, 619, 617, 615, 613, 611, 609, 608, 606, 604, 603, 601, 599, 598, 596, 595, 593, 592, 591, 589, 588, 586, 585, 584, 582, 581, 580, 579, 577, 576, 575, 574, 573, 572, 570, 569, 568, 567, 566, 565, 564, 563, 562, 561, 560, 559, 558, 557, 556, 555, 554, 553, 552, 551, 550, 549, 548, 547, 547, 546, 545, 544, 543, 542, 541, 540, 540, 539, 538, 537, 536, 536, 535, 534, 533, 532, 532, 531, 530, 529, 528, 528, 527, 526, 525, 525, 524, 523, 522, 522, 521, 520, 520, 519}
+
+// BLS12381G2MSMDiscountTable is the per-pair discount in thousandths of a G2 multi-scalar
+// multiplication of k pairs, indexed by k-1 and capped at the last entry
+var BLS12381G2MSMDiscountTable = [128]uint64{1000, 1000, 923, 884, 855, 832, 812, 796, 782, 770, 759, 749, 740, 732, 724, 717, 711, 704, 699, 693, 688, 683, 679, 674, 670, 666, 663, 659, 655, 652, 649, 646, 643, 640, 637, 634, 632, 629, 627, 624, 622, 620, 618, 615, 613, 611, 609, 607, 606, 604, 602, 600, 598, 597, 595, 593, 592, 590, 589, 587, 586, 584, 583, 582, 580, 579, 578, 576, 575, 574, 573, 571, 570, 569, 568, 567, 566, 565, 563, 562, 561, 560, 559, 558, 557, 556, 555, 554, 553, 552, 552, 551, 550, 549, 548, 547, 546, 545, 545, 544, 543, 542, 541, 541, 540, 539, 538, 537, 537, 536, 535, 535, 534, 533, 532, 532, 531, 530, 530, 529, 528, 528, 527, 526, 526, 525, 524, 524}
+
+// msmGas returns k * mulGas * discount / 1000 for the k pairs of input
+func msmGas(input []byte, pairLength int, mulGas uint64, discounts *[128]uint64) uint64 {
+	k := len(input) / pairLength
+	if k == 0 {
+		return 0
+	}
+	discount := discounts[min(k, len(discounts))-1]
+	return uint64(k) * mulGas * discount / 1000
+}
+
+// BLS12381G1Add adds two G1 points (EIP-2537), active since Prague
+type BLS12381G1Add struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*BLS12381G1Add) RequiredGas(input []byte) uint64 {
+	return BLS12381G1AddGas
+}
+
+// Run returns the sum of the two points. The points are not checked to be in the subgroup.
+func (*BLS12381G1Add) Run(input []byte) ([]byte, error) {
+	if len(input) != 2*bls12381G1Length {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+	a, err := decodeBLS12381G1(input[:bls12381G1Length])
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeBLS12381G1(input[bls12381G1Length:])
+	if err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G1(new(bls12381.G1Affine).Add(a, b)), nil
+}
+
+// BLS12381G1MSM computes the multi-scalar multiplication of G1 points (EIP-2537), active
+// since Prague
+type BLS12381G1MSM struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*BLS12381G1MSM) RequiredGas(input []byte) uint64 {
+	return msmGas(input, bls12381G1MSMPairLength, BLS12381G1MulGas, &BLS12381G1MSMDiscountTable)
+}
+
+// Run returns the sum of the points multiplied by their scalars
+func (*BLS12381G1MSM) Run(input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input)%bls12381G1MSMPairLength != 0 {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+
+	k := len(input) / bls12381G1MSMPairLength
+	points := make([]bls12381.G1Affine, k)
+	scalars := make([]fr.Element, k)
+	for i := range k {
+		pair := input[i*bls12381G1MSMPairLength:]
+		p, err := decodeBLS12381G1(pair[:bls12381G1Length])
+		if err != nil {
+			return nil, err
+		}
+		if !p.IsInSubGroup() {
+			return nil, ErrBLS12381G1NotInSubgroup
+		}
+		points[i] = *p
+		scalars[i].SetBytes(pair[bls12381G1Length:bls12381G1MSMPairLength])
+	}
+
+	r := new(bls12381.G1Affine)
+	if _, err := r.MultiExp(points, scalars, ecc.MultiExpConfig{}); err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G1(r), nil
+}
+
+// BLS12381G2Add adds two G2 points (EIP-2537), active since Prague
+type BLS12381G2Add struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*BLS12381G2Add) RequiredGas(input []byte) uint64 {
+	return BLS12381G2AddGas
+}
+
+// Run returns the sum of the two points. The points are not checked to be in the subgroup.
+func (*BLS12381G2Add) Run(input []byte) ([]byte, error) {
+	if len(input) != 2*bls12381G2Length {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+	a, err := decodeBLS12381G2(input[:bls12381G2Length])
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeBLS12381G2(input[bls12381G2Length:])
+	if err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G2(new(bls12381.G2Affine).Add(a, b)), nil
+}
+
+// BLS12381G2MSM computes the multi-scalar multiplication of G2 points (EIP-2537), active
+// since Prague
+type BLS12381G2MSM struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*BLS12381G2MSM) RequiredGas(input []byte) uint64 {
+	return msmGas(input, bls12381G2MSMPairLength, BLS12381G2MulGas, &BLS12381G2MSMDiscountTable)
+}
+
+// Run returns the sum of the points multiplied by their scalars
+func (*BLS12381G2MSM) Run(input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input)%bls12381G2MSMPairLength != 0 {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+
+	k := len(input) / bls12381G2MSMPairLength
+	points := make([]bls12381.G2Affine, k)
+	scalars := make([]fr.Element, k)
+	for i := range k {
+		pair := input[i*bls12381G2MSMPairLength:]
+		p, err := decodeBLS12381G2(pair[:bls12381G2Length])
+		if err != nil {
+			return nil, err
+		}
+		if !p.IsInSubGroup() {
+			return nil, ErrBLS12381G2NotInSubgroup
+		}
+		points[i] = *p
+		scalars[i].SetBytes(pair[bls12381G2Length:bls12381G2MSMPairLength])
+	}
+
+	r := new(bls12381.G2Affine)
+	if _, err := r.MultiExp(points, scalars, ecc.MultiExpConfig{}); err != nil {
+		return nil, err
+	}
+	return encodeBLS12381G2(r), nil
+}
+
+// BLS12381Pairing checks that the product of the pairings of G1 and G2 points is one
+// (EIP-2537), active since Prague
+type BLS12381Pairing struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*BLS12381Pairing) RequiredGas(input []byte) uint64 {
+	return BLS12381PairingBaseGas + uint64(len(input)/bls12381PairingPairLength)*BLS12381PairingPerPairGas
+}
+
+// Run returns 1 as a 32-byte word if the pairing check succeeds and 0 otherwise
+func (*BLS12381Pairing) Run(input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input)%bls12381PairingPairLength != 0 {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+
+	k := len(input) / bls12381PairingPairLength
+	g1 := make([]bls12381.G1Affine, 0, k)
+	g2 := make([]bls12381.G2Affine, 0, k)
+	for i := range k {
+		pair := input[i*bls12381PairingPairLength:]
+		p, err := decodeBLS12381G1(pair[:bls12381G1Length])
+		if err != nil {
+			return nil, err
+		}
+		q, err := decodeBLS12381G2(pair[bls12381G1Length:bls12381PairingPairLength])
+		if err != nil {
+			return nil, err
+		}
+		if !p.IsInSubGroup() {
+			return nil, ErrBLS12381G1NotInSubgroup
+		}
+		if !q.IsInSubGroup() {
+			return nil, ErrBLS12381G2NotInSubgroup
+		}
+		g1 = append(g1, *p)
+		g2 = append(g2, *q)
+	}
+
+	out := make([]byte, 32)
+	if ok, err := bls12381.PairingCheck(g1, g2); err == nil && ok {
+		out[31] = 1
+	}
+	return out, nil
+}
+
+// BLS12381MapG1 maps a base field element to a G1 point (EIP-2537), active since Prague
+type BLS12381MapG1 struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*BLS12381MapG1) RequiredGas(input []byte) uint64 {
+	return BLS12381MapG1Gas
+}
+
+// Run executes the precompile
+func (*BLS12381MapG1) Run(input []byte) ([]byte, error) {
+	if len(input) != bls12381FieldElementLength {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+	e, err := decodeBLS12381FieldElement(input)
+	if err != nil {
+		return nil, err
+	}
+	p := bls12381.MapToG1(e)
+	return encodeBLS12381G1(&p), nil
+}
+
+// BLS12381MapG2 maps an element of the quadratic extension field to a G2 point
+// (EIP-2537), active since Prague
+type BLS12381MapG2 struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*BLS12381MapG2) RequiredGas(input []byte) uint64 {
+	return BLS12381MapG2Gas
+}
+
+// Run executes the precompile
+func (*BLS12381MapG2) Run(input []byte) ([]byte, error) {
+	if len(input) != 2*bls12381FieldElementLength {
+		return nil, ErrBLS12381InvalidInputLength
+	}
+	c0, err := decodeBLS12381FieldElement(input[:bls12381FieldElementLength])
+	if err != nil {
+		return nil, err
+	}
+	c1, err := decodeBLS12381FieldElement(input[bls12381FieldElementLength:])
+	if err != nil {
+		return nil, err
+	}
+	p := bls12381.MapToG2(bls12381.E2{A0: c0, A1: c1})
+	return encodeBLS12381G2(&p), nil
+}
+
+// decodeBLS12381FieldElement decodes a 64-byte field element, which must have its top 16
+// bytes zero and be smaller than the field modulus
+func decodeBLS12381FieldElement(b []byte) (fp.Element, error) {
+	if !isZero(b[:16]) {
+		return fp.Element{}, ErrBLS12381InvalidFieldElement
+	}
+	e, err := fp.BigEndian.Element((*[fp.Bytes]byte)(b[16:bls12381FieldElementLength]))
+	if err != nil {
+		return fp.Element{}, ErrBLS12381InvalidFieldElement
+	}
+	return e, nil
+}
+
+// decodeBLS12381G1 decodes a G1 point from x || y. All zeroes is the point at infinity.
+func decodeBLS12381G1(b []byte) (*bls12381.G1Affine, error) {
+	x, err := decodeBLS12381FieldElement(b[:64])
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBLS12381FieldElement(b[64:128])
+	if err != nil {
+		return nil, err
+	}
+	p := &bls12381.G1Affine{X: x, Y: y}
+	if !p.IsOnCurve() {
+		return nil, ErrBLS12381NotOnCurve
+	}
+	return p, nil
+}
+
+// decodeBLS12381G2 decodes a G2 point from x_0 || x_1 || y_0 || y_1. All zeroes is the point
+// at infinity.
+func decodeBLS12381G2(b []byte) (*bls12381.G2Affine, error) {
+	var coords [4]fp.Element
+	for i := range coords {
+		e, err := decodeBLS12381FieldElement(b[i*64 : (i+1)*64])
+		if err != nil {
+			return nil, err
+		}
+		coords[i] = e
+	}
+	p := &bls12381.G2Affine{
+		X: bls12381.E2{A0: coords[0], A1: coords[1]},
+		Y: bls12381.E2{A0: coords[2], A1: coords[3]},
+	}
+	if !p.IsOnCurve() {
+		return nil, ErrBLS12381NotOnCurve
+	}
+	return p, nil
+}
+
+// encodeBLS12381G1 encodes a G1 point as two 64-byte field elements
+func encodeBLS12381G1(p *bls12381.G1Affine) []byte {
+	out := make([]byte, bls12381G1Length)
+	fp.BigEndian.PutElement((*[fp.Bytes]byte)(out[16:64]), p.X)
+	fp.BigEndian.PutElement((*[fp.Bytes]byte)(out[80:128]), p.Y)
+	return out
+}
+
+// encodeBLS12381G2 encodes a G2 point as four 64-byte field elements
+func encodeBLS12381G2(p *bls12381.G2Affine) []byte {
+	out := make([]byte, bls12381G2Length)
+	for i, e := range []fp.Element{p.X.A0, p.X.A1, p.Y.A0, p.Y.A1} {
+		fp.BigEndian.PutElement((*[fp.Bytes]byte)(out[i*64+16:(i+1)*64]), e)
+	}
+	return out
+}
+
+// Name returns the name of the precompile
+func (*BLS12381G1Add) Name() string {
+	return "BLS12_G1ADD"
+}
+
+// DecodeInput returns the two points
+func (*BLS12381G1Add) DecodeInput(input []byte) []InputField {
+	return splitFields(input, []string{"p0", "p1"}, []uint64{bls12381G1Length, bls12381G1Length})
+}
+
+// Name returns the name of the precompile
+func (*BLS12381G1MSM) Name() string {
+	return "BLS12_G1MSM"
+}
+
+// DecodeInput returns the number of point and scalar pairs
+func (*BLS12381G1MSM) DecodeInput(input []byte) []InputField {
+	return []InputField{uintField("pairs", uint64(len(input)/bls12381G1MSMPairLength))}
+}
+
+// Name returns the name of the precompile
+func (*BLS12381G2Add) Name() string {
+	return "BLS12_G2ADD"
+}
+
+// DecodeInput returns the two points
+func (*BLS12381G2Add) DecodeInput(input []byte) []InputField {
+	return splitFields(input, []string{"p0", "p1"}, []uint64{bls12381G2Length, bls12381G2Length})
+}
+
+// Name returns the name of the precompile
+func (*BLS12381G2MSM) Name() string {
+	return "BLS12_G2MSM"
+}
+
+// DecodeInput returns the number of point and scalar pairs
+func (*BLS12381G2MSM) DecodeInput(input []byte) []InputField {
+	return []InputField{uintField("pairs", uint64(len(input)/bls12381G2MSMPairLength))}
+}
+
+// Name returns the name of the precompile
+func (*BLS12381Pairing) Name() string {
+	return "BLS12_PAIRING_CHECK"
+}
+
+// DecodeInput returns the number of point pairs
+func (*BLS12381Pairing) DecodeInput(input []byte) []InputField {
+	return []InputField{uintField("pairs", uint64(len(input)/bls12381PairingPairLength))}
+}
+
+// Name returns the name of the precompile
+func (*BLS12381MapG1) Name() string {
+	return "BLS12_MAP_FP_TO_G1"
+}
+
+// Name returns the name of the precompile
+func (*BLS12381MapG2) Name() string {
+	return "BLS12_MAP_FP2_TO_G2"
+}
@@ -0,0 +1,221 @@
+package precompiles
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// Gas costs of the BN254 precompiles before and since Istanbul (EIP-1108)
+const (
+	BN254AddGasByzantium             = 500
+	BN254AddGasIstanbul              = 150
+	BN254MulGasByzantium             = 40000
+	BN254MulGasIstanbul              = 6000
+	BN254PairingBaseGasByzantium     = 100000
+	BN254PairingBaseGasIstanbul      = 45000
+	BN254PairingPerPointGasByzantium = 80000
+	BN254PairingPerPointGasIstanbul  = 34000
+)
+
+// Errors
+var (
+	ErrBN254InvalidFieldElement = errors.New("bn254: coordinate is not a valid field element")
+	ErrBN254NotOnCurve          = errors.New("bn254: point is not on the curve")
+	ErrBN254NotInSubgroup       = errors.New("bn254: G2 point is not in the subgroup")
+	ErrBN254InvalidPairingInput = errors.New("bn254: pairing input is not a multiple of 192 bytes")
+)
+
+// bn254PairInputLength is the size of a G1 and a G2 point in the pairing input
+const bn254PairInputLength = 192
+
+// BN254Add adds two points on the alt_bn128 curve (EIP-196). EIP1108 enables the Istanbul
+// pricing.
+type BN254Add struct {
+	EIP1108 bool
+}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (c *BN254Add) RequiredGas(input []byte) uint64 {
+	if c.EIP1108 {
+		return BN254AddGasIstanbul
+	}
+	return BN254AddGasByzantium
+}
+
+// Run returns the sum of the two G1 points in the input, right-padded to 128 bytes
+func (c *BN254Add) Run(input []byte) ([]byte, error) {
+	input = getData(input, 0, 128)
+	a, err := unmarshalBN254G1(input[:64])
+	if err != nil {
+		return nil, err
+	}
+	b, err := unmarshalBN254G1(input[64:])
+	if err != nil {
+		return nil, err
+	}
+	return marshalBN254G1(new(bn254.G1Affine).Add(a, b)), nil
+}
+
+// BN254Mul multiplies a point on the alt_bn128 curve by a scalar (EIP-196). EIP1108
+// enables the Istanbul pricing.
+type BN254Mul struct {
+	EIP1108 bool
+}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (c *BN254Mul) RequiredGas(input []byte) uint64 {
+	if c.EIP1108 {
+		return BN254MulGasIstanbul
+	}
+	return BN254MulGasByzantium
+}
+
+// Run returns the G1 point of the input multiplied by the 32-byte scalar following it,
+// with the input right-padded to 96 bytes
+func (c *BN254Mul) Run(input []byte) ([]byte, error) {
+	input = getData(input, 0, 96)
+	p, err := unmarshalBN254G1(input[:64])
+	if err != nil {
+		return nil, err
+	}
+	scalar := new(big.Int).SetBytes(input[64:96])
+	return marshalBN254G1(new(bn254.G1Affine).ScalarMultiplication(p, scalar)), nil
+}
+
+// BN254Pairing checks that the product of the pairings of G1 and G2 points is one
+// (EIP-197). EIP1108 enables the Istanbul pricing.
+type BN254Pairing struct {
+	EIP1108 bool
+}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (c *BN254Pairing) RequiredGas(input []byte) uint64 {
+	pairs := uint64(len(input) / bn254PairInputLength)
+	if c.EIP1108 {
+		return BN254PairingBaseGasIstanbul + pairs*BN254PairingPerPointGasIstanbul
+	}
+	return BN254PairingBaseGasByzantium + pairs*BN254PairingPerPointGasByzantium
+}
+
+// Run returns 1 as a 32-byte word if the pairing check succeeds and 0 otherwise. An empty
+// input succeeds.
+func (c *BN254Pairing) Run(input []byte) ([]byte, error) {
+	if len(input)%bn254PairInputLength != 0 {
+		return nil, ErrBN254InvalidPairingInput
+	}
+
+	n := len(input) / bn254PairInputLength
+	g1 := make([]bn254.G1Affine, 0, n)
+	g2 := make([]bn254.G2Affine, 0, n)
+	for i := 0; i < len(input); i += bn254PairInputLength {
+		p, err := unmarshalBN254G1(input[i : i+64])
+		if err != nil {
+			return nil, err
+		}
+		q, err := unmarshalBN254G2(input[i+64 : i+bn254PairInputLength])
+		if err != nil {
+			return nil, err
+		}
+		g1 = append(g1, *p)
+		g2 = append(g2, *q)
+	}
+
+	if n == 0 {
+		return leftPad([]byte{1}, 32), nil
+	}
+	ok, err := bn254.PairingCheck(g1, g2)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return make([]byte, 32), nil
+	}
+	return leftPad([]byte{1}, 32), nil
+}
+
+// isZero reports whether all bytes of b are zero
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// unmarshalBN254G1 decodes a G1 point from x || y. All zeroes is the point at infinity.
+func unmarshalBN254G1(b []byte) (*bn254.G1Affine, error) {
+	p := new(bn254.G1Affine)
+	if isZero(b) {
+		return p, nil
+	}
+	if p.X.SetBytesCanonical(b[:32]) != nil || p.Y.SetBytesCanonical(b[32:64]) != nil {
+		return nil, ErrBN254InvalidFieldElement
+	}
+	// G1 has a cofactor of one, every point on the curve is in the subgroup
+	if !p.IsOnCurve() {
+		return nil, ErrBN254NotOnCurve
+	}
+	return p, nil
+}
+
+// unmarshalBN254G2 decodes a G2 point from x_imaginary || x_real || y_imaginary || y_real.
+// All zeroes is the point at infinity.
+func unmarshalBN254G2(b []byte) (*bn254.G2Affine, error) {
+	q := new(bn254.G2Affine)
+	if isZero(b) {
+		return q, nil
+	}
+	if q.X.A1.SetBytesCanonical(b[0:32]) != nil || q.X.A0.SetBytesCanonical(b[32:64]) != nil ||
+		q.Y.A1.SetBytesCanonical(b[64:96]) != nil || q.Y.A0.SetBytesCanonical(b[96:128]) != nil {
+		return nil, ErrBN254InvalidFieldElement
+	}
+	if !q.IsOnCurve() {
+		return nil, ErrBN254NotOnCurve
+	}
+	if !q.IsInSubGroup() {
+		return nil, ErrBN254NotInSubgroup
+	}
+	return q, nil
+}
+
+// marshalBN254G1 encodes a G1 point as x || y
+func marshalBN254G1(p *bn254.G1Affine) []byte {
+	out := make([]byte, 64)
+	x, y := p.X.Bytes(), p.Y.Bytes()
+	copy(out[:32], x[:])
+	copy(out[32:], y[:])
+	return out
+}
+
+// Name returns the name of the precompile
+func (c *BN254Add) Name() string {
+	return "BN254_ADD"
+}
+
+// DecodeInput returns the coordinates of the two points
+func (c *BN254Add) DecodeInput(input []byte) []InputField {
+	return splitFields(input, []string{"x1", "y1", "x2", "y2"}, []uint64{32, 32, 32, 32})
+}
+
+// Name returns the name of the precompile
+func (c *BN254Mul) Name() string {
+	return "BN254_MUL"
+}
+
+// DecodeInput returns the coordinates of the point and the scalar
+func (c *BN254Mul) DecodeInput(input []byte) []InputField {
+	return splitFields(input, []string{"x", "y", "scalar"}, []uint64{32, 32, 32})
+}
+
+// Name returns the name of the precompile
+func (c *BN254Pairing) Name() string {
+	return "BN254_PAIRING"
+}
+
+// DecodeInput returns the number of point pairs
+func (c *BN254Pairing) DecodeInput(input []byte) []InputField {
+	return []InputField{uintField("pairs", uint64(len(input)/bn254PairInputLength))}
+}
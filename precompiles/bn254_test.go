@@ -0,0 +1,232 @@
+package precompiles
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+type bn254Test struct {
+	name     string
+	input    string
+	expected string
+}
+
+// bn254 vectors from the go-ethereum precompile tests
+var bn254AddTests = []bn254Test{
+	{
+		name: "chfast1",
+		input: "18b18acfb4c2c30276db5411368e7185b311dd124691610c5d3b74034e093dc9" +
+			"063c909c4720840cb5134cb9f59fa749755796819658d32efc0d288198f37266" +
+			"07c2b7f58a84bd6145f00c9c2bc0bb1a187f20ff2c92963a88019e7c6a014eed" +
+			"06614e20c147e940f2d70da3f74c9a17df361706a4485c742bd6788478fa17d7",
+		expected: "2243525c5efd4b9c3d3c45ac0ca3fe4dd85e830a4ce6b65fa1eeaee202839703" +
+			"301d1d33be6da8e509df21cc35964723180eed7532537db9ae5e7d48f195c915",
+	},
+	{
+		name: "cdetrio2",
+		input: "0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+		expected: "0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+	},
+	{
+		name: "cdetrio11",
+		input: "0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000002" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000002",
+		expected: "030644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd3" +
+			"15ed738c0e0a7c92e7845f96b2ae9c0a68a6a449e3538fc7ff3ebf7a5a18a2c4",
+	},
+	{
+		name: "cdetrio14",
+		input: "17c139df0efee0f766bc0204762b774362e4ded88953a39ce849a8a7fa163fa9" +
+			"01e0559bacb160664764a357af8a9fe70baa9258e0b959273ffc5718c6d4cc7c" +
+			"17c139df0efee0f766bc0204762b774362e4ded88953a39ce849a8a7fa163fa9" +
+			"2e83f8d734803fc370eba25ed1f6b8768bd6d83887b87165fc2434fe11a830cb" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+		expected: "0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+	},
+}
+
+var bn254MulTests = []bn254Test{
+	{
+		name: "chfast1",
+		input: "2bd3e6d0f3b142924f5ca7b49ce5b9d54c4703d7ae5648e61d02268b1a0a9fb7" +
+			"21611ce0a6af85915e2f1d70300909ce2e49dfad4a4619c8390cae66cefdb204" +
+			"00000000000000000000000000000000000000000000000011138ce750fa15c2",
+		expected: "070a8d6a982153cae4be29d434e8faef8a47b274a053f5a4ee2a6c9c13c31e5c" +
+			"031b8ce914eba3a9ffb989f9cdd5b0f01943074bf4f0f315690ec3cec6981afc",
+	},
+	{
+		name: "cdetrio11",
+		input: "039730ea8dff1254c0fee9c0ea777d29a9c710b7e616683f194f18c43b43b869" +
+			"073a5ffcc6fc7a28c30723d6e58ce577356982d65b833a5a5c15bf9024b43d98" +
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		expected: "00a1a234d08efaa2616607e31eca1980128b00b415c845ff25bba3afcb81dc00" +
+			"242077290ed33906aeb8e42fd98c41bcb9057ba03421af3f2d08cfc441186024",
+	},
+	{
+		name: "zeroScalar",
+		input: "039730ea8dff1254c0fee9c0ea777d29a9c710b7e616683f194f18c43b43b869" +
+			"073a5ffcc6fc7a28c30723d6e58ce577356982d65b833a5a5c15bf9024b43d98" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+		expected: "0000000000000000000000000000000000000000000000000000000000000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+	},
+}
+
+var bn254PairingTests = []bn254Test{
+	{
+		name: "jeff1",
+		input: "1c76476f4def4bb94541d57ebba1193381ffa7aa76ada664dd31c16024c43f59" +
+			"3034dd2920f673e204fee2811c678745fc819b55d3e9d294e45c9b03a76aef41" +
+			"209dd15ebff5d46c4bd888e51a93cf99a7329636c63514396b4a452003a35bf7" +
+			"04bf11ca01483bfa8b34b43561848d28905960114c8ac04049af4b6315a41678" +
+			"2bb8324af6cfc93537a2ad1a445cfd0ca2a71acd7ac41fadbf933c2a51be344d" +
+			"120a2a4cf30c1bf9845f20c6fe39e07ea2cce61f0c9bb048165fe5e4de877550" +
+			"111e129f1cf1097710d41c4ac70fcdfa5ba2023c6ff1cbeac322de49d1b6df7c" +
+			"2032c61a830e3c17286de9462bf242fca2883585b93870a73853face6a6bf411" +
+			"198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c2" +
+			"1800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed" +
+			"090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b" +
+			"12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa",
+		expected: "0000000000000000000000000000000000000000000000000000000000000001",
+	},
+	{
+		name: "jeff6",
+		input: "1c76476f4def4bb94541d57ebba1193381ffa7aa76ada664dd31c16024c43f59" +
+			"3034dd2920f673e204fee2811c678745fc819b55d3e9d294e45c9b03a76aef41" +
+			"209dd15ebff5d46c4bd888e51a93cf99a7329636c63514396b4a452003a35bf7" +
+			"04bf11ca01483bfa8b34b43561848d28905960114c8ac04049af4b6315a41678" +
+			"2bb8324af6cfc93537a2ad1a445cfd0ca2a71acd7ac41fadbf933c2a51be344d" +
+			"120a2a4cf30c1bf9845f20c6fe39e07ea2cce61f0c9bb048165fe5e4de877550" +
+			"111e129f1cf1097710d41c4ac70fcdfa5ba2023c6ff1cbeac322de49d1b6df7c" +
+			"103188585e2364128fe25c70558f1560f4f9350baf3959e603cc91486e110936" +
+			"198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c2" +
+			"1800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed" +
+			"090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b" +
+			"12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa",
+		expected: "0000000000000000000000000000000000000000000000000000000000000000",
+	},
+	{
+		name:     "empty_data",
+		input:    "",
+		expected: "0000000000000000000000000000000000000000000000000000000000000001",
+	},
+	{
+		name: "one_point",
+		input: "0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000002" +
+			"198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c2" +
+			"1800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed" +
+			"090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b" +
+			"12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa",
+		expected: "0000000000000000000000000000000000000000000000000000000000000000",
+	},
+	{
+		name: "two_point_match_2",
+		input: "0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000002" +
+			"198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c2" +
+			"1800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed" +
+			"090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b" +
+			"12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000002" +
+			"198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c2" +
+			"1800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed" +
+			"275dc4a288d1afb3cbb1ac09187524c7db36395df7be3b99e673b13a075a65ec" +
+			"1d9befcd05a5323e6da4d435f3b617cdb3af83285c2df711ef39c01571827f9d",
+		expected: "0000000000000000000000000000000000000000000000000000000000000001",
+	},
+}
+
+// bn254G2NotInSubgroup is a point on the G2 twist outside of the prime order subgroup
+const bn254G2NotInSubgroup = "0000000000000000000000000000000000000000000000000000000000000001" +
+	"0000000000000000000000000000000000000000000000000000000000000002" +
+	"2b76c179599bb92a963dac85546a005a777f7c13f6a7b75d5918b6b5808f5fde" +
+	"101f7278419308b95099eca02dcee0c5381f4d26d1d62313f057167f064101ce"
+
+func runBN254Tests(t *testing.T, p interface{ Run([]byte) ([]byte, error) }, tests []bn254Test) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := hex.DecodeString(tt.input)
+			expected, _ := hex.DecodeString(tt.expected)
+			out, err := p.Run(input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(out, expected) {
+				t.Fatalf("expected %x, got %x", expected, out)
+			}
+		})
+	}
+}
+
+func TestBN254Add(t *testing.T) {
+	runBN254Tests(t, &BN254Add{EIP1108: true}, bn254AddTests)
+}
+
+func TestBN254Mul(t *testing.T) {
+	runBN254Tests(t, &BN254Mul{EIP1108: true}, bn254MulTests)
+}
+
+func TestBN254Pairing(t *testing.T) {
+	runBN254Tests(t, &BN254Pairing{EIP1108: true}, bn254PairingTests)
+}
+
+func TestBN254Gas(t *testing.T) {
+	twoPairs := make([]byte, 2*bn254PairInputLength)
+	tests := []struct {
+		name     string
+		gas      uint64
+		expected uint64
+	}{
+		{"add", (&BN254Add{}).RequiredGas(nil), 500},
+		{"add eip-1108", (&BN254Add{EIP1108: true}).RequiredGas(nil), 150},
+		{"mul", (&BN254Mul{}).RequiredGas(nil), 40000},
+		{"mul eip-1108", (&BN254Mul{EIP1108: true}).RequiredGas(nil), 6000},
+		{"pairing", (&BN254Pairing{}).RequiredGas(twoPairs), 260000},
+		{"pairing eip-1108", (&BN254Pairing{EIP1108: true}).RequiredGas(twoPairs), 113000},
+	}
+	for _, tt := range tests {
+		if tt.gas != tt.expected {
+			t.Errorf("%s: expected gas %d, got %d", tt.name, tt.expected, tt.gas)
+		}
+	}
+}
+
+func TestBN254InvalidInputs(t *testing.T) {
+	// The G1 generator (1, 2) and a point off the curve
+	generator := make([]byte, 64)
+	generator[31], generator[63] = 1, 2
+	offCurve := bytes.Clone(generator)
+	offCurve[63] = 3
+	// x equal to the field modulus
+	modulus, _ := hex.DecodeString("30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47")
+	notCanonical := append(bytes.Clone(modulus), generator[32:]...)
+	g2, _ := hex.DecodeString(bn254G2NotInSubgroup)
+
+	tests := []struct {
+		name  string
+		run   func() ([]byte, error)
+		error error
+	}{
+		{"add off curve", func() ([]byte, error) { return (&BN254Add{}).Run(append(generator, offCurve...)) }, ErrBN254NotOnCurve},
+		{"add non-canonical", func() ([]byte, error) { return (&BN254Add{}).Run(notCanonical) }, ErrBN254InvalidFieldElement},
+		{"mul off curve", func() ([]byte, error) { return (&BN254Mul{}).Run(offCurve) }, ErrBN254NotOnCurve},
+		{"pairing size", func() ([]byte, error) { return (&BN254Pairing{}).Run(make([]byte, 191)) }, ErrBN254InvalidPairingInput},
+		{"pairing G2 subgroup", func() ([]byte, error) { return (&BN254Pairing{}).Run(append(bytes.Clone(generator), g2...)) }, ErrBN254NotInSubgroup},
+	}
+	for _, tt := range tests {
+		if out, err := tt.run(); !errors.Is(err, tt.error) {
+			t.Errorf("%s: expected %v, got %x (%v)", tt.name, tt.error, out, err)
+		}
+	}
+}
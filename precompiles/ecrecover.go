@@ -0,0 +1,62 @@
+package precompiles
+
+import (
+	"github.com/daniellehrner/evmdbg/crypto"
+	"github.com/holiman/uint256"
+)
+
+// EcrecoverGas is the fixed cost of an ECRECOVER call
+const EcrecoverGas = 3000
+
+// ecrecoverInputLength is the size of hash || v || r || s
+const ecrecoverInputLength = 128
+
+// Ecrecover recovers the address that signed a hash with secp256k1
+type Ecrecover struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*Ecrecover) RequiredGas(input []byte) uint64 {
+	return EcrecoverGas
+}
+
+// Run returns the signer address left-padded to 32 bytes and empty output for invalid
+// signatures. The input is right-padded to 128 bytes, invalid inputs never fail the call.
+func (*Ecrecover) Run(input []byte) ([]byte, error) {
+	input = getData(input, 0, ecrecoverInputLength)
+
+	// v is a 32-byte word that must be 27 or 28
+	for _, b := range input[32:63] {
+		if b != 0 {
+			return nil, nil
+		}
+	}
+	v := input[63]
+	if v != 27 && v != 28 {
+		return nil, nil
+	}
+
+	// r and s must be in [1, n), the EIP-2 upper bound on s only applies to transactions
+	r := new(uint256.Int).SetBytes(input[64:96])
+	s := new(uint256.Int).SetBytes(input[96:128])
+	if !crypto.ValidateSignatureValues(v-27, r, s, false) {
+		return nil, nil
+	}
+
+	var hash [32]byte
+	copy(hash[:], input[:32])
+	addr, err := crypto.RecoverAddress(hash, v-27, r, s)
+	if err != nil {
+		return nil, nil
+	}
+	return leftPad(addr[:], 32), nil
+}
+
+// Name returns the name of the precompile
+func (*Ecrecover) Name() string {
+	return "ECRECOVER"
+}
+
+// DecodeInput returns the hash, v, r and s of the input
+func (*Ecrecover) DecodeInput(input []byte) []InputField {
+	return splitFields(input, []string{"hash", "v", "r", "s"}, []uint64{32, 32, 32, 32})
+}
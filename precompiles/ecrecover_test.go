@@ -0,0 +1,68 @@
+package precompiles
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+// ecrecoverInput is hash || v || r || s of a signature by 0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b
+const ecrecoverInput = "18c547e4f7b0f325ad1e56f57e26c745b09a3e503d86e00e5255ff7f715d3d1c" +
+	"000000000000000000000000000000000000000000000000000000000000001c" +
+	"73b1693892219d736caba55bdb67216e485557ea6b6af75f37096c9aa6a5a75f" +
+	"eeb940b1d03b21e36b0e47e79769f095fe2ab855bd91e3a38756b7d75a9c4549"
+
+func TestEcrecover(t *testing.T) {
+	valid, _ := hex.DecodeString(ecrecoverInput)
+	signer, _ := hex.DecodeString("000000000000000000000000a94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+
+	// Negating s and flipping v recovers the same key, s above half the curve order is accepted
+	n, _ := uint256.FromHex("0xfffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")
+	s := new(uint256.Int).SetBytes(valid[96:128])
+	highS := bytes.Clone(valid)
+	highS[63] = 27
+	negated := new(uint256.Int).Sub(n, s).Bytes32()
+	copy(highS[96:], negated[:])
+
+	withInput := func(f func(b []byte)) []byte {
+		b := bytes.Clone(valid)
+		f(b)
+		return b
+	}
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected []byte
+	}{
+		{"valid signature", valid, signer},
+		{"trailing bytes are ignored", append(bytes.Clone(valid), 0x01, 0x02), signer},
+		{"high s", highS, signer},
+		{"v of 29", withInput(func(b []byte) { b[63] = 29 }), nil},
+		{"v of 0", withInput(func(b []byte) { b[63] = 0 }), nil},
+		{"high bits of v set", withInput(func(b []byte) { b[32] = 0x10 }), nil},
+		{"zero r", withInput(func(b []byte) { copy(b[64:96], make([]byte, 32)) }), nil},
+		{"s equal to the curve order", withInput(func(b []byte) { nb := n.Bytes32(); copy(b[96:], nb[:]) }), nil},
+		// The input is right-padded, leaving s zero
+		{"short input", valid[:96], nil},
+		{"empty input", nil, nil},
+	}
+
+	p := &Ecrecover{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gas := p.RequiredGas(tt.input); gas != EcrecoverGas {
+				t.Fatalf("expected gas %d, got %d", EcrecoverGas, gas)
+			}
+			output, err := p.Run(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(output, tt.expected) {
+				t.Fatalf("expected %x, got %x", tt.expected, output)
+			}
+		})
+	}
+}
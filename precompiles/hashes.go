@@ -0,0 +1,80 @@
+package precompiles
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Gas costs of the hash and identity precompiles: a base cost plus a cost per 32-byte word
+const (
+	SHA256BaseGas    = 60
+	SHA256WordGas    = 12
+	RIPEMD160BaseGas = 600
+	RIPEMD160WordGas = 120
+	IdentityBaseGas  = 15
+	IdentityWordGas  = 3
+)
+
+// wordGas returns base + perWord for every started 32-byte word of input
+func wordGas(input []byte, base, perWord uint64) uint64 {
+	return base + (uint64(len(input))+31)/32*perWord
+}
+
+// SHA256 returns the SHA-256 hash of its input
+type SHA256 struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*SHA256) RequiredGas(input []byte) uint64 {
+	return wordGas(input, SHA256BaseGas, SHA256WordGas)
+}
+
+// Run executes the precompile
+func (*SHA256) Run(input []byte) ([]byte, error) {
+	h := sha256.Sum256(input)
+	return h[:], nil
+}
+
+// RIPEMD160 returns the RIPEMD-160 hash of its input, left-padded to 32 bytes
+type RIPEMD160 struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*RIPEMD160) RequiredGas(input []byte) uint64 {
+	return wordGas(input, RIPEMD160BaseGas, RIPEMD160WordGas)
+}
+
+// Run executes the precompile
+func (*RIPEMD160) Run(input []byte) ([]byte, error) {
+	h := ripemd160.New()
+	h.Write(input)
+	return leftPad(h.Sum(nil), 32), nil
+}
+
+// Identity returns a copy of its input
+type Identity struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*Identity) RequiredGas(input []byte) uint64 {
+	return wordGas(input, IdentityBaseGas, IdentityWordGas)
+}
+
+// Run executes the precompile
+func (*Identity) Run(input []byte) ([]byte, error) {
+	return bytes.Clone(input), nil
+}
+
+// Name returns the name of the precompile
+func (*SHA256) Name() string {
+	return "SHA256"
+}
+
+// Name returns the name of the precompile
+func (*RIPEMD160) Name() string {
+	return "RIPEMD160"
+}
+
+// Name returns the name of the precompile
+func (*Identity) Name() string {
+	return "IDENTITY"
+}
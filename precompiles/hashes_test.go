@@ -0,0 +1,53 @@
+package precompiles
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHashPrecompiles(t *testing.T) {
+	long := bytes.Repeat([]byte{'a'}, 33)
+
+	tests := []struct {
+		name     string
+		p        precompile
+		input    []byte
+		expected string
+		gas      uint64
+	}{
+		{"sha256 empty", &SHA256{}, nil, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", 60},
+		{"sha256 abc", &SHA256{}, []byte("abc"), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", 72},
+		{"sha256 two words", &SHA256{}, long, "852785c805c77e71a22340a54e9d95933ed49121e7d2bf3c2d358854bc1359ea", 84},
+		{"ripemd160 empty", &RIPEMD160{}, nil, "0000000000000000000000009c1185a5c5e9fc54612808977ee8f548b2258d31", 600},
+		{"ripemd160 abc", &RIPEMD160{}, []byte("abc"), "0000000000000000000000008eb208f7e05d987a9b044a8e98c6b087f15a0bfc", 720},
+		{"ripemd160 two words", &RIPEMD160{}, long, "000000000000000000000000ee5a2d952055b942545e9fadb3b5f494e5a01996", 840},
+		{"identity empty", &Identity{}, nil, "", 15},
+		{"identity abc", &Identity{}, []byte("abc"), "616263", 18},
+		{"identity two words", &Identity{}, long, hex.EncodeToString(long), 21},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gas := tt.p.RequiredGas(tt.input); gas != tt.gas {
+				t.Fatalf("expected gas %d, got %d", tt.gas, gas)
+			}
+			output, err := tt.p.Run(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := hex.EncodeToString(output); got != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIdentityCopiesInput(t *testing.T) {
+	input := []byte{1, 2, 3}
+	output, _ := (&Identity{}).Run(input)
+	input[0] = 0xff
+	if !bytes.Equal(output, []byte{1, 2, 3}) {
+		t.Fatalf("expected the output to be a copy, got %x", output)
+	}
+}
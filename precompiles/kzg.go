@@ -0,0 +1,108 @@
+package precompiles
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+
+	goethkzg "github.com/crate-crypto/go-eth-kzg"
+)
+
+// PointEvaluationGas is the fixed cost of a point evaluation call (EIP-4844)
+const PointEvaluationGas = 50000
+
+// pointEvaluationInputLength is the size of versioned_hash || z || y || commitment || proof
+const pointEvaluationInputLength = 192
+
+// blobCommitmentVersionKZG is the version byte of KZG versioned hashes
+const blobCommitmentVersionKZG = 0x01
+
+// Errors
+var (
+	ErrPointEvaluationInputLength   = errors.New("point evaluation: input length is not 192 bytes")
+	ErrPointEvaluationVersionedHash = errors.New("point evaluation: versioned hash does not match the commitment")
+	ErrPointEvaluationProof         = errors.New("point evaluation: invalid KZG proof")
+)
+
+// pointEvaluationOutput is FIELD_ELEMENTS_PER_BLOB || BLS_MODULUS, each as a 32-byte word
+var pointEvaluationOutput = func() []byte {
+	out := make([]byte, 64)
+	out[30] = 0x10
+	modulus := [32]byte{
+		0x73, 0xed, 0xa7, 0x53, 0x29, 0x9d, 0x7d, 0x48, 0x33, 0x39, 0xd8, 0x08, 0x09, 0xa1, 0xd8, 0x05,
+		0x53, 0xbd, 0xa4, 0x02, 0xff, 0xfe, 0x5b, 0xfe, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x01,
+	}
+	copy(out[32:], modulus[:])
+	return out
+}()
+
+// kzgContext holds the mainnet trusted setup, loaded on first use
+var (
+	kzgContext     *goethkzg.Context
+	kzgContextErr  error
+	kzgContextOnce sync.Once
+)
+
+func loadKZGContext() (*goethkzg.Context, error) {
+	kzgContextOnce.Do(func() {
+		kzgContext, kzgContextErr = goethkzg.NewContext4096Secure()
+	})
+	return kzgContext, kzgContextErr
+}
+
+// KZGVersionedHash returns the versioned hash of a KZG commitment: its SHA-256 hash with the
+// first byte replaced by the version
+func KZGVersionedHash(commitment []byte) [32]byte {
+	h := sha256.Sum256(commitment)
+	h[0] = blobCommitmentVersionKZG
+	return h
+}
+
+// PointEvaluation verifies that a blob committed to by a versioned hash evaluates to y at
+// z (EIP-4844), active since Cancun. It uses the trusted setup of the mainnet KZG ceremony.
+type PointEvaluation struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*PointEvaluation) RequiredGas(input []byte) uint64 {
+	return PointEvaluationGas
+}
+
+// Run returns the number of field elements per blob and the BLS modulus for a valid proof
+func (*PointEvaluation) Run(input []byte) ([]byte, error) {
+	if len(input) != pointEvaluationInputLength {
+		return nil, ErrPointEvaluationInputLength
+	}
+
+	var z, y goethkzg.Scalar
+	var commitment goethkzg.KZGCommitment
+	var proof goethkzg.KZGProof
+	copy(z[:], input[32:64])
+	copy(y[:], input[64:96])
+	copy(commitment[:], input[96:144])
+	copy(proof[:], input[144:192])
+
+	if KZGVersionedHash(commitment[:]) != [32]byte(input[:32]) {
+		return nil, ErrPointEvaluationVersionedHash
+	}
+
+	ctx, err := loadKZGContext()
+	if err != nil {
+		return nil, fmt.Errorf("point evaluation: loading the trusted setup: %w", err)
+	}
+	if err := ctx.VerifyKZGProof(commitment, z, y, proof); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPointEvaluationProof, err)
+	}
+	return append([]byte(nil), pointEvaluationOutput...), nil
+}
+
+// Name returns the name of the precompile
+func (*PointEvaluation) Name() string {
+	return "KZG_POINT_EVALUATION"
+}
+
+// DecodeInput returns the versioned hash, the evaluation point and claimed value, the
+// commitment and the proof
+func (*PointEvaluation) DecodeInput(input []byte) []InputField {
+	return splitFields(input, []string{"versionedHash", "z", "y", "commitment", "proof"}, []uint64{32, 32, 32, 48, 48})
+}
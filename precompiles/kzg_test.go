@@ -0,0 +1,55 @@
+package precompiles
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// pointEvaluationInput is versioned_hash || z || y || commitment || proof of a valid proof from
+// the go-ethereum precompile tests
+const pointEvaluationInput = "01e798154708fe7789429634053cbf9f99b619f9f084048927333fce637f549b" +
+	"564c0a11a0f704f4fc3e8acfe0f8245f0ad1347b378fbf96e206da11a5d36306" +
+	"24d25032e67a7e6a4910df5834b8fe70e6bcfeeac0352434196bdf4b2485d5a1" +
+	"8f59a8d2a1a625a17f3fea0fe5eb8c896db3764f3185481bc22f91b4aaffcca25f26936857bc3a7c2539ea8ec3a952b7" +
+	"873033e038326e87ed3e1276fd140253fa08e9fc25fb2d9a98527fc22a2c9612fbeafdad446cbc7bcdbdcd780af2c16a"
+
+func TestPointEvaluation(t *testing.T) {
+	valid, _ := hex.DecodeString(pointEvaluationInput)
+	expected, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000001000" +
+		"73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001")
+
+	p := &PointEvaluation{}
+	if gas := p.RequiredGas(valid); gas != PointEvaluationGas {
+		t.Fatalf("expected gas %d, got %d", PointEvaluationGas, gas)
+	}
+	out, err := p.Run(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Fatalf("expected %x, got %x", expected, out)
+	}
+
+	wrongVersion := bytes.Clone(valid)
+	wrongVersion[0] = 0x02
+	wrongClaim := bytes.Clone(valid)
+	wrongClaim[95] ^= 0x01
+
+	tests := []struct {
+		name  string
+		input []byte
+		error error
+	}{
+		{"short", valid[:191], ErrPointEvaluationInputLength},
+		{"long", append(bytes.Clone(valid), 0x00), ErrPointEvaluationInputLength},
+		{"versioned hash", wrongVersion, ErrPointEvaluationVersionedHash},
+		{"wrong claim", wrongClaim, ErrPointEvaluationProof},
+	}
+	for _, tt := range tests {
+		if _, err := p.Run(tt.input); !errors.Is(err, tt.error) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.error, err)
+		}
+	}
+}
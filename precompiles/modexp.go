@@ -0,0 +1,211 @@
+package precompiles
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"math/bits"
+
+	"github.com/holiman/uint256"
+)
+
+// ModExpMaxInputLength is the largest base, exponent and modulus length accepted since
+// Osaka (EIP-7823)
+const ModExpMaxInputLength = 1024
+
+// ModExpMaxPadding is how far the operands may extend past the end of the input. The zero
+// padding of longer operands costs more gas than a mainnet block holds, but calls without
+// gas metering would allocate it.
+const ModExpMaxPadding = 1 << 27
+
+// Errors
+var (
+	ErrModExpInputTooLarge   = errors.New("modexp base, exponent or modulus length exceeds 1024 bytes")
+	ErrModExpOutOfGas        = errors.New("modexp gas cost overflows")
+	ErrModExpPaddingTooLarge = errors.New("modexp operands extend too far past the input")
+)
+
+// ModExp computes base**exp % mod for arbitrary sized integers (EIP-198). The flags enable
+// the Berlin repricing (EIP-2565) and the Osaka input bounds (EIP-7823) and repricing
+// (EIP-7883), which supersedes EIP-2565.
+type ModExp struct {
+	EIP2565 bool
+	EIP7823 bool
+	EIP7883 bool
+}
+
+// modExpHeader returns the base, exponent and modulus lengths, saturated at MaxUint64
+func modExpHeader(input []byte) (baseLen, expLen, modLen uint64) {
+	length := func(offset uint64) uint64 {
+		l := new(uint256.Int).SetBytes(getData(input, offset, 32))
+		if !l.IsUint64() {
+			return math.MaxUint64
+		}
+		return l.Uint64()
+	}
+	return length(0), length(32), length(64)
+}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (c *ModExp) RequiredGas(input []byte) uint64 {
+	baseLen, expLen, modLen := modExpHeader(input)
+
+	// The first 32 bytes of the exponent determine the adjusted exponent length
+	var expHead uint256.Int
+	body := input[min(len(input), 96):]
+	if uint64(len(body)) > baseLen {
+		expHead.SetBytes(getData(body, baseLen, min(expLen, 32)))
+	}
+
+	maxLen := max(baseLen, modLen)
+	switch {
+	case c.EIP7883:
+		return modExpGas(osakaMultComplexity(maxLen), modExpIterationCount(expLen, &expHead, 16), 1, 500)
+	case c.EIP2565:
+		return modExpGas(berlinMultComplexity(maxLen), modExpIterationCount(expLen, &expHead, 8), 3, 200)
+	}
+	return modExpGas(byzantiumMultComplexity(maxLen), modExpIterationCount(expLen, &expHead, 8), 20, 0)
+}
+
+// Run executes the precompile
+func (c *ModExp) Run(input []byte) ([]byte, error) {
+	baseLen, expLen, modLen := modExpHeader(input)
+	if c.EIP7823 && max(baseLen, expLen, modLen) > ModExpMaxInputLength {
+		return nil, ErrModExpInputTooLarge
+	}
+	if c.RequiredGas(input) == math.MaxUint64 {
+		return nil, ErrModExpOutOfGas
+	}
+
+	if modLen == 0 {
+		return []byte{}, nil
+	}
+
+	body := input[min(len(input), 96):]
+	if operandsLen(baseLen, expLen, modLen) > uint64(len(body))+ModExpMaxPadding {
+		return nil, ErrModExpPaddingTooLarge
+	}
+	base := new(big.Int).SetBytes(getData(body, 0, baseLen))
+	exp := new(big.Int).SetBytes(getData(body, baseLen, expLen))
+	mod := new(big.Int).SetBytes(getData(body, baseLen+expLen, modLen))
+
+	// Modulo 0 is defined as 0
+	if mod.Sign() == 0 {
+		return make([]byte, modLen), nil
+	}
+	return leftPad(new(big.Int).Exp(base, exp, mod).Bytes(), int(modLen)), nil
+}
+
+// operandsLen returns the total length of the operands, saturated at MaxUint64
+func operandsLen(baseLen, expLen, modLen uint64) uint64 {
+	sum, carry := bits.Add64(baseLen, expLen, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	sum, carry = bits.Add64(sum, modLen, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+// modExpGas returns max(complexity * iterations / divisor, minGas), saturating at MaxUint64
+func modExpGas(complexity, iterations, divisor, minGas uint64) uint64 {
+	if complexity == math.MaxUint64 {
+		return math.MaxUint64
+	}
+	hi, gas := bits.Mul64(complexity, iterations)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return max(gas/divisor, minGas)
+}
+
+// modExpIterationCount returns the adjusted exponent length, at least 1
+func modExpIterationCount(expLen uint64, expHead *uint256.Int, multiplier uint64) uint64 {
+	var count uint64
+	if expLen > 32 {
+		hi, lo := bits.Mul64(expLen-32, multiplier)
+		if hi != 0 {
+			return math.MaxUint64
+		}
+		count = lo
+	}
+	if bitLen := expHead.BitLen(); bitLen > 0 {
+		sum, carry := bits.Add64(count, uint64(bitLen-1), 0)
+		if carry != 0 {
+			return math.MaxUint64
+		}
+		count = sum
+	}
+	return max(count, 1)
+}
+
+// byzantiumMultComplexity is the EIP-198 multiplication complexity of x byte operands
+func byzantiumMultComplexity(x uint64) uint64 {
+	switch {
+	case x <= 64:
+		return x * x
+	case x <= 1024:
+		return x*x/4 + 96*x - 3072
+	default:
+		hi, sq := bits.Mul64(x, x)
+		if hi != 0 {
+			return math.MaxUint64
+		}
+		sum, carry := bits.Add64(sq/16, 480*x-199680, 0)
+		if carry != 0 {
+			return math.MaxUint64
+		}
+		return sum
+	}
+}
+
+// berlinMultComplexity is the EIP-2565 multiplication complexity: ceil(x/8)^2
+func berlinMultComplexity(x uint64) uint64 {
+	words := x/8 + min(x%8, 1)
+	hi, sq := bits.Mul64(words, words)
+	if hi != 0 {
+		return math.MaxUint64
+	}
+	return sq
+}
+
+// osakaMultComplexity is the EIP-7883 multiplication complexity: 16 for operands of up to
+// 32 bytes, 2 * ceil(x/8)^2 above
+func osakaMultComplexity(x uint64) uint64 {
+	if x <= 32 {
+		return 16
+	}
+	words := x/8 + min(x%8, 1)
+	hi, sq := bits.Mul64(words, words)
+	if hi != 0 || sq > math.MaxUint64/2 {
+		return math.MaxUint64
+	}
+	return 2 * sq
+}
+
+// Name returns the name of the precompile
+func (c *ModExp) Name() string {
+	return "MODEXP"
+}
+
+// DecodeInput returns the operand lengths and the operands. Operands longer than the
+// Osaka bound of 1024 bytes are not decoded.
+func (c *ModExp) DecodeInput(input []byte) []InputField {
+	baseLen, expLen, modLen := modExpHeader(input)
+	fields := []InputField{
+		uintField("baseLen", baseLen),
+		uintField("expLen", expLen),
+		uintField("modLen", modLen),
+	}
+	if max(baseLen, expLen, modLen) > ModExpMaxInputLength {
+		return fields
+	}
+	body := input[min(len(input), 96):]
+	return append(fields,
+		hexField("base", getData(body, 0, baseLen)),
+		hexField("exp", getData(body, baseLen, expLen)),
+		hexField("mod", getData(body, baseLen+expLen, modLen)),
+	)
+}
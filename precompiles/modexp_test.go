@@ -0,0 +1,181 @@
+package precompiles
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+)
+
+// modexp vectors from the EIP-198, EIP-2565 and EIP-7883 test suites
+var modExpTests = []struct {
+	name      string
+	input     string
+	expected  string
+	gas       uint64 // EIP-198 pricing
+	berlinGas uint64 // EIP-2565 pricing
+	osakaGas  uint64 // EIP-7883 pricing
+}{
+	{
+		name: "eip_example1",
+		input: "0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"03" +
+			"fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2e" +
+			"fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f",
+		expected:  "0000000000000000000000000000000000000000000000000000000000000001",
+		gas:       13056,
+		berlinGas: 1360,
+		osakaGas:  4080,
+	},
+	{
+		name: "nagydani-1-pow0x10001",
+		input: "0000000000000000000000000000000000000000000000000000000000000040" +
+			"0000000000000000000000000000000000000000000000000000000000000003" +
+			"0000000000000000000000000000000000000000000000000000000000000040" +
+			"e09ad9675465c53a109fac66a445c91b292d2bb2c5268addb30cd82f80fcb003" +
+			"3ff97c80a5fc6f39193ae969c6ede6710a6b7ac27078a06d90ef1c72e5c85fb5" +
+			"010001" +
+			"fc9e1f6beb81516545975218075ec2af118cd8798df6e08a147c60fd6095ac2b" +
+			"b02c2908cf4dd7c81f11c289e4bce98f3553768f392a80ce22bf5c4f4a248c6b",
+		expected: "c36d804180c35d4426b57b50c5bfcca5c01856d104564cd513b461d3c8b84091" +
+			"28a5573e416d0ebe38f5f736766d9dc27143e4da981dfa4d67f7dc474cbee6d2",
+		gas:       3276,
+		berlinGas: 341,
+		osakaGas:  2048,
+	},
+	{
+		name: "marius-1-even",
+		input: "0000000000000000000000000000000000000000000000000000000000000003" +
+			"00000000000000000000000000000000000000000000000000000000000000c1" +
+			"000000000000000000000000000000000000000000000000000000000000000c" +
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe000007d7d7d83828282348286877d7d827d407d797d7d7d7d7d7d7d7d7d7d7d5b00000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000000000000000000000000000000000021000000000000000000000000000000000000000000000000000000000000000cffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff4000007d7d7d83828282348286877d7d82",
+		expected:  "36a385a417859b5e178d3ab9",
+		gas:       11109,
+		berlinGas: 2057,
+		osakaGas:  45296,
+	},
+	{
+		name: "mod-32-exp-65",
+		input: "0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000009" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"00ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" +
+			"01ffffffffffffffff" +
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff00",
+		expected:  "00ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		gas:       3276,
+		berlinGas: 341,
+		osakaGas:  1024,
+	},
+}
+
+func TestModExp(t *testing.T) {
+	for _, tt := range modExpTests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := hex.DecodeString(tt.input)
+			expected, _ := hex.DecodeString(tt.expected)
+
+			byzantium := &ModExp{}
+			berlin := &ModExp{EIP2565: true}
+			osaka := &ModExp{EIP2565: true, EIP7823: true, EIP7883: true}
+
+			if gas := byzantium.RequiredGas(input); gas != tt.gas {
+				t.Errorf("expected EIP-198 gas %d, got %d", tt.gas, gas)
+			}
+			if gas := berlin.RequiredGas(input); gas != tt.berlinGas {
+				t.Errorf("expected EIP-2565 gas %d, got %d", tt.berlinGas, gas)
+			}
+			if gas := osaka.RequiredGas(input); gas != tt.osakaGas {
+				t.Errorf("expected EIP-7883 gas %d, got %d", tt.osakaGas, gas)
+			}
+
+			out, err := osaka.Run(input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(out, expected) {
+				t.Fatalf("expected %x, got %x", expected, out)
+			}
+		})
+	}
+}
+
+func TestModExpOsakaMinimumGas(t *testing.T) {
+	// 2**1 % 3 with one byte operands
+	input, _ := hex.DecodeString(
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"020103")
+
+	if gas := (&ModExp{EIP7883: true}).RequiredGas(input); gas != 500 {
+		t.Fatalf("expected minimum gas 500, got %d", gas)
+	}
+	out, err := (&ModExp{}).Run(input)
+	if err != nil || !bytes.Equal(out, []byte{0x02}) {
+		t.Fatalf("expected 02, got %x (%v)", out, err)
+	}
+}
+
+func TestModExpBerlinMinimumGas(t *testing.T) {
+	// 2**1 % 3 with one byte operands
+	input, _ := hex.DecodeString(
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"020103")
+
+	if gas := (&ModExp{EIP2565: true}).RequiredGas(input); gas != 200 {
+		t.Fatalf("expected minimum gas 200, got %d", gas)
+	}
+}
+
+func TestModExpInputBounds(t *testing.T) {
+	// A 1025 byte modulus exceeds the EIP-7823 bound
+	input, _ := hex.DecodeString(
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000401")
+	input = append(input, 0x02, 0x01)
+	input = append(input, make([]byte, 1024)...)
+	input = append(input, 0x03)
+
+	if _, err := (&ModExp{EIP7823: true}).Run(input); !errors.Is(err, ErrModExpInputTooLarge) {
+		t.Fatalf("expected ErrModExpInputTooLarge, got %v", err)
+	}
+
+	out, err := (&ModExp{}).Run(input)
+	if err != nil {
+		t.Fatalf("expected no bound before Osaka, got %v", err)
+	}
+	if len(out) != 1025 || out[1024] != 0x02 {
+		t.Fatalf("expected 2 padded to 1025 bytes, got %x", out)
+	}
+}
+
+func TestModExpPaddingBound(t *testing.T) {
+	// A 2**33 byte base with a one byte modulus, priced below MaxUint64 before Osaka
+	header, _ := hex.DecodeString(
+		"0000000000000000000000000000000000000000000000000000000200000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000")
+	input := append(append([]byte{}, header...), make([]byte, 31)...)
+	input = append(input, 0x01, 0x02)
+
+	berlin := &ModExp{EIP2565: true}
+	if gas := berlin.RequiredGas(input); gas == math.MaxUint64 {
+		t.Fatal("expected the gas cost not to saturate")
+	}
+	if _, err := berlin.Run(input); !errors.Is(err, ErrModExpPaddingTooLarge) {
+		t.Fatalf("expected ErrModExpPaddingTooLarge, got %v", err)
+	}
+
+	// Without a modulus the result is empty, the operands are not read
+	input[95] = 0x00
+	out, err := berlin.Run(input)
+	if err != nil || len(out) != 0 {
+		t.Fatalf("expected an empty result, got %x (%v)", out, err)
+	}
+}
@@ -0,0 +1,50 @@
+package precompiles
+
+import (
+	"math/big"
+
+	"github.com/daniellehrner/evmdbg/crypto"
+)
+
+// P256VerifyGas is the fixed cost of a P256VERIFY call (EIP-7951)
+const P256VerifyGas = 6900
+
+// p256VerifyInputLength is the size of hash || r || s || x || y
+const p256VerifyInputLength = 160
+
+// P256Verify verifies secp256r1 signatures (EIP-7951), active since Osaka
+type P256Verify struct{}
+
+// RequiredGas returns the gas needed to execute the precompile
+func (*P256Verify) RequiredGas(input []byte) uint64 {
+	return P256VerifyGas
+}
+
+// Run returns 1 as a 32-byte word for a valid signature and empty output otherwise.
+// Invalid inputs never fail the call.
+func (*P256Verify) Run(input []byte) ([]byte, error) {
+	if len(input) != p256VerifyInputLength {
+		return nil, nil
+	}
+
+	hash := input[0:32]
+	r := new(big.Int).SetBytes(input[32:64])
+	s := new(big.Int).SetBytes(input[64:96])
+	x := new(big.Int).SetBytes(input[96:128])
+	y := new(big.Int).SetBytes(input[128:160])
+
+	if !crypto.P256Verify(hash, r, s, x, y) {
+		return nil, nil
+	}
+	return leftPad([]byte{1}, 32), nil
+}
+
+// Name returns the name of the precompile
+func (*P256Verify) Name() string {
+	return "P256VERIFY"
+}
+
+// DecodeInput returns the hash, the signature and the public key
+func (*P256Verify) DecodeInput(input []byte) []InputField {
+	return splitFields(input, []string{"hash", "r", "s", "x", "y"}, []uint64{32, 32, 32, 32, 32})
+}
@@ -0,0 +1,52 @@
+package precompiles
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// p256VerifyInput is hash || r || s || x || y of a valid signature from the EIP-7951 test suite
+const p256VerifyInput = "4cee90eb86eaa050036147a12d49004b6b9c72bd725d39d4785011fe190f0b4d" +
+	"a73bd4903f0ce3b639bbbf6e8e80d16931ff4bcf5993d58468e8fb19086e8cac" +
+	"36dbcd03009df8c59286b162af3bd7fcc0450c9aa81be5d10d312af6c66b1d60" +
+	"4aebd3099c618202fcfe16ae7770b0c49ab5eadf74b754204a3bb6060e44eff3" +
+	"7618b065f9832de4ca6ca971a7a1adc826d0f7c00181a5fb2ddf79ae00b4e10e"
+
+func TestP256Verify(t *testing.T) {
+	valid, _ := hex.DecodeString(p256VerifyInput)
+
+	tampered := bytes.Clone(valid)
+	tampered[0] ^= 0x01
+
+	infinity := bytes.Clone(valid)
+	copy(infinity[96:], make([]byte, 64))
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected []byte
+	}{
+		{"valid signature", valid, leftPad([]byte{1}, 32)},
+		{"tampered hash", tampered, nil},
+		{"point at infinity", infinity, nil},
+		{"short input", valid[:159], nil},
+		{"long input", append(bytes.Clone(valid), 0x00), nil},
+	}
+
+	p := &P256Verify{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gas := p.RequiredGas(tt.input); gas != P256VerifyGas {
+				t.Fatalf("expected gas %d, got %d", P256VerifyGas, gas)
+			}
+			out, err := p.Run(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(out, tt.expected) {
+				t.Fatalf("expected %x, got %x", tt.expected, out)
+			}
+		})
+	}
+}
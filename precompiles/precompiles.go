@@ -0,0 +1,53 @@
+// Package precompiles implements the precompiled contracts of the Ethereum mainnet
+package precompiles
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// getData returns size bytes of data starting at start, padded with zeroes past its end
+func getData(data []byte, start, size uint64) []byte {
+	out := make([]byte, size)
+	if start < uint64(len(data)) {
+		copy(out, data[start:])
+	}
+	return out
+}
+
+// leftPad returns b left-padded with zeroes to size bytes
+func leftPad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	out := make([]byte, size)
+	copy(out[size-len(b):], b)
+	return out
+}
+
+// InputField is a named field of a decoded precompile input, formatted for display
+type InputField struct {
+	Name  string
+	Value string
+}
+
+// hexField formats b as a 0x-prefixed hex string
+func hexField(name string, b []byte) InputField {
+	return InputField{Name: name, Value: fmt.Sprintf("0x%x", b)}
+}
+
+// uintField formats v as a decimal number
+func uintField(name string, v uint64) InputField {
+	return InputField{Name: name, Value: strconv.FormatUint(v, 10)}
+}
+
+// splitFields names consecutive fields of the given sizes in input, right-padded with zeroes
+func splitFields(input []byte, names []string, sizes []uint64) []InputField {
+	fields := make([]InputField, 0, len(names))
+	var offset uint64
+	for i, name := range names {
+		fields = append(fields, hexField(name, getData(input, offset, sizes[i])))
+		offset += sizes[i]
+	}
+	return fields
+}
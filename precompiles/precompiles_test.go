@@ -0,0 +1,40 @@
+package precompiles
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestDecodeInput(t *testing.T) {
+	ecrecover, _ := hex.DecodeString(ecrecoverInput)
+	blake2f, _ := hex.DecodeString(blake2FTests[1].input)
+	// A modulus length beyond the Osaka bound
+	hugeModExp, _ := hex.DecodeString(
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000401")
+
+	tests := []struct {
+		name   string
+		fields []InputField
+		index  int
+		field  InputField
+		count  int
+	}{
+		{"ecrecover v", (&Ecrecover{}).DecodeInput(ecrecover), 1, InputField{"v", "0x000000000000000000000000000000000000000000000000000000000000001c"}, 4},
+		{"ecrecover short input is padded", (&Ecrecover{}).DecodeInput(ecrecover[:32]), 3, InputField{"s", "0x0000000000000000000000000000000000000000000000000000000000000000"}, 4},
+		{"blake2f rounds", (&Blake2F{}).DecodeInput(blake2f), 0, InputField{"rounds", "12"}, 5},
+		{"blake2f final flag", (&Blake2F{}).DecodeInput(blake2f), 4, InputField{"f", "0x01"}, 5},
+		{"modexp lengths only", (&ModExp{}).DecodeInput(hugeModExp), 2, InputField{"modLen", "1025"}, 3},
+		{"bn254 pairing pairs", (&BN254Pairing{}).DecodeInput(make([]byte, 2*bn254PairInputLength)), 0, InputField{"pairs", "2"}, 1},
+	}
+	for _, tt := range tests {
+		if len(tt.fields) != tt.count {
+			t.Errorf("%s: expected %d fields, got %d", tt.name, tt.count, len(tt.fields))
+			continue
+		}
+		if got := tt.fields[tt.index]; got != tt.field {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.field, got)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package rlp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/holiman/uint256"
+)
+
+// Errors
+var (
+	ErrUnexpectedEOF  = errors.New("rlp: unexpected end of input")
+	ErrNonCanonical   = errors.New("rlp: non-canonical encoding")
+	ErrExpectedString = errors.New("rlp: expected string")
+	ErrExpectedList   = errors.New("rlp: expected list")
+)
+
+// Kind represents the type of an RLP item
+type Kind int
+
+const (
+	String Kind = iota
+	List
+)
+
+// EncodeBytes encodes a byte string
+func EncodeBytes(b []byte) []byte {
+	// A single byte below 0x80 is its own encoding
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(encodeHeader(0x80, uint64(len(b))), b...)
+}
+
+// EncodeUint64 encodes an unsigned integer using its minimal big-endian representation
+func EncodeUint64(u uint64) []byte {
+	if u == 0 {
+		return []byte{0x80}
+	}
+	return EncodeBytes(trimmedUint64(u))
+}
+
+// EncodeUint256 encodes a 256-bit unsigned integer using its minimal big-endian representation
+func EncodeUint256(u *uint256.Int) []byte {
+	if u == nil || u.IsZero() {
+		return []byte{0x80}
+	}
+	return EncodeBytes(u.Bytes())
+}
+
+// EncodeList wraps already encoded items into a list
+func EncodeList(items ...[]byte) []byte {
+	size := 0
+	for _, item := range items {
+		size += len(item)
+	}
+
+	out := encodeHeader(0xc0, uint64(size))
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// encodeHeader writes the prefix for a string (0x80) or list (0xc0) of the given size
+func encodeHeader(offset byte, size uint64) []byte {
+	if size < 56 {
+		return []byte{offset + byte(size)}
+	}
+	sizeBytes := trimmedUint64(size)
+	header := make([]byte, 1, 1+len(sizeBytes))
+	header[0] = offset + 55 + byte(len(sizeBytes))
+	return append(header, sizeBytes...)
+}
+
+func trimmedUint64(u uint64) []byte {
+	var out []byte
+	for u > 0 {
+		out = append([]byte{byte(u)}, out...)
+		u >>= 8
+	}
+	return out
+}
+
+// Split returns the kind and content of the first item in b and the remaining bytes after it
+func Split(b []byte) (Kind, []byte, []byte, error) {
+	if len(b) == 0 {
+		return 0, nil, nil, ErrUnexpectedEOF
+	}
+
+	prefix := b[0]
+	switch {
+	case prefix < 0x80:
+		return String, b[:1], b[1:], nil
+	case prefix < 0xb8:
+		size := uint64(prefix - 0x80)
+		if size == 1 && len(b) > 1 && b[1] < 0x80 {
+			return 0, nil, nil, ErrNonCanonical
+		}
+		return splitContent(String, b, 1, size)
+	case prefix < 0xc0:
+		size, err := readSize(b[1:], int(prefix-0xb7))
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		return splitContent(String, b, 1+int(prefix-0xb7), size)
+	case prefix < 0xf8:
+		return splitContent(List, b, 1, uint64(prefix-0xc0))
+	default:
+		size, err := readSize(b[1:], int(prefix-0xf7))
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		return splitContent(List, b, 1+int(prefix-0xf7), size)
+	}
+}
+
+func splitContent(kind Kind, b []byte, headerSize int, size uint64) (Kind, []byte, []byte, error) {
+	if uint64(len(b)-headerSize) < size {
+		return 0, nil, nil, ErrUnexpectedEOF
+	}
+	end := headerSize + int(size)
+	return kind, b[headerSize:end], b[end:], nil
+}
+
+func readSize(b []byte, n int) (uint64, error) {
+	if len(b) < n {
+		return 0, ErrUnexpectedEOF
+	}
+	if b[0] == 0 {
+		return 0, ErrNonCanonical
+	}
+
+	var size uint64
+	for i := 0; i < n; i++ {
+		size = size<<8 | uint64(b[i])
+	}
+	if size < 56 {
+		return 0, ErrNonCanonical
+	}
+	return size, nil
+}
+
+// SplitString returns the content of the string at the start of b and the remaining bytes
+func SplitString(b []byte) ([]byte, []byte, error) {
+	kind, content, rest, err := Split(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if kind != String {
+		return nil, nil, ErrExpectedString
+	}
+	return content, rest, nil
+}
+
+// SplitList returns the content of the list at the start of b and the remaining bytes
+func SplitList(b []byte) ([]byte, []byte, error) {
+	kind, content, rest, err := Split(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if kind != List {
+		return nil, nil, ErrExpectedList
+	}
+	return content, rest, nil
+}
+
+// SplitUint64 decodes an unsigned integer at the start of b and returns the remaining bytes
+func SplitUint64(b []byte) (uint64, []byte, error) {
+	content, rest, err := SplitString(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(content) > 8 {
+		return 0, nil, fmt.Errorf("rlp: integer too large for uint64: %d bytes", len(content))
+	}
+	if len(content) > 0 && content[0] == 0 {
+		return 0, nil, ErrNonCanonical
+	}
+
+	var u uint64
+	for _, c := range content {
+		u = u<<8 | uint64(c)
+	}
+	return u, rest, nil
+}
+
+// SplitUint256 decodes a 256-bit unsigned integer at the start of b and returns the remaining bytes
+func SplitUint256(b []byte) (*uint256.Int, []byte, error) {
+	content, rest, err := SplitString(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(content) > 32 {
+		return nil, nil, fmt.Errorf("rlp: integer too large for uint256: %d bytes", len(content))
+	}
+	if len(content) > 0 && content[0] == 0 {
+		return nil, nil, ErrNonCanonical
+	}
+	return new(uint256.Int).SetBytes(content), rest, nil
+}
+
+// ListItems splits the content of a list into its raw encoded items
+func ListItems(b []byte) ([][]byte, error) {
+	content, rest, err := SplitList(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("rlp: %d trailing bytes after list", len(rest))
+	}
+
+	var items [][]byte
+	for len(content) > 0 {
+		_, _, next, err := Split(content)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, content[:len(content)-len(next)])
+		content = next
+	}
+	return items, nil
+}
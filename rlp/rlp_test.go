@@ -0,0 +1,120 @@
+package rlp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestEncodeBytes(t *testing.T) {
+	long := bytes.Repeat([]byte{0xaa}, 56)
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected []byte
+	}{
+		{"empty", nil, []byte{0x80}},
+		{"single byte below 0x80", []byte{0x7f}, []byte{0x7f}},
+		{"single byte 0x80", []byte{0x80}, []byte{0x81, 0x80}},
+		{"short string", []byte("dog"), []byte{0x83, 'd', 'o', 'g'}},
+		{"long string", long, append([]byte{0xb8, 56}, long...)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := EncodeBytes(tc.input)
+			if !bytes.Equal(got, tc.expected) {
+				t.Fatalf("expected %x, got %x", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestEncodeIntegers(t *testing.T) {
+	if got := EncodeUint64(0); !bytes.Equal(got, []byte{0x80}) {
+		t.Fatalf("expected 80, got %x", got)
+	}
+	if got := EncodeUint64(15); !bytes.Equal(got, []byte{0x0f}) {
+		t.Fatalf("expected 0f, got %x", got)
+	}
+	if got := EncodeUint64(1024); !bytes.Equal(got, []byte{0x82, 0x04, 0x00}) {
+		t.Fatalf("expected 820400, got %x", got)
+	}
+	if got := EncodeUint256(uint256.NewInt(0x0400)); !bytes.Equal(got, []byte{0x82, 0x04, 0x00}) {
+		t.Fatalf("expected 820400, got %x", got)
+	}
+	if got := EncodeUint256(nil); !bytes.Equal(got, []byte{0x80}) {
+		t.Fatalf("expected 80, got %x", got)
+	}
+}
+
+func TestEncodeList(t *testing.T) {
+	// ["cat", "dog"]
+	got := EncodeList(EncodeBytes([]byte("cat")), EncodeBytes([]byte("dog")))
+	expected := []byte{0xc8, 0x83, 'c', 'a', 't', 0x83, 'd', 'o', 'g'}
+	if !bytes.Equal(got, expected) {
+		t.Fatalf("expected %x, got %x", expected, got)
+	}
+
+	if got := EncodeList(); !bytes.Equal(got, []byte{0xc0}) {
+		t.Fatalf("expected c0, got %x", got)
+	}
+}
+
+func TestListItemsRoundTrip(t *testing.T) {
+	long := bytes.Repeat([]byte{0x01}, 100)
+	enc := EncodeList(EncodeBytes([]byte("cat")), EncodeUint64(1024), EncodeBytes(long), EncodeList())
+
+	items, err := ListItems(enc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(items))
+	}
+
+	s, _, err := SplitString(items[0])
+	if err != nil || string(s) != "cat" {
+		t.Fatalf("expected cat, got %q (err %v)", s, err)
+	}
+	u, _, err := SplitUint64(items[1])
+	if err != nil || u != 1024 {
+		t.Fatalf("expected 1024, got %d (err %v)", u, err)
+	}
+	s, _, err = SplitString(items[2])
+	if err != nil || !bytes.Equal(s, long) {
+		t.Fatalf("long string mismatch (err %v)", err)
+	}
+	if _, _, err := SplitList(items[3]); err != nil {
+		t.Fatalf("expected empty list, got error %v", err)
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"empty input", []byte{}},
+		{"truncated string", []byte{0x83, 'd', 'o'}},
+		{"non-canonical single byte", []byte{0x81, 0x01}},
+		{"non-canonical long size", []byte{0xb8, 0x01, 0x00}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, _, err := Split(tc.input); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+
+	if _, _, err := SplitList([]byte{0x80}); err != ErrExpectedList {
+		t.Fatalf("expected ErrExpectedList, got %v", err)
+	}
+	if _, _, err := SplitUint64([]byte{0x82, 0x00, 0x01}); err != ErrNonCanonical {
+		t.Fatalf("expected ErrNonCanonical, got %v", err)
+	}
+}
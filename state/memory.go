@@ -0,0 +1,190 @@
+package state
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// Account is the in-memory representation of an account
+type Account struct {
+	Nonce   uint64
+	Balance *uint256.Int
+	Code    []byte
+	Storage map[[32]byte]*uint256.Int
+}
+
+// MemoryState is an in-memory implementation of vm.StateProvider that can compute state and storage roots
+type MemoryState struct {
+	accounts    map[[20]byte]*Account
+	blockHashes map[uint64][32]byte
+	snapshots   []map[[20]byte]*Account
+}
+
+var (
+	_ vm.StateProvider = (*MemoryState)(nil)
+	_ vm.CodeWriter    = (*MemoryState)(nil)
+	_ vm.Snapshotter   = (*MemoryState)(nil)
+)
+
+func NewMemoryState() *MemoryState {
+	return &MemoryState{
+		accounts:    make(map[[20]byte]*Account),
+		blockHashes: make(map[uint64][32]byte),
+	}
+}
+
+// AddAccount adds an account with the given code and balance, replacing any existing account
+func (s *MemoryState) AddAccount(addr [20]byte, code []byte, balance *uint256.Int) {
+	_ = s.CreateAccount(addr, code, balance)
+}
+
+// Account returns the account at addr, or nil if it does not exist
+func (s *MemoryState) Account(addr [20]byte) *Account {
+	return s.accounts[addr]
+}
+
+// Addresses returns the addresses of all existing accounts in ascending order
+func (s *MemoryState) Addresses() [][20]byte {
+	addrs := make([][20]byte, 0, len(s.accounts))
+	for addr := range s.accounts {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
+// SetBlockHash sets the hash returned by GetBlockHash for the given block number
+func (s *MemoryState) SetBlockHash(blockNumber uint64, hash [32]byte) {
+	s.blockHashes[blockNumber] = hash
+}
+
+func (s *MemoryState) GetBalance(addr [20]byte) *uint256.Int {
+	if acc, ok := s.accounts[addr]; ok {
+		return new(uint256.Int).Set(acc.Balance)
+	}
+	return uint256.NewInt(0)
+}
+
+func (s *MemoryState) GetCode(addr [20]byte) []byte {
+	if acc, ok := s.accounts[addr]; ok {
+		return acc.Code
+	}
+	return nil
+}
+
+func (s *MemoryState) GetStorage(addr [20]byte, key *uint256.Int) *uint256.Int {
+	if acc, ok := s.accounts[addr]; ok {
+		if val, ok := acc.Storage[key.Bytes32()]; ok {
+			return new(uint256.Int).Set(val)
+		}
+	}
+	return uint256.NewInt(0)
+}
+
+func (s *MemoryState) SetStorage(addr [20]byte, key *uint256.Int, value *uint256.Int) {
+	acc := s.getOrNewAccount(addr)
+	if value.IsZero() {
+		delete(acc.Storage, key.Bytes32())
+		return
+	}
+	acc.Storage[key.Bytes32()] = new(uint256.Int).Set(value)
+}
+
+func (s *MemoryState) AccountExists(addr [20]byte) bool {
+	_, ok := s.accounts[addr]
+	return ok
+}
+
+func (s *MemoryState) GetBlockHash(blockNumber uint64) [32]byte {
+	return s.blockHashes[blockNumber]
+}
+
+func (s *MemoryState) CreateAccount(addr [20]byte, code []byte, balance *uint256.Int) error {
+	if balance == nil {
+		balance = uint256.NewInt(0)
+	}
+	s.accounts[addr] = &Account{
+		Balance: new(uint256.Int).Set(balance),
+		Code:    code,
+		Storage: make(map[[32]byte]*uint256.Int),
+	}
+	return nil
+}
+
+func (s *MemoryState) GetNonce(addr [20]byte) uint64 {
+	if acc, ok := s.accounts[addr]; ok {
+		return acc.Nonce
+	}
+	return 0
+}
+
+func (s *MemoryState) SetNonce(addr [20]byte, nonce uint64) {
+	s.getOrNewAccount(addr).Nonce = nonce
+}
+
+func (s *MemoryState) SetBalance(addr [20]byte, balance *uint256.Int) {
+	s.getOrNewAccount(addr).Balance = new(uint256.Int).Set(balance)
+}
+
+func (s *MemoryState) DeleteAccount(addr [20]byte) error {
+	delete(s.accounts, addr)
+	return nil
+}
+
+func (s *MemoryState) getOrNewAccount(addr [20]byte) *Account {
+	acc, ok := s.accounts[addr]
+	if !ok {
+		acc = &Account{
+			Balance: uint256.NewInt(0),
+			Storage: make(map[[32]byte]*uint256.Int),
+		}
+		s.accounts[addr] = acc
+	}
+	return acc
+}
+
+// SetCode replaces the code of the account at addr, creating the account if needed
+func (s *MemoryState) SetCode(addr [20]byte, code []byte) {
+	s.getOrNewAccount(addr).Code = code
+}
+
+// Snapshot records the current state and returns an identifier for RevertToSnapshot
+func (s *MemoryState) Snapshot() int {
+	snapshot := make(map[[20]byte]*Account, len(s.accounts))
+	for addr, acc := range s.accounts {
+		storage := make(map[[32]byte]*uint256.Int, len(acc.Storage))
+		for key, val := range acc.Storage {
+			storage[key] = new(uint256.Int).Set(val)
+		}
+		snapshot[addr] = &Account{
+			Nonce:   acc.Nonce,
+			Balance: new(uint256.Int).Set(acc.Balance),
+			Code:    acc.Code,
+			Storage: storage,
+		}
+	}
+	s.snapshots = append(s.snapshots, snapshot)
+	return len(s.snapshots) - 1
+}
+
+// RevertToSnapshot restores the state recorded by Snapshot and discards all later snapshots
+func (s *MemoryState) RevertToSnapshot(id int) {
+	if id < 0 || id >= len(s.snapshots) {
+		return
+	}
+	s.accounts = s.snapshots[id]
+	s.snapshots = s.snapshots[:id]
+}
+
+// DiscardSnapshot releases the snapshot and all later snapshots, keeping the current state
+func (s *MemoryState) DiscardSnapshot(id int) {
+	if id < 0 || id >= len(s.snapshots) {
+		return
+	}
+	s.snapshots = s.snapshots[:id]
+}
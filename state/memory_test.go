@@ -0,0 +1,33 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestMemoryStateSnapshots(t *testing.T) {
+	s := NewMemoryState()
+	addr := [20]byte{0xaa}
+	s.AddAccount(addr, nil, uint256.NewInt(1))
+
+	outer := s.Snapshot()
+	s.SetBalance(addr, uint256.NewInt(2))
+	inner := s.Snapshot()
+	s.SetBalance(addr, uint256.NewInt(3))
+
+	// Discarding keeps the changes and releases the snapshot
+	s.DiscardSnapshot(inner)
+	if len(s.snapshots) != 1 {
+		t.Fatalf("expected one snapshot left, got %d", len(s.snapshots))
+	}
+	s.RevertToSnapshot(inner)
+	if balance := s.GetBalance(addr); balance.Uint64() != 3 {
+		t.Fatalf("expected balance 3 after discarding, got %d", balance)
+	}
+
+	s.RevertToSnapshot(outer)
+	if balance := s.GetBalance(addr); balance.Uint64() != 1 || len(s.snapshots) != 0 {
+		t.Fatalf("expected balance 1 and no snapshots after reverting, got %d and %d", balance, len(s.snapshots))
+	}
+}
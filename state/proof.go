@@ -0,0 +1,269 @@
+package state
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/daniellehrner/evmdbg/rlp"
+	"github.com/daniellehrner/evmdbg/trie"
+	"github.com/holiman/uint256"
+)
+
+// ErrInvalidProof is returned when an account or storage proof does not match the state root
+var ErrInvalidProof = errors.New("invalid proof")
+
+// StorageProof is the proof of a single storage slot, as returned by eth_getProof
+type StorageProof struct {
+	Key   *uint256.Int
+	Value *uint256.Int
+	Proof [][]byte
+}
+
+// AccountProof is the proof of an account and a set of its storage slots, as returned by eth_getProof
+type AccountProof struct {
+	Address      [20]byte
+	AccountProof [][]byte
+	Balance      *uint256.Int
+	CodeHash     [32]byte
+	Nonce        uint64
+	StorageHash  [32]byte
+	StorageProof []StorageProof
+}
+
+// GetProof returns the proof of the account at addr and the given storage slots against Root()
+func (s *MemoryState) GetProof(addr [20]byte, keys []*uint256.Int) *AccountProof {
+	p := &AccountProof{
+		Address:      addr,
+		AccountProof: s.StateTrie().Prove(addr[:]),
+		Balance:      s.GetBalance(addr),
+		CodeHash:     CodeHash(s.GetCode(addr)),
+		Nonce:        s.GetNonce(addr),
+		StorageHash:  s.StorageRoot(addr),
+	}
+
+	storageTrie := s.StorageTrie(addr)
+	for _, key := range keys {
+		slot := key.Bytes32()
+		p.StorageProof = append(p.StorageProof, StorageProof{
+			Key:   new(uint256.Int).Set(key),
+			Value: s.GetStorage(addr, key),
+			Proof: storageTrie.Prove(slot[:]),
+		})
+	}
+	return p
+}
+
+// VerifyAccountProof checks the account proof and all storage proofs of p against stateRoot
+func VerifyAccountProof(stateRoot [32]byte, p *AccountProof) error {
+	_, err := verifyAccountProof(stateRoot, p)
+	return err
+}
+
+// verifyAccountProof verifies p and reports whether the account exists in the state trie
+func verifyAccountProof(stateRoot [32]byte, p *AccountProof) (bool, error) {
+	leaf, err := trie.VerifySecureProof(stateRoot, p.Address[:], p.AccountProof)
+	if err != nil {
+		return false, fmt.Errorf("%w: account %x: %v", ErrInvalidProof, p.Address, err)
+	}
+
+	balance := p.Balance
+	if balance == nil {
+		balance = uint256.NewInt(0)
+	}
+
+	if leaf == nil {
+		// The proof shows the account does not exist, so it has to be empty
+		if p.Nonce != 0 || !balance.IsZero() || p.CodeHash != EmptyCodeHash || p.StorageHash != trie.EmptyRoot {
+			return false, fmt.Errorf("%w: account %x is absent but proof claims non-empty fields", ErrInvalidProof, p.Address)
+		}
+	} else {
+		expected := EncodeAccount(p.Nonce, balance, p.StorageHash, p.CodeHash)
+		if string(leaf) != string(expected) {
+			return false, fmt.Errorf("%w: account %x does not match the proven leaf", ErrInvalidProof, p.Address)
+		}
+	}
+
+	for _, sp := range p.StorageProof {
+		if err := VerifyStorageProof(p.StorageHash, sp); err != nil {
+			return false, fmt.Errorf("account %x: %w", p.Address, err)
+		}
+	}
+	return leaf != nil, nil
+}
+
+// VerifyStorageProof checks a single storage proof against the storage root of its account
+func VerifyStorageProof(storageRoot [32]byte, sp StorageProof) error {
+	slot := sp.Key.Bytes32()
+	leaf, err := trie.VerifySecureProof(storageRoot, slot[:], sp.Proof)
+	if err != nil {
+		return fmt.Errorf("%w: slot %s: %v", ErrInvalidProof, sp.Key.Hex(), err)
+	}
+
+	value := uint256.NewInt(0)
+	if leaf != nil {
+		value, _, err = rlp.SplitUint256(leaf)
+		if err != nil {
+			return fmt.Errorf("%w: slot %s: %v", ErrInvalidProof, sp.Key.Hex(), err)
+		}
+	}
+
+	claimed := sp.Value
+	if claimed == nil {
+		claimed = uint256.NewInt(0)
+	}
+	if value.Cmp(claimed) != 0 {
+		return fmt.Errorf("%w: slot %s has value %s, proof claims %s", ErrInvalidProof, sp.Key.Hex(), value.Hex(), claimed.Hex())
+	}
+	return nil
+}
+
+// JSON encoding follows the eth_getProof response format
+
+type storageProofJSON struct {
+	Key   string   `json:"key"`
+	Value string   `json:"value"`
+	Proof []string `json:"proof"`
+}
+
+type accountProofJSON struct {
+	Address      string             `json:"address"`
+	AccountProof []string           `json:"accountProof"`
+	Balance      string             `json:"balance"`
+	CodeHash     string             `json:"codeHash"`
+	Nonce        string             `json:"nonce"`
+	StorageHash  string             `json:"storageHash"`
+	StorageProof []storageProofJSON `json:"storageProof"`
+}
+
+func (p *AccountProof) MarshalJSON() ([]byte, error) {
+	balance := p.Balance
+	if balance == nil {
+		balance = uint256.NewInt(0)
+	}
+
+	out := accountProofJSON{
+		Address:      encodeHex(p.Address[:]),
+		AccountProof: encodeHexList(p.AccountProof),
+		Balance:      balance.Hex(),
+		CodeHash:     encodeHex(p.CodeHash[:]),
+		Nonce:        uint256.NewInt(p.Nonce).Hex(),
+		StorageHash:  encodeHex(p.StorageHash[:]),
+		StorageProof: make([]storageProofJSON, 0, len(p.StorageProof)),
+	}
+	for _, sp := range p.StorageProof {
+		value := sp.Value
+		if value == nil {
+			value = uint256.NewInt(0)
+		}
+		out.StorageProof = append(out.StorageProof, storageProofJSON{
+			Key:   sp.Key.Hex(),
+			Value: value.Hex(),
+			Proof: encodeHexList(sp.Proof),
+		})
+	}
+	return json.Marshal(out)
+}
+
+func (p *AccountProof) UnmarshalJSON(data []byte) error {
+	var in accountProofJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	var err error
+	if err = decodeFixedHex(in.Address, p.Address[:]); err != nil {
+		return fmt.Errorf("address: %w", err)
+	}
+	if p.AccountProof, err = decodeHexList(in.AccountProof); err != nil {
+		return fmt.Errorf("accountProof: %w", err)
+	}
+	if p.Balance, err = decodeQuantity(in.Balance); err != nil {
+		return fmt.Errorf("balance: %w", err)
+	}
+	if err = decodeFixedHex(in.CodeHash, p.CodeHash[:]); err != nil {
+		return fmt.Errorf("codeHash: %w", err)
+	}
+	nonce, err := decodeQuantity(in.Nonce)
+	if err != nil || !nonce.IsUint64() {
+		return fmt.Errorf("nonce: invalid value %q", in.Nonce)
+	}
+	p.Nonce = nonce.Uint64()
+	if err = decodeFixedHex(in.StorageHash, p.StorageHash[:]); err != nil {
+		return fmt.Errorf("storageHash: %w", err)
+	}
+
+	p.StorageProof = nil
+	for i, sp := range in.StorageProof {
+		var out StorageProof
+		if out.Key, err = decodeQuantity(sp.Key); err != nil {
+			return fmt.Errorf("storageProof[%d].key: %w", i, err)
+		}
+		if out.Value, err = decodeQuantity(sp.Value); err != nil {
+			return fmt.Errorf("storageProof[%d].value: %w", i, err)
+		}
+		if out.Proof, err = decodeHexList(sp.Proof); err != nil {
+			return fmt.Errorf("storageProof[%d].proof: %w", i, err)
+		}
+		p.StorageProof = append(p.StorageProof, out)
+	}
+	return nil
+}
+
+func encodeHex(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+func encodeHexList(list [][]byte) []string {
+	out := make([]string, 0, len(list))
+	for _, b := range list {
+		out = append(out, encodeHex(b))
+	}
+	return out
+}
+
+func decodeHex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	return hex.DecodeString(s)
+}
+
+func decodeHexList(list []string) ([][]byte, error) {
+	out := make([][]byte, 0, len(list))
+	for _, s := range list {
+		b, err := decodeHex(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+func decodeFixedHex(s string, out []byte) error {
+	b, err := decodeHex(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != len(out) {
+		return fmt.Errorf("expected %d bytes, got %d", len(out), len(b))
+	}
+	copy(out, b)
+	return nil
+}
+
+// decodeQuantity accepts both minimal quantities and zero-padded 32-byte values
+func decodeQuantity(s string) (*uint256.Int, error) {
+	b, err := decodeHex(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 32 {
+		return nil, fmt.Errorf("value %q exceeds 32 bytes", s)
+	}
+	return new(uint256.Int).SetBytes(b), nil
+}
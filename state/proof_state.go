@@ -0,0 +1,319 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/daniellehrner/evmdbg/trie"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// ErrUnprovenAccess is the cause of the panic raised by ProofState on reads outside the supplied proofs
+var ErrUnprovenAccess = errors.New("access to unproven state")
+
+// UnprovenAccessError describes a read that is not covered by the proofs of a ProofState
+type UnprovenAccessError struct {
+	What        string
+	Address     [20]byte
+	Slot        *uint256.Int
+	BlockNumber uint64 // only set for block hash reads
+}
+
+func (e *UnprovenAccessError) Error() string {
+	if e.What == "block hash" {
+		return fmt.Sprintf("%v: hash of block %d", ErrUnprovenAccess, e.BlockNumber)
+	}
+	if e.Slot != nil {
+		return fmt.Sprintf("%v: %s of account %x, slot %s", ErrUnprovenAccess, e.What, e.Address, e.Slot.Hex())
+	}
+	return fmt.Sprintf("%v: %s of account %x", ErrUnprovenAccess, e.What, e.Address)
+}
+
+func (e *UnprovenAccessError) Unwrap() error {
+	return ErrUnprovenAccess
+}
+
+// Is makes unproven reads match vm.ErrStateUnavailable, so a vm.Session fails the
+// transaction instead of panicking
+func (e *UnprovenAccessError) Is(target error) bool {
+	return target == vm.ErrStateUnavailable
+}
+
+// ProofState is a vm.StateProvider that serves reads only from account and storage proofs
+// verified against a trusted state root. Reads of accounts, slots, code or block hashes
+// that were not supplied panic with an *UnprovenAccessError, since the StateProvider
+// interface cannot return errors. A vm.Session records it as the transaction error. Slots
+// of accounts proven to be absent or to have empty storage read as zero without a proof.
+//
+// Writes are kept in an overlay and are visible to subsequent reads. Snapshots roll back
+// the overlay together with the journal of accounts it shadows.
+type ProofState struct {
+	root        [32]byte
+	accounts    map[[20]byte]*AccountProof
+	slots       map[[20]byte]map[[32]byte]*uint256.Int
+	codes       map[[32]byte][]byte
+	blockHashes map[uint64][32]byte
+	exists      map[[20]byte]bool
+
+	overlay *MemoryState
+	written map[[20]byte]bool
+	fresh   map[[20]byte]bool // created or deleted during execution, not backed by proofs
+
+	journal   []proofJournalEntry
+	snapshots []proofSnapshot
+}
+
+// proofJournalEntry records the first time an account was marked written or fresh
+type proofJournalEntry struct {
+	addr  [20]byte
+	fresh bool
+}
+
+type proofSnapshot struct {
+	overlay int
+	journal int
+}
+
+var (
+	_ vm.StateProvider = (*ProofState)(nil)
+	_ vm.CodeWriter    = (*ProofState)(nil)
+	_ vm.Snapshotter   = (*ProofState)(nil)
+)
+
+// NewProofState verifies all proofs against root and returns a state provider backed by them.
+// codes supplies the bytecode of proven accounts, matched by code hash.
+func NewProofState(root [32]byte, proofs []*AccountProof, codes [][]byte) (*ProofState, error) {
+	s := &ProofState{
+		root:        root,
+		accounts:    make(map[[20]byte]*AccountProof),
+		slots:       make(map[[20]byte]map[[32]byte]*uint256.Int),
+		codes:       make(map[[32]byte][]byte),
+		blockHashes: make(map[uint64][32]byte),
+		exists:      make(map[[20]byte]bool),
+		overlay:     NewMemoryState(),
+		written:     make(map[[20]byte]bool),
+		fresh:       make(map[[20]byte]bool),
+	}
+
+	for _, p := range proofs {
+		exists, err := verifyAccountProof(root, p)
+		if err != nil {
+			return nil, err
+		}
+		s.accounts[p.Address] = p
+		s.exists[p.Address] = exists
+
+		slots := s.slots[p.Address]
+		if slots == nil {
+			slots = make(map[[32]byte]*uint256.Int)
+			s.slots[p.Address] = slots
+		}
+		for _, sp := range p.StorageProof {
+			value := sp.Value
+			if value == nil {
+				value = uint256.NewInt(0)
+			}
+			slots[sp.Key.Bytes32()] = new(uint256.Int).Set(value)
+		}
+	}
+
+	for _, code := range codes {
+		s.codes[CodeHash(code)] = code
+	}
+	s.codes[EmptyCodeHash] = nil
+
+	return s, nil
+}
+
+// Root returns the trusted state root the proofs were verified against
+func (s *ProofState) Root() [32]byte {
+	return s.root
+}
+
+// SetBlockHash supplies the hash returned by GetBlockHash. Block hashes cannot be proven
+// against a state root and are trusted as given.
+func (s *ProofState) SetBlockHash(blockNumber uint64, hash [32]byte) {
+	s.blockHashes[blockNumber] = hash
+}
+
+// proven returns the proof of addr, panicking if the account was not proven
+func (s *ProofState) proven(what string, addr [20]byte) *AccountProof {
+	p, ok := s.accounts[addr]
+	if !ok {
+		panic(&UnprovenAccessError{What: what, Address: addr})
+	}
+	return p
+}
+
+// touch copies the proven account into the overlay before its first write. Writing to an
+// unproven account is treated like reading it, since the overlay would otherwise serve
+// made-up values for its other fields.
+func (s *ProofState) touch(addr [20]byte) {
+	if s.written[addr] {
+		return
+	}
+
+	p := s.proven("write", addr)
+	s.markWritten(addr)
+	if s.exists[addr] {
+		_ = s.overlay.CreateAccount(addr, s.provenCode(p), p.Balance)
+		s.overlay.SetNonce(addr, p.Nonce)
+	}
+}
+
+// markWritten makes the overlay serve all reads of addr
+func (s *ProofState) markWritten(addr [20]byte) {
+	if !s.written[addr] {
+		s.written[addr] = true
+		s.journal = append(s.journal, proofJournalEntry{addr: addr})
+	}
+}
+
+// markFresh makes the overlay serve the storage of addr without falling back to proofs
+func (s *ProofState) markFresh(addr [20]byte) {
+	s.markWritten(addr)
+	if !s.fresh[addr] {
+		s.fresh[addr] = true
+		s.journal = append(s.journal, proofJournalEntry{addr: addr, fresh: true})
+	}
+}
+
+func (s *ProofState) provenCode(p *AccountProof) []byte {
+	code, ok := s.codes[p.CodeHash]
+	if !ok {
+		panic(&UnprovenAccessError{What: "code", Address: p.Address})
+	}
+	return code
+}
+
+func (s *ProofState) GetBalance(addr [20]byte) *uint256.Int {
+	if s.written[addr] {
+		return s.overlay.GetBalance(addr)
+	}
+	p := s.proven("balance", addr)
+	if p.Balance == nil {
+		return uint256.NewInt(0)
+	}
+	return new(uint256.Int).Set(p.Balance)
+}
+
+func (s *ProofState) GetCode(addr [20]byte) []byte {
+	if s.written[addr] {
+		return s.overlay.GetCode(addr)
+	}
+	return s.provenCode(s.proven("code", addr))
+}
+
+func (s *ProofState) GetStorage(addr [20]byte, key *uint256.Int) *uint256.Int {
+	if s.fresh[addr] {
+		return s.overlay.GetStorage(addr, key)
+	}
+	if acc := s.overlay.Account(addr); acc != nil {
+		if val, ok := acc.Storage[key.Bytes32()]; ok {
+			return new(uint256.Int).Set(val)
+		}
+	}
+
+	p := s.proven("storage", addr)
+	if !s.exists[addr] || p.StorageHash == trie.EmptyRoot {
+		return uint256.NewInt(0)
+	}
+	val, ok := s.slots[addr][key.Bytes32()]
+	if !ok {
+		panic(&UnprovenAccessError{What: "storage", Address: addr, Slot: key})
+	}
+	return new(uint256.Int).Set(val)
+}
+
+func (s *ProofState) SetStorage(addr [20]byte, key *uint256.Int, value *uint256.Int) {
+	s.touch(addr)
+
+	// Zero values are kept explicitly so they shadow the proven value
+	acc := s.overlay.getOrNewAccount(addr)
+	acc.Storage[key.Bytes32()] = new(uint256.Int).Set(value)
+}
+
+func (s *ProofState) AccountExists(addr [20]byte) bool {
+	if s.written[addr] {
+		return s.overlay.AccountExists(addr)
+	}
+	s.proven("existence", addr)
+	return s.exists[addr]
+}
+
+func (s *ProofState) GetBlockHash(blockNumber uint64) [32]byte {
+	hash, ok := s.blockHashes[blockNumber]
+	if !ok {
+		panic(&UnprovenAccessError{What: "block hash", BlockNumber: blockNumber})
+	}
+	return hash
+}
+
+func (s *ProofState) CreateAccount(addr [20]byte, code []byte, balance *uint256.Int) error {
+	s.markFresh(addr)
+	return s.overlay.CreateAccount(addr, code, balance)
+}
+
+func (s *ProofState) GetNonce(addr [20]byte) uint64 {
+	if s.written[addr] {
+		return s.overlay.GetNonce(addr)
+	}
+	return s.proven("nonce", addr).Nonce
+}
+
+func (s *ProofState) SetNonce(addr [20]byte, nonce uint64) {
+	s.touch(addr)
+	s.overlay.SetNonce(addr, nonce)
+}
+
+func (s *ProofState) SetBalance(addr [20]byte, balance *uint256.Int) {
+	s.touch(addr)
+	s.overlay.SetBalance(addr, balance)
+}
+
+func (s *ProofState) DeleteAccount(addr [20]byte) error {
+	s.markFresh(addr)
+	return s.overlay.DeleteAccount(addr)
+}
+
+func (s *ProofState) SetCode(addr [20]byte, code []byte) {
+	s.touch(addr)
+	s.overlay.SetCode(addr, code)
+}
+
+// Snapshot records the current overlay and returns an identifier for RevertToSnapshot
+func (s *ProofState) Snapshot() int {
+	s.snapshots = append(s.snapshots, proofSnapshot{overlay: s.overlay.Snapshot(), journal: len(s.journal)})
+	return len(s.snapshots) - 1
+}
+
+// RevertToSnapshot restores the overlay recorded by Snapshot and discards all later snapshots
+func (s *ProofState) RevertToSnapshot(id int) {
+	if id < 0 || id >= len(s.snapshots) {
+		return
+	}
+	snap := s.snapshots[id]
+	for i := len(s.journal) - 1; i >= snap.journal; i-- {
+		if e := s.journal[i]; e.fresh {
+			delete(s.fresh, e.addr)
+		} else {
+			delete(s.written, e.addr)
+		}
+	}
+	s.journal = s.journal[:snap.journal]
+	s.overlay.RevertToSnapshot(snap.overlay)
+	s.snapshots = s.snapshots[:id]
+}
+
+// DiscardSnapshot releases the snapshot and all later snapshots, keeping the current overlay
+func (s *ProofState) DiscardSnapshot(id int) {
+	if id < 0 || id >= len(s.snapshots) {
+		return
+	}
+	s.overlay.DiscardSnapshot(s.snapshots[id].overlay)
+	s.snapshots = s.snapshots[:id]
+	if id == 0 {
+		s.journal = nil
+	}
+}
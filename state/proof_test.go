@@ -0,0 +1,259 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/daniellehrner/evmdbg/vm/opcode_handlers"
+	"github.com/holiman/uint256"
+)
+
+func TestGetProofAndVerify(t *testing.T) {
+	s := testState()
+	root := s.Root()
+
+	b := [20]byte{0x20}
+	b[19] = 0x02
+	p := s.GetProof(b, []*uint256.Int{uint256.NewInt(0), uint256.NewInt(3), uint256.NewInt(99)})
+
+	if err := VerifyAccountProof(root, p); err != nil {
+		t.Fatalf("verification failed: %v", err)
+	}
+	if p.Nonce != 1 {
+		t.Fatalf("expected nonce 1, got %d", p.Nonce)
+	}
+	if p.StorageProof[1].Value.Cmp(uint256.NewInt(0xdeadbeef)) != 0 {
+		t.Fatalf("expected 0xdeadbeef, got %s", p.StorageProof[1].Value.Hex())
+	}
+	if !p.StorageProof[2].Value.IsZero() {
+		t.Fatalf("expected zero for unset slot, got %s", p.StorageProof[2].Value.Hex())
+	}
+}
+
+func TestVerifyAccountProofRejectsWrongValues(t *testing.T) {
+	s := testState()
+	root := s.Root()
+
+	b := [20]byte{0x20}
+	b[19] = 0x02
+
+	p := s.GetProof(b, []*uint256.Int{uint256.NewInt(3)})
+	p.Balance = uint256.NewInt(1)
+	if err := VerifyAccountProof(root, p); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("expected ErrInvalidProof for wrong balance, got %v", err)
+	}
+
+	p = s.GetProof(b, []*uint256.Int{uint256.NewInt(3)})
+	p.StorageProof[0].Value = uint256.NewInt(1)
+	if err := VerifyAccountProof(root, p); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("expected ErrInvalidProof for wrong slot value, got %v", err)
+	}
+
+	// An absent account cannot claim a balance
+	missing := [20]byte{0x99}
+	p = s.GetProof(missing, nil)
+	if err := VerifyAccountProof(root, p); err != nil {
+		t.Fatalf("absence proof failed: %v", err)
+	}
+	p.Balance = uint256.NewInt(5)
+	if err := VerifyAccountProof(root, p); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("expected ErrInvalidProof for absent account, got %v", err)
+	}
+}
+
+func TestAccountProofJSONRoundTrip(t *testing.T) {
+	s := testState()
+	b := [20]byte{0x20}
+	b[19] = 0x02
+	p := s.GetProof(b, []*uint256.Int{uint256.NewInt(3)})
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var decoded AccountProof
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if err := VerifyAccountProof(s.Root(), &decoded); err != nil {
+		t.Fatalf("decoded proof does not verify: %v", err)
+	}
+	if decoded.StorageProof[0].Value.Cmp(uint256.NewInt(0xdeadbeef)) != 0 {
+		t.Fatalf("expected 0xdeadbeef, got %s", decoded.StorageProof[0].Value.Hex())
+	}
+}
+
+func TestProofStateExecution(t *testing.T) {
+	s := testState()
+	root := s.Root()
+
+	b := [20]byte{0x20}
+	b[19] = 0x02
+	proof := s.GetProof(b, []*uint256.Int{uint256.NewInt(0), uint256.NewInt(3)})
+
+	ps, err := NewProofState(root, []*AccountProof{proof}, [][]byte{s.GetCode(b)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// SLOAD(3), SSTORE(0, that value), SLOAD(0)
+	code := []byte{vm.PUSH1, 0x03, vm.SLOAD, vm.PUSH1, 0x00, vm.SSTORE, vm.PUSH1, 0x00, vm.SLOAD}
+	d := vm.NewDebuggerVM(code, opcode_handlers.GetHandler)
+	d.StateProvider = ps
+	d.Context = &vm.ExecutionContext{Address: b}
+
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			t.Fatalf("execution error: %v", err)
+		}
+	}
+
+	top, _ := d.Stack().Pop()
+	if top.Cmp(uint256.NewInt(0xdeadbeef)) != 0 {
+		t.Fatalf("expected 0xdeadbeef, got %s", top.Hex())
+	}
+	if got := ps.GetNonce(b); got != 1 {
+		t.Fatalf("expected proven nonce 1, got %d", got)
+	}
+}
+
+func TestProofStateFailsOnUnprovenAccess(t *testing.T) {
+	s := testState()
+	b := [20]byte{0x20}
+	b[19] = 0x02
+	ps, err := NewProofState(s.Root(), []*AccountProof{s.GetProof(b, []*uint256.Int{uint256.NewInt(0)})}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			r := recover()
+			err, ok := r.(error)
+			if !ok || !errors.Is(err, ErrUnprovenAccess) {
+				t.Fatalf("%s: expected ErrUnprovenAccess panic, got %v", name, r)
+			}
+		}()
+		fn()
+	}
+
+	expectPanic("unproven slot", func() { ps.GetStorage(b, uint256.NewInt(3)) })
+	expectPanic("unproven account", func() { ps.GetBalance([20]byte{0x42}) })
+	expectPanic("missing code", func() { ps.GetCode(b) })
+	expectPanic("block hash", func() { ps.GetBlockHash(1) })
+	expectPanic("write to unproven account", func() { ps.SetBalance([20]byte{0x42}, uint256.NewInt(1)) })
+
+	// Proven slot is served without panicking
+	if got := ps.GetStorage(b, uint256.NewInt(0)); got.Cmp(uint256.NewInt(1)) != 0 {
+		t.Fatalf("expected 1, got %s", got.Hex())
+	}
+}
+
+func TestProofStateProvablyZeroSlots(t *testing.T) {
+	s := testState()
+	a := [20]byte{0x10}
+	a[19] = 0x01
+	absent := [20]byte{0x42}
+	ps, err := NewProofState(s.Root(), []*AccountProof{s.GetProof(a, nil), s.GetProof(absent, nil)}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Neither an account with empty storage nor an absent account need storage proofs
+	if got := ps.GetStorage(a, uint256.NewInt(7)); !got.IsZero() {
+		t.Fatalf("expected zero for an account with empty storage, got %s", got.Hex())
+	}
+	if got := ps.GetStorage(absent, uint256.NewInt(7)); !got.IsZero() {
+		t.Fatalf("expected zero for an absent account, got %s", got.Hex())
+	}
+}
+
+func TestSessionRecordsUnprovenAccess(t *testing.T) {
+	s := testState()
+	a := [20]byte{0x10}
+	a[19] = 0x01
+	b := [20]byte{0x20}
+	b[19] = 0x02
+	// The code of b stores to slot 0, which is not proven
+	proofs := []*AccountProof{s.GetProof(a, nil), s.GetProof(b, []*uint256.Int{uint256.NewInt(3)})}
+	ps, err := NewProofState(s.Root(), proofs, [][]byte{s.GetCode(b)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	session := vm.NewSession(ps, nil, opcode_handlers.GetHandler, []vm.Transaction{{From: a, To: &b, Gas: 100000}})
+	session.VM.GasSchedule = vm.LondonGasSchedule
+	if err := session.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	var unproven *UnprovenAccessError
+	if err := session.Results[0].Err; !errors.As(err, &unproven) || unproven.What != "storage" {
+		t.Fatalf("expected the unproven storage read as transaction error, got %v", err)
+	}
+}
+
+func TestProofStateRevertsFailedTransactions(t *testing.T) {
+	s := testState()
+	a := [20]byte{0x10}
+	a[19] = 0x01
+	b := [20]byte{0x20}
+	b[19] = 0x02
+	proofs := []*AccountProof{s.GetProof(a, nil), s.GetProof(b, []*uint256.Int{uint256.NewInt(0)})}
+	ps, err := NewProofState(s.Root(), proofs, [][]byte{s.GetCode(b)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The value transfer to b is rolled back when its code runs out of gas
+	txs := []vm.Transaction{{From: a, To: &b, Gas: 21005, Value: uint256.NewInt(7)}}
+	session := vm.NewSession(ps, nil, opcode_handlers.GetHandler, txs)
+	session.VM.GasSchedule = vm.LondonGasSchedule
+	if err := session.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	if session.Results[0].Err == nil {
+		t.Fatal("expected the transaction to fail")
+	}
+	if got := ps.GetBalance(b); !got.IsZero() {
+		t.Fatalf("expected the proven balance 0 of b, got %s", got.Dec())
+	}
+	if got := ps.GetBalance(a); got.Cmp(uint256.NewInt(1000000000000000000)) != 0 {
+		t.Fatalf("expected the proven balance of a, got %s", got.Dec())
+	}
+	if got := ps.GetNonce(a); got != 6 {
+		t.Fatalf("expected the nonce increment to survive, got %d", got)
+	}
+
+	// Reverting a deletion serves the proven storage again
+	snap := ps.Snapshot()
+	if err := ps.DeleteAccount(b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ps.GetStorage(b, uint256.NewInt(0)); !got.IsZero() {
+		t.Fatalf("expected zero storage after deletion, got %s", got.Hex())
+	}
+	ps.RevertToSnapshot(snap)
+	if got := ps.GetStorage(b, uint256.NewInt(0)); got.Cmp(uint256.NewInt(1)) != 0 {
+		t.Fatalf("expected the proven slot after reverting, got %s", got.Hex())
+	}
+	if !ps.AccountExists(b) || len(ps.snapshots) != 0 {
+		t.Fatalf("expected b to exist and no snapshots left, got %d", len(ps.snapshots))
+	}
+}
+
+func TestNewProofStateRejectsInvalidProof(t *testing.T) {
+	s := testState()
+	b := [20]byte{0x20}
+	b[19] = 0x02
+	p := s.GetProof(b, nil)
+	p.Nonce = 7
+
+	if _, err := NewProofState(s.Root(), []*AccountProof{p}, nil); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("expected ErrInvalidProof, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package state
+
+import (
+	"github.com/daniellehrner/evmdbg/rlp"
+	"github.com/daniellehrner/evmdbg/trie"
+	"github.com/holiman/uint256"
+	"golang.org/x/crypto/sha3"
+)
+
+// EmptyCodeHash is the code hash of accounts without code: keccak256("")
+var EmptyCodeHash = [32]byte{
+	0xc5, 0xd2, 0x46, 0x01, 0x86, 0xf7, 0x23, 0x3c, 0x92, 0x7e, 0x7d, 0xb2, 0xdc, 0xc7, 0x03, 0xc0,
+	0xe5, 0x00, 0xb6, 0x53, 0xca, 0x82, 0x27, 0x3b, 0x7b, 0xfa, 0xd8, 0x04, 0x5d, 0x85, 0xa4, 0x70,
+}
+
+// CodeHash returns the keccak256 hash of code
+func CodeHash(code []byte) [32]byte {
+	var h [32]byte
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(code)
+	copy(h[:], hasher.Sum(nil))
+	return h
+}
+
+// EncodeAccount returns the RLP encoding of an account leaf in the state trie:
+// rlp([nonce, balance, storageRoot, codeHash])
+func EncodeAccount(nonce uint64, balance *uint256.Int, storageRoot, codeHash [32]byte) []byte {
+	return rlp.EncodeList(
+		rlp.EncodeUint64(nonce),
+		rlp.EncodeUint256(balance),
+		rlp.EncodeBytes(storageRoot[:]),
+		rlp.EncodeBytes(codeHash[:]),
+	)
+}
+
+// EncodeStorageValue returns the RLP encoding of a storage slot value as stored in the storage trie
+func EncodeStorageValue(value *uint256.Int) []byte {
+	return rlp.EncodeUint256(value)
+}
+
+// StorageTrie builds the storage trie of the account at addr
+func (s *MemoryState) StorageTrie(addr [20]byte) *trie.SecureTrie {
+	t := trie.NewSecure()
+	acc, ok := s.accounts[addr]
+	if !ok {
+		return t
+	}
+
+	for key, val := range acc.Storage {
+		if val.IsZero() {
+			continue
+		}
+		t.Update(key[:], EncodeStorageValue(val))
+	}
+	return t
+}
+
+// StorageRoot returns the storage root of the account at addr
+func (s *MemoryState) StorageRoot(addr [20]byte) [32]byte {
+	return s.StorageTrie(addr).Hash()
+}
+
+// StateTrie builds the state trie of all accounts
+func (s *MemoryState) StateTrie() *trie.SecureTrie {
+	t := trie.NewSecure()
+	for addr, acc := range s.accounts {
+		t.Update(addr[:], EncodeAccount(acc.Nonce, acc.Balance, s.StorageRoot(addr), CodeHash(acc.Code)))
+	}
+	return t
+}
+
+// Root returns the state root of all accounts
+func (s *MemoryState) Root() [32]byte {
+	return s.StateTrie().Hash()
+}
@@ -0,0 +1,118 @@
+package state
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/trie"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/daniellehrner/evmdbg/vm/opcode_handlers"
+	"github.com/holiman/uint256"
+)
+
+func mustHash(t *testing.T, s string) [32]byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 32 {
+		t.Fatalf("invalid hash %q", s)
+	}
+	var h [32]byte
+	copy(h[:], b)
+	return h
+}
+
+func testState() *MemoryState {
+	s := NewMemoryState()
+
+	a := [20]byte{0x10}
+	a[19] = 0x01
+	s.SetBalance(a, uint256.NewInt(1000000000000000000))
+	s.SetNonce(a, 5)
+
+	b := [20]byte{0x20}
+	b[19] = 0x02
+	s.AddAccount(b, []byte{0x60, 0x01, 0x60, 0x00, 0x55}, uint256.NewInt(0))
+	s.SetNonce(b, 1)
+	s.SetStorage(b, uint256.NewInt(0), uint256.NewInt(1))
+	s.SetStorage(b, uint256.NewInt(3), uint256.NewInt(0xdeadbeef))
+
+	return s
+}
+
+func TestEmptyStateRoot(t *testing.T) {
+	s := NewMemoryState()
+	if s.Root() != trie.EmptyRoot {
+		t.Fatalf("expected empty root, got %x", s.Root())
+	}
+	if s.StorageRoot([20]byte{0x01}) != trie.EmptyRoot {
+		t.Fatal("expected empty storage root for missing account")
+	}
+}
+
+func TestStateAndStorageRoot(t *testing.T) {
+	s := testState()
+
+	b := [20]byte{0x20}
+	b[19] = 0x02
+	expectedStorage := mustHash(t, "c8a8aa87cd29707802414e10e33fd36a1dc728827d7caf873d7e8571563b5693")
+	if got := s.StorageRoot(b); got != expectedStorage {
+		t.Fatalf("expected storage root %x, got %x", expectedStorage, got)
+	}
+
+	expectedState := mustHash(t, "d8af9e7ced63ea06f0d4132b79bf7e6903ed86443fffe29519350c28a47d8527")
+	if got := s.Root(); got != expectedState {
+		t.Fatalf("expected state root %x, got %x", expectedState, got)
+	}
+}
+
+func TestZeroStorageValueIsRemoved(t *testing.T) {
+	s := testState()
+	before := s.Root()
+
+	b := [20]byte{0x20}
+	b[19] = 0x02
+	s.SetStorage(b, uint256.NewInt(7), uint256.NewInt(42))
+	if s.Root() == before {
+		t.Fatal("expected root to change after storage write")
+	}
+
+	s.SetStorage(b, uint256.NewInt(7), uint256.NewInt(0))
+	if s.Root() != before {
+		t.Fatalf("expected root %x after clearing slot, got %x", before, s.Root())
+	}
+}
+
+func TestStateRootAfterExecution(t *testing.T) {
+	s := NewMemoryState()
+	contract := [20]byte{0x20}
+	contract[19] = 0x02
+
+	// SSTORE(0, 1); SSTORE(3, 0xdeadbeef)
+	code := []byte{
+		vm.PUSH1, 0x01, vm.PUSH1, 0x00, vm.SSTORE,
+		vm.PUSH4, 0xde, 0xad, 0xbe, 0xef, vm.PUSH1, 0x03, vm.SSTORE,
+	}
+	s.AddAccount(contract, []byte{0x60, 0x01, 0x60, 0x00, 0x55}, uint256.NewInt(0))
+	s.SetNonce(contract, 1)
+
+	d := vm.NewDebuggerVM(code, opcode_handlers.GetHandler)
+	d.StateProvider = s
+	d.Context = &vm.ExecutionContext{Address: contract}
+
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			t.Fatalf("execution error: %v", err)
+		}
+	}
+
+	expected := mustHash(t, "c8a8aa87cd29707802414e10e33fd36a1dc728827d7caf873d7e8571563b5693")
+	if got := s.StorageRoot(contract); got != expected {
+		t.Fatalf("expected storage root %x, got %x", expected, got)
+	}
+	if got := d.ReadStorage(uint256.NewInt(3)); got.Cmp(uint256.NewInt(0xdeadbeef)) != 0 {
+		t.Fatalf("expected 0xdeadbeef, got %s", got.Hex())
+	}
+	if len(d.Storage) != 0 {
+		t.Fatalf("expected VM-local storage to stay empty, got %d entries", len(d.Storage))
+	}
+}
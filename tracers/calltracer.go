@@ -0,0 +1,239 @@
+package tracers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// CallTracerConfig holds the options of the call tracer, using the names of geth's
+// callTracer config
+type CallTracerConfig struct {
+	OnlyTopCall bool `json:"onlyTopCall"` // only record the outermost frame
+	WithLog     bool `json:"withLog"`     // record the logs emitted by each frame
+}
+
+// CallTrace is a frame in the call tracer output. The logs of a failed frame and its
+// nested frames are dropped, as they are not part of the receipt.
+type CallTrace struct {
+	Type         string      `json:"type"`
+	From         string      `json:"from"`
+	To           string      `json:"to,omitempty"`
+	Value        string      `json:"value,omitempty"`
+	Gas          hexUint     `json:"gas"`
+	GasUsed      hexUint     `json:"gasUsed"`
+	Input        string      `json:"input"`
+	Output       string      `json:"output,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	RevertReason string      `json:"revertReason,omitempty"`
+	Calls        []CallTrace `json:"calls,omitempty"`
+	Logs         []CallLog   `json:"logs,omitempty"`
+}
+
+// CallLog is a log emitted by a frame. Position is the number of nested calls the frame
+// made before the log.
+type CallLog struct {
+	Address  string   `json:"address"`
+	Topics   []string `json:"topics"`
+	Data     string   `json:"data"`
+	Index    hexUint  `json:"index"`
+	Position hexUint  `json:"position"`
+}
+
+// CallTracer is a vm.Tracer building the tree of frames of a transaction in the format
+// of geth's callTracer, with the type, value, gas, input, output and error of each call.
+// The outermost frame is reported by vm.Session with the gas limit and the gas used by
+// the transaction.
+type CallTracer struct {
+	vm.NoopTracer
+
+	cfg   CallTracerConfig
+	stack []CallTrace // the frames entered and not yet returned
+	depth int
+	logs  int // the logs of the transaction so far
+
+	result *CallTrace
+}
+
+// NewCallTracer returns a CallTracer with the given options, cfg may be nil
+func NewCallTracer(cfg *CallTracerConfig) *CallTracer {
+	t := &CallTracer{}
+	if cfg != nil {
+		t.cfg = *cfg
+	}
+	return t
+}
+
+func (t *CallTracer) OnEnter(frame *vm.CallFrame) {
+	t.depth = frame.Depth
+	if frame.Depth == 1 {
+		// A new transaction starts
+		t.stack, t.logs, t.result = t.stack[:0], 0, nil
+	} else if t.cfg.OnlyTopCall || len(t.stack) == 0 {
+		return
+	}
+
+	call := CallTrace{
+		Type:  frame.Type.String(),
+		From:  hexAddress(frame.From),
+		To:    hexAddress(frame.To),
+		Gas:   hexUint(frame.Gas),
+		Input: hexData(frame.Input),
+	}
+	if frame.Value != nil {
+		call.Value = frame.Value.Hex()
+	}
+	t.stack = append(t.stack, call)
+}
+
+func (t *CallTracer) OnExit(frame *vm.CallFrame, output []byte, gasUsed uint64, err error) {
+	t.depth = frame.Depth - 1
+	if len(t.stack) == 0 || (frame.Depth > 1 && t.cfg.OnlyTopCall) {
+		return
+	}
+
+	call := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	call.GasUsed = hexUint(gasUsed)
+	call.setOutput(frame.Type, output, err)
+
+	if len(t.stack) > 0 {
+		parent := &t.stack[len(t.stack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	if t.cfg.WithLog {
+		clearFailedLogs(&call, false)
+	}
+	t.result = &call
+}
+
+func (t *CallTracer) OnLog(log *vm.LogEntry) {
+	if !t.cfg.WithLog || len(t.stack) == 0 || (t.cfg.OnlyTopCall && t.depth > 1) {
+		return
+	}
+	topics := make([]string, len(log.Topics))
+	for i, topic := range log.Topics {
+		topics[i] = hexData(topic)
+	}
+	call := &t.stack[len(t.stack)-1]
+	call.Logs = append(call.Logs, CallLog{
+		Address:  hexAddress(log.Address),
+		Topics:   topics,
+		Data:     hexData(log.Data),
+		Index:    hexUint(t.logs),
+		Position: hexUint(len(call.Calls)),
+	})
+	t.logs++
+}
+
+// Result returns the outermost frame of the last transaction once it returned, nil
+// before
+func (t *CallTracer) Result() *CallTrace {
+	return t.result
+}
+
+// MarshalJSON encodes the outermost frame of the last transaction, null before
+func (t *CallTracer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.result)
+}
+
+// setOutput records the output of a returned frame. The output of a failed frame is
+// only kept if it reverted, and a failed creation has no address.
+func (c *CallTrace) setOutput(typ vm.CallType, output []byte, err error) {
+	if err == nil {
+		if len(output) > 0 {
+			c.Output = hexData(output)
+		}
+		return
+	}
+	c.Error = err.Error()
+	if typ == vm.CallTypeCreate || typ == vm.CallTypeCreate2 {
+		c.To = ""
+	}
+	if !errors.Is(err, vm.ErrExecutionReverted) || len(output) == 0 {
+		return
+	}
+	c.Output = hexData(output)
+	if reason, ok := revertReason(output); ok {
+		c.RevertReason = reason
+	}
+}
+
+// clearFailedLogs drops the logs of failed frames and their nested frames
+func clearFailedLogs(c *CallTrace, parentFailed bool) {
+	failed := parentFailed || c.Error != ""
+	if failed {
+		c.Logs = nil
+	}
+	for i := range c.Calls {
+		clearFailedLogs(&c.Calls[i], failed)
+	}
+}
+
+var (
+	errorSelector = []byte{0x08, 0xc3, 0x79, 0xa0} // Error(string)
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71} // Panic(uint256)
+)
+
+// panicReasons describes the Solidity panic codes
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "enum overflow",
+	0x22: "invalid encoded storage byte array accessed",
+	0x31: "out-of-bounds array access; popping on an empty array",
+	0x32: "out-of-bounds access of an array or bytesN",
+	0x41: "out of memory",
+	0x51: "uninitialized function",
+}
+
+// revertReason decodes revert data encoded as Solidity's Error(string) or Panic(uint256)
+func revertReason(data []byte) (string, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+	args := data[4:]
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		if len(args) < 64 {
+			return "", false
+		}
+		offset := new(uint256.Int).SetBytes(args[:32])
+		if !offset.IsUint64() || offset.Uint64() > uint64(len(args)-32) {
+			return "", false
+		}
+		start := offset.Uint64() + 32
+		length := new(uint256.Int).SetBytes(args[start-32 : start])
+		if !length.IsUint64() || length.Uint64() > uint64(len(args))-start {
+			return "", false
+		}
+		return string(args[start : start+length.Uint64()]), true
+	case bytes.Equal(data[:4], panicSelector):
+		if len(args) < 32 {
+			return "", false
+		}
+		code := new(uint256.Int).SetBytes(args[:32])
+		if reason, ok := panicReasons[code.Uint64()]; ok && code.IsUint64() {
+			return reason, true
+		}
+		return "unknown panic code: " + code.Hex(), true
+	}
+	return "", false
+}
+
+// hexAddress returns addr as 0x prefixed hex string
+func hexAddress(addr [20]byte) string {
+	return "0x" + hex.EncodeToString(addr[:])
+}
+
+// hexData returns b as 0x prefixed hex string
+func hexData(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
@@ -0,0 +1,112 @@
+package tracers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/daniellehrner/evmdbg/vm/opcode_handlers"
+	"github.com/holiman/uint256"
+)
+
+// errorNope is the revert data of Error("nope")
+var errorNope, _ = hex.DecodeString("08c379a0" +
+	"0000000000000000000000000000000000000000000000000000000000000020" +
+	"0000000000000000000000000000000000000000000000000000000000000004" +
+	"6e6f706500000000000000000000000000000000000000000000000000000000")
+
+func runCallTracer(t *testing.T, cfg *CallTracerConfig) string {
+	t.Helper()
+
+	callee := [20]byte{19: 0xcc}
+	sp := state.NewMemoryState()
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	// Calls 0xcc with 1 wei, emits a log, then calls the identity precompile with 0x2a
+	sp.AddAccount(contract, []byte{
+		vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0x01, vm.PUSH1, 0xcc, vm.GAS, vm.CALL, vm.POP,
+		vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.LOG1,
+		vm.PUSH1, 0x2a, vm.PUSH0, vm.MSTORE8,
+		vm.PUSH0, vm.PUSH0, vm.PUSH1, 0x01, vm.PUSH0, vm.PUSH1, 0x04, vm.GAS, vm.STATICCALL,
+		vm.STOP,
+	}, uint256.NewInt(0))
+	// Emits a log and reverts with Error("nope")
+	sp.AddAccount(callee, append([]byte{
+		vm.PUSH0, vm.PUSH0, vm.LOG0,
+		vm.PUSH1, byte(len(errorNope)), vm.PUSH1, 13, vm.PUSH0, vm.CODECOPY,
+		vm.PUSH1, byte(len(errorNope)), vm.PUSH0, vm.REVERT,
+	}, errorNope...), uint256.NewInt(0))
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1}, opcode_handlers.GetHandler, []vm.Transaction{
+		{From: sender, To: &contract, Value: uint256.NewInt(5), Data: []byte{0x01, 0x02}, Gas: 100000},
+	})
+	s.VM.GasSchedule = vm.LondonGasSchedule
+	tracer := NewCallTracer(cfg)
+	s.VM.Tracer = tracer
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	b, err := json.Marshal(tracer)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	return string(b)
+}
+
+const (
+	callTraceRoot   = `"type":"CALL","from":"0x00000000000000000000000000000000000000aa","to":"0x00000000000000000000000000000000000000bb","value":"0x5","gas":"0x186a0","gasUsed":"0x7bb4","input":"0x0102"`
+	callTraceRevert = `{"type":"CALL","from":"0x00000000000000000000000000000000000000bb","to":"0x00000000000000000000000000000000000000cc","value":"0x1","gas":"0x10bf8","gasUsed":"0x1a3","input":"0x","output":"0x` +
+		`08c379a0000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000046e6f706500000000000000000000000000000000000000000000000000000000",` +
+		`"error":"execution reverted","revertReason":"nope"}`
+	callTraceIdentity = `{"type":"STATICCALL","from":"0x00000000000000000000000000000000000000bb","to":"0x0000000000000000000000000000000000000004","gas":"0x106d3","gasUsed":"0x12","input":"0x2a","output":"0x2a"}`
+)
+
+func TestCallTracer(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *CallTracerConfig
+		expected string
+	}{
+		{"default", nil, `{` + callTraceRoot + `,"calls":[` + callTraceRevert + `,` + callTraceIdentity + `]}`},
+		{"only top call", &CallTracerConfig{OnlyTopCall: true}, `{` + callTraceRoot + `}`},
+		// The log of the reverted call is dropped, the log of the caller follows one call
+		{"with log", &CallTracerConfig{WithLog: true}, `{` + callTraceRoot + `,"calls":[` + callTraceRevert + `,` + callTraceIdentity + `],` +
+			`"logs":[{"address":"0x00000000000000000000000000000000000000bb","topics":["0x0000000000000000000000000000000000000000000000000000000000000000"],"data":"0x","index":"0x1","position":"0x1"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runCallTracer(t, tt.cfg); got != tt.expected {
+				t.Errorf("unexpected trace:\n got %s\nwant %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRevertReason(t *testing.T) {
+	panicData, _ := hex.DecodeString("4e487b71" + "0000000000000000000000000000000000000000000000000000000000000011")
+	unknownPanic, _ := hex.DecodeString("4e487b71" + "00000000000000000000000000000000000000000000000000000000000000ff")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+		ok       bool
+	}{
+		{"error", errorNope, "nope", true},
+		{"panic", panicData, "arithmetic underflow or overflow", true},
+		{"unknown panic", unknownPanic, "unknown panic code: 0xff", true},
+		{"truncated error", errorNope[:68], "", false},
+		{"custom error", []byte{0x01, 0x02, 0x03, 0x04}, "", false},
+		{"short", []byte{0x08, 0xc3}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, ok := revertReason(tt.data)
+			if reason != tt.expected || ok != tt.ok {
+				t.Errorf("expected %q, %v, got %q, %v", tt.expected, tt.ok, reason, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package tracers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/daniellehrner/evmdbg/vm"
+)
+
+// jsonLog is an EIP-3155 trace line of a single instruction
+type jsonLog struct {
+	PC      uint64   `json:"pc"`
+	Op      byte     `json:"op"`
+	Gas     hexUint  `json:"gas"`
+	GasCost hexUint  `json:"gasCost"`
+	MemSize int      `json:"memSize"`
+	Stack   []string `json:"stack"`
+	Depth   int      `json:"depth"`
+	Refund  uint64   `json:"refund"`
+	OpName  string   `json:"opName"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// jsonSummary is the EIP-3155 line closing the trace of a transaction
+type jsonSummary struct {
+	Output  string  `json:"output"`
+	GasUsed hexUint `json:"gasUsed"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// JSONLogger is a vm.Tracer writing an EIP-3155 trace: one JSON line per executed
+// instruction, and a summary line with the output, the gas used and the error once the
+// outermost frame returns. The outermost frame is reported by vm.Session; without a
+// session, WriteSummary closes the trace.
+type JSONLogger struct {
+	vm.NoopTracer
+
+	enc *json.Encoder
+	err error
+
+	// The line of the last instruction, written once its outcome is known
+	pending *jsonLog
+}
+
+// NewJSONLogger returns a JSONLogger writing to w
+func NewJSONLogger(w io.Writer) *JSONLogger {
+	return &JSONLogger{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error writing the trace
+func (l *JSONLogger) Err() error {
+	return l.err
+}
+
+func (l *JSONLogger) OnStepStart(step *vm.StepState) {
+	l.flush()
+	l.pending = &jsonLog{
+		PC:      step.PC,
+		Op:      step.Op,
+		Gas:     hexUint(step.Gas),
+		GasCost: hexUint(step.Cost),
+		MemSize: step.Memory.Size(),
+		Stack:   stackItems(step.Stack),
+		Depth:   step.Depth,
+		Refund:  step.Refund,
+		OpName:  vm.OpCode(step.Op).String(),
+	}
+}
+
+func (l *JSONLogger) OnStepEnd(step *vm.StepState, err error) {
+	if l.pending != nil && err != nil {
+		l.pending.Error = err.Error()
+	}
+	l.flush()
+}
+
+func (l *JSONLogger) OnEnter(*vm.CallFrame) {
+	l.flush()
+}
+
+func (l *JSONLogger) OnExit(frame *vm.CallFrame, output []byte, gasUsed uint64, err error) {
+	l.flush()
+	if frame.Depth == 1 {
+		l.WriteSummary(output, gasUsed, err)
+	}
+}
+
+// WriteSummary writes the summary line of the trace
+func (l *JSONLogger) WriteSummary(output []byte, gasUsed uint64, err error) {
+	l.flush()
+	summary := jsonSummary{Output: hex.EncodeToString(output), GasUsed: hexUint(gasUsed)}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	l.write(summary)
+}
+
+// flush writes the line of the last instruction
+func (l *JSONLogger) flush() {
+	if l.pending != nil {
+		l.write(l.pending)
+		l.pending = nil
+	}
+}
+
+func (l *JSONLogger) write(v any) {
+	if err := l.enc.Encode(v); err != nil && l.err == nil {
+		l.err = err
+	}
+}
+
+// stackItems returns the stack from bottom to top as hex quantities
+func stackItems(s *vm.Stack) []string {
+	items := make([]string, s.Len())
+	for i := range items {
+		v, _ := s.Peek(len(items) - 1 - i)
+		items[i] = v.Hex()
+	}
+	return items
+}
+
+// hexUint is a uint64 encoded as a JSON hex quantity
+type hexUint uint64
+
+func (h hexUint) MarshalJSON() ([]byte, error) {
+	return json.Marshal("0x" + strconv.FormatUint(uint64(h), 16))
+}
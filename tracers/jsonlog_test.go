@@ -0,0 +1,98 @@
+package tracers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/daniellehrner/evmdbg/vm/opcode_handlers"
+	"github.com/holiman/uint256"
+)
+
+var (
+	sender   = [20]byte{19: 0xaa}
+	contract = [20]byte{19: 0xbb}
+)
+
+// runTraced executes a transaction from sender to contract with the given code and gas
+// limit, reporting to tracer
+func runTraced(t *testing.T, code []byte, gas uint64, tracer vm.Tracer) *vm.Session {
+	t.Helper()
+
+	sp := state.NewMemoryState()
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	sp.AddAccount(contract, code, uint256.NewInt(0))
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1}, opcode_handlers.GetHandler, []vm.Transaction{
+		{From: sender, To: &contract, Gas: gas},
+	})
+	s.VM.GasSchedule = vm.LondonGasSchedule
+	s.VM.Tracer = tracer
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	return s
+}
+
+func TestJSONLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewJSONLogger(&out)
+	runTraced(t, []byte{
+		vm.PUSH1, 0x01, vm.PUSH1, 0x02, vm.ADD, vm.PUSH0, vm.MSTORE,
+		vm.PUSH1, 0x20, vm.PUSH0, vm.RETURN,
+	}, 30000, logger)
+	if err := logger.Err(); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	expected := `{"pc":0,"op":96,"gas":"0x2328","gasCost":"0x3","memSize":0,"stack":[],"depth":1,"refund":0,"opName":"PUSH1"}
+{"pc":2,"op":96,"gas":"0x2325","gasCost":"0x3","memSize":0,"stack":["0x1"],"depth":1,"refund":0,"opName":"PUSH1"}
+{"pc":4,"op":1,"gas":"0x2322","gasCost":"0x3","memSize":0,"stack":["0x1","0x2"],"depth":1,"refund":0,"opName":"ADD"}
+{"pc":5,"op":95,"gas":"0x231f","gasCost":"0x2","memSize":0,"stack":["0x3"],"depth":1,"refund":0,"opName":"PUSH0"}
+{"pc":6,"op":82,"gas":"0x231d","gasCost":"0x6","memSize":0,"stack":["0x3","0x0"],"depth":1,"refund":0,"opName":"MSTORE"}
+{"pc":7,"op":96,"gas":"0x2317","gasCost":"0x3","memSize":32,"stack":[],"depth":1,"refund":0,"opName":"PUSH1"}
+{"pc":9,"op":95,"gas":"0x2314","gasCost":"0x2","memSize":32,"stack":["0x20"],"depth":1,"refund":0,"opName":"PUSH0"}
+{"pc":10,"op":243,"gas":"0x2312","gasCost":"0x0","memSize":32,"stack":["0x20","0x0"],"depth":1,"refund":0,"opName":"RETURN"}
+{"output":"0000000000000000000000000000000000000000000000000000000000000003","gasUsed":"0x521e"}
+`
+	if out.String() != expected {
+		t.Fatalf("unexpected trace:\n%s\nwant:\n%s", out.String(), expected)
+	}
+}
+
+func TestJSONLoggerError(t *testing.T) {
+	var out bytes.Buffer
+	runTraced(t, []byte{vm.PUSH1, 0x05, vm.JUMP}, 30000, NewJSONLogger(&out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d:\n%s", len(lines), out.String())
+	}
+	if want := `"opName":"JUMP","error":"invalid jump destination"}`; !strings.HasSuffix(lines[1], want) {
+		t.Errorf("expected the JUMP line to end with %s, got %s", want, lines[1])
+	}
+	if want := `{"output":"","gasUsed":"0x7530","error":"invalid jump destination"}`; lines[2] != want {
+		t.Errorf("expected summary %s, got %s", want, lines[2])
+	}
+}
+
+func TestJSONLoggerNestedCall(t *testing.T) {
+	var out bytes.Buffer
+	// Calls itself without gas, the nested frame runs out of gas on its first instruction
+	runTraced(t, []byte{
+		vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.ADDRESS, vm.PUSH0, vm.CALL, vm.STOP,
+	}, 30000, NewJSONLogger(&out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 11 {
+		t.Fatalf("expected 11 lines, got %d:\n%s", len(lines), out.String())
+	}
+	if want := `"depth":2,"refund":0,"opName":"PUSH0","error":"out of gas"}`; !strings.HasSuffix(lines[8], want) {
+		t.Errorf("expected the nested line to end with %s, got %s", want, lines[8])
+	}
+	if !strings.HasSuffix(lines[9], `"depth":1,"refund":0,"opName":"STOP"}`) {
+		t.Errorf("expected STOP in the outer frame, got %s", lines[9])
+	}
+}
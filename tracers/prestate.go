@@ -0,0 +1,284 @@
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+	"golang.org/x/crypto/sha3"
+)
+
+// PrestateTracerConfig holds the options of the prestate tracer, using the names of
+// geth's prestateTracer config
+type PrestateTracerConfig struct {
+	DiffMode       bool `json:"diffMode"`       // return the pre and post state of the changed accounts
+	DisableCode    bool `json:"disableCode"`    // omit the code of the accounts
+	DisableStorage bool `json:"disableStorage"` // omit the storage of the accounts
+	IncludeEmpty   bool `json:"includeEmpty"`   // keep accounts that were empty, ignored in diff mode
+}
+
+// PrestateAccount is the state of an account in the prestate tracer output. In the post
+// state of diff mode only the changed fields are set.
+type PrestateAccount struct {
+	Balance  string            `json:"balance,omitempty"`
+	Code     *string           `json:"code,omitempty"`
+	CodeHash string            `json:"codeHash,omitempty"`
+	Nonce    uint64            `json:"nonce,omitempty"`
+	Storage  map[string]string `json:"storage,omitempty"`
+}
+
+// PrestateDiff is the output of the prestate tracer in diff mode
+type PrestateDiff struct {
+	Post map[string]*PrestateAccount `json:"post"`
+	Pre  map[string]*PrestateAccount `json:"pre"`
+}
+
+// prestateAccount holds the values of an account before the execution, a field is unknown
+// until the account is changed or the result is built
+type prestateAccount struct {
+	balance    *uint256.Int
+	nonce      uint64
+	nonceKnown bool
+	code       []byte
+	codeKnown  bool
+	storage    map[[32]byte]*uint256.Int
+}
+
+// PrestateTracer is a vm.Tracer recording the accounts and storage slots touched by an
+// execution with their values before it, in the format of geth's prestateTracer. It
+// covers everything executed while it is installed, so the output of a Session holds
+// the state needed to replay all of its transactions.
+//
+// The previous values of changes are taken from the tracer events, the values of fields
+// that did not change are read from the StateProvider when the result is built, as are
+// the post values in diff mode.
+type PrestateTracer struct {
+	vm.NoopTracer
+
+	sp       vm.StateProvider
+	cfg      PrestateTracerConfig
+	accounts map[[20]byte]*prestateAccount
+}
+
+// NewPrestateTracer returns a PrestateTracer reading the state from sp with the given
+// options, cfg may be nil
+func NewPrestateTracer(sp vm.StateProvider, cfg *PrestateTracerConfig) *PrestateTracer {
+	t := &PrestateTracer{sp: sp, accounts: make(map[[20]byte]*prestateAccount)}
+	if cfg != nil {
+		t.cfg = *cfg
+	}
+	return t
+}
+
+func (t *PrestateTracer) OnStepStart(step *vm.StepState) {
+	top, err := step.Stack.Peek(0)
+	if err != nil {
+		return
+	}
+	switch vm.OpCode(step.Op) {
+	case vm.SLOAD, vm.SSTORE:
+		t.touchSlot(step.Address, top, nil)
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.EXTCODEHASH, vm.SELFDESTRUCT:
+		t.touch(top.Bytes20())
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// The callee is touched even if the call fails before entering a frame
+		if addr, err := step.Stack.Peek(1); err == nil {
+			t.touch(addr.Bytes20())
+		}
+	}
+}
+
+func (t *PrestateTracer) OnEnter(frame *vm.CallFrame) {
+	t.touch(frame.From)
+	t.touch(frame.To)
+	if target, ok := vm.ParseDelegation(t.sp.GetCode(frame.To)); ok {
+		t.touch(target)
+	}
+}
+
+func (t *PrestateTracer) OnStorageChange(addr [20]byte, slot, prev, _ *uint256.Int) {
+	t.touchSlot(addr, slot, prev)
+}
+
+func (t *PrestateTracer) OnBalanceChange(addr [20]byte, prev, _ *uint256.Int) {
+	if acc := t.touch(addr); acc.balance == nil {
+		acc.balance = new(uint256.Int).Set(prev)
+	}
+}
+
+func (t *PrestateTracer) OnNonceChange(addr [20]byte, prev, _ uint64) {
+	if acc := t.touch(addr); !acc.nonceKnown {
+		acc.nonce, acc.nonceKnown = prev, true
+	}
+}
+
+func (t *PrestateTracer) OnCodeChange(addr [20]byte, prev, _ []byte) {
+	if acc := t.touch(addr); !acc.codeKnown {
+		acc.code, acc.codeKnown = bytes.Clone(prev), true
+	}
+}
+
+// touch records addr as accessed and returns its entry
+func (t *PrestateTracer) touch(addr [20]byte) *prestateAccount {
+	acc := t.accounts[addr]
+	if acc == nil {
+		acc = &prestateAccount{storage: make(map[[32]byte]*uint256.Int)}
+		t.accounts[addr] = acc
+	}
+	return acc
+}
+
+// touchSlot records the value of a slot of addr before its first access, prev is the
+// value before a write or nil to read it from the state
+func (t *PrestateTracer) touchSlot(addr [20]byte, slot, prev *uint256.Int) {
+	acc := t.touch(addr)
+	key := slot.Bytes32()
+	if _, ok := acc.storage[key]; ok {
+		return
+	}
+	if prev == nil {
+		prev = t.sp.GetStorage(addr, slot)
+	}
+	acc.storage[key] = new(uint256.Int).Set(prev)
+}
+
+// resolve fills the fields of acc that did not change with their current values
+func (t *PrestateTracer) resolve(addr [20]byte, acc *prestateAccount) {
+	if acc.balance == nil {
+		acc.balance = new(uint256.Int).Set(t.sp.GetBalance(addr))
+	}
+	if !acc.nonceKnown {
+		acc.nonce, acc.nonceKnown = t.sp.GetNonce(addr), true
+	}
+	if !acc.codeKnown {
+		acc.code, acc.codeKnown = bytes.Clone(t.sp.GetCode(addr)), true
+	}
+}
+
+// Prestate returns the accounts touched so far with their values before the execution
+func (t *PrestateTracer) Prestate() map[string]*PrestateAccount {
+	pre := make(map[string]*PrestateAccount)
+	for addr, acc := range t.accounts {
+		t.resolve(addr, acc)
+		if t.cfg.IncludeEmpty || !acc.empty() {
+			pre[hexAddress(addr)] = t.account(acc)
+		}
+	}
+	return pre
+}
+
+// Diff returns the accounts changed so far with the changed fields before and after the
+// execution. Slots that did not change are omitted and zero slots are omitted on either
+// side. A deleted account only has a pre state.
+func (t *PrestateTracer) Diff() *PrestateDiff {
+	diff := &PrestateDiff{
+		Post: make(map[string]*PrestateAccount),
+		Pre:  make(map[string]*PrestateAccount),
+	}
+	for addr, acc := range t.accounts {
+		t.resolve(addr, acc)
+		if acc.empty() {
+			// A created account only has a post state
+			if post, modified := t.post(addr, acc); modified {
+				diff.Post[hexAddress(addr)] = post
+			}
+			continue
+		}
+
+		pre := t.account(acc)
+		if !t.sp.AccountExists(addr) {
+			diff.Pre[hexAddress(addr)] = pre
+			continue
+		}
+		post, modified := t.post(addr, acc)
+		if !modified {
+			continue
+		}
+		for key, value := range acc.storage {
+			if current := t.sp.GetStorage(addr, new(uint256.Int).SetBytes(key[:])); value.IsZero() || value.Eq(current) {
+				delete(pre.Storage, hexData(key[:]))
+			}
+		}
+		if len(pre.Storage) == 0 {
+			pre.Storage = nil
+		}
+		diff.Pre[hexAddress(addr)] = pre
+		diff.Post[hexAddress(addr)] = post
+	}
+	return diff
+}
+
+// post returns the fields of an account that changed and whether any did
+func (t *PrestateTracer) post(addr [20]byte, acc *prestateAccount) (*PrestateAccount, bool) {
+	post := &PrestateAccount{}
+	modified := false
+	if balance := t.sp.GetBalance(addr); !balance.Eq(acc.balance) {
+		post.Balance, modified = balance.Hex(), true
+	}
+	if nonce := t.sp.GetNonce(addr); nonce != acc.nonce {
+		post.Nonce, modified = nonce, true
+	}
+	if code := t.sp.GetCode(addr); !bytes.Equal(code, acc.code) {
+		post.CodeHash, modified = codeHash(code), true
+		if !t.cfg.DisableCode {
+			c := hexData(code)
+			post.Code = &c
+		}
+	}
+	if !t.cfg.DisableStorage {
+		for key, value := range acc.storage {
+			current := t.sp.GetStorage(addr, new(uint256.Int).SetBytes(key[:]))
+			if current.Eq(value) {
+				continue
+			}
+			modified = true
+			if !current.IsZero() {
+				if post.Storage == nil {
+					post.Storage = make(map[string]string)
+				}
+				post.Storage[hexData(key[:])] = word(current)
+			}
+		}
+	}
+	return post, modified
+}
+
+// account returns the output of the values of acc before the execution
+func (t *PrestateTracer) account(acc *prestateAccount) *PrestateAccount {
+	out := &PrestateAccount{Balance: acc.balance.Hex(), Nonce: acc.nonce}
+	if len(acc.code) > 0 {
+		out.CodeHash = codeHash(acc.code)
+		if !t.cfg.DisableCode {
+			c := hexData(acc.code)
+			out.Code = &c
+		}
+	}
+	if !t.cfg.DisableStorage && len(acc.storage) > 0 {
+		out.Storage = make(map[string]string, len(acc.storage))
+		for key, value := range acc.storage {
+			out.Storage[hexData(key[:])] = word(value)
+		}
+	}
+	return out
+}
+
+// MarshalJSON encodes the prestate, or the diff in diff mode
+func (t *PrestateTracer) MarshalJSON() ([]byte, error) {
+	if t.cfg.DiffMode {
+		return json.Marshal(t.Diff())
+	}
+	return json.Marshal(t.Prestate())
+}
+
+// empty reports whether the account had no balance, nonce or code before the execution
+func (acc *prestateAccount) empty() bool {
+	return acc.balance.IsZero() && acc.nonce == 0 && len(acc.code) == 0
+}
+
+// codeHash returns the Keccak-256 hash of code as 0x prefixed hex string
+func codeHash(code []byte) string {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(code)
+	return hexData(hasher.Sum(nil))
+}
@@ -0,0 +1,89 @@
+package tracers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/daniellehrner/evmdbg/vm/opcode_handlers"
+	"github.com/holiman/uint256"
+)
+
+func runPrestateTracer(t *testing.T, cfg *PrestateTracerConfig) string {
+	t.Helper()
+
+	other := [20]byte{19: 0xdd}
+	sp := state.NewMemoryState()
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	sp.AddAccount(other, nil, uint256.NewInt(3))
+	// Reads slot 2, writes slot 1, reads the balance of 0xdd and calls 0xcc
+	sp.AddAccount(contract, []byte{
+		vm.PUSH1, 0x02, vm.SLOAD, vm.POP,
+		vm.PUSH1, 0x2a, vm.PUSH1, 0x01, vm.SSTORE,
+		vm.PUSH1, 0xdd, vm.BALANCE, vm.POP,
+		vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0xcc, vm.GAS, vm.CALL,
+		vm.STOP,
+	}, uint256.NewInt(0))
+	sp.SetStorage(contract, uint256.NewInt(1), uint256.NewInt(5))
+	sp.SetStorage(contract, uint256.NewInt(2), uint256.NewInt(7))
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1}, opcode_handlers.GetHandler, []vm.Transaction{
+		{From: sender, To: &contract, Value: uint256.NewInt(5), Gas: 100000},
+		// Deploys the code 0x2a
+		{From: sender, Data: []byte{vm.PUSH1, 0x2a, vm.PUSH0, vm.MSTORE8, vm.PUSH1, 0x01, vm.PUSH0, vm.RETURN}, Gas: 100000},
+	})
+	s.VM.GasSchedule = vm.LondonGasSchedule
+	tracer := NewPrestateTracer(sp, cfg)
+	s.VM.Tracer = tracer
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	for i, result := range s.Results {
+		if result.Failed() {
+			t.Fatalf("transaction %d failed: %v", i, result.Err)
+		}
+	}
+	b, err := json.Marshal(tracer)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	return string(b)
+}
+
+const (
+	prestateSender   = `"0x00000000000000000000000000000000000000aa":{"balance":"0x3e8"}`
+	prestateContract = `"0x00000000000000000000000000000000000000bb":{"balance":"0x0","code":"0x60025450602a60015560dd31505f5f5f5f5f60cc5af100",` +
+		`"codeHash":"0xa91e69c39d4ae51c710b57eb1203786ac7d17d8a6b57a7ca0c8a4a5dbcce51f0","storage":{"` + slot1 + `":"` + word5
+	slot2 = "0x0000000000000000000000000000000000000000000000000000000000000002"
+	word5 = "0x0000000000000000000000000000000000000000000000000000000000000005"
+	word7 = "0x0000000000000000000000000000000000000000000000000000000000000007"
+)
+
+func TestPrestateTracer(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *PrestateTracerConfig
+		expected string
+	}{
+		// The empty callee and the created account are omitted
+		{"prestate", nil, `{` + prestateSender + `,` + prestateContract + `","` + slot2 + `":"` + word7 + `"}},` +
+			`"0x00000000000000000000000000000000000000dd":{"balance":"0x3"}}`},
+		{"include empty", &PrestateTracerConfig{IncludeEmpty: true, DisableCode: true, DisableStorage: true}, `{` + prestateSender + `,` +
+			`"0x00000000000000000000000000000000000000bb":{"balance":"0x0","codeHash":"0xa91e69c39d4ae51c710b57eb1203786ac7d17d8a6b57a7ca0c8a4a5dbcce51f0"},` +
+			`"0x00000000000000000000000000000000000000cc":{"balance":"0x0"},"0x00000000000000000000000000000000000000dd":{"balance":"0x3"},` +
+			`"0xccec344d9d8246c8d06d99ccefc856bfa17e0526":{"balance":"0x0"}}`},
+		// Only changed accounts and slots are kept, the created account has no pre state
+		{"diff", &PrestateTracerConfig{DiffMode: true}, `{"post":{"0x00000000000000000000000000000000000000aa":{"balance":"0x3e3","nonce":2},` +
+			`"0x00000000000000000000000000000000000000bb":{"balance":"0x5","storage":{"` + slot1 + `":"` + word2a + `"}},` +
+			`"0xccec344d9d8246c8d06d99ccefc856bfa17e0526":{"code":"0x2a","codeHash":"0x04994f67dc55b09e814ab7ffc8df3686b4afb2bb53e60eae97ef043fe03fb829","nonce":1}},` +
+			`"pre":{` + prestateSender + `,` + prestateContract + `"}}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runPrestateTracer(t, tt.cfg); got != tt.expected {
+				t.Errorf("unexpected prestate:\n got %s\nwant %s", got, tt.expected)
+			}
+		})
+	}
+}
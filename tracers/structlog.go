@@ -0,0 +1,189 @@
+package tracers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// StructLogConfig holds the options of the struct logger, using the names of the
+// debug_traceTransaction tracer config
+type StructLogConfig struct {
+	DisableStack     bool `json:"disableStack"`
+	DisableMemory    bool `json:"disableMemory"`
+	DisableStorage   bool `json:"disableStorage"`
+	EnableReturnData bool `json:"enableReturnData"`
+}
+
+// StructLog is a single instruction in the struct logger output
+type StructLog struct {
+	PC         uint64             `json:"pc"`
+	Op         string             `json:"op"`
+	Gas        uint64             `json:"gas"`
+	GasCost    uint64             `json:"gasCost"`
+	Depth      int                `json:"depth"`
+	Error      string             `json:"error,omitempty"`
+	Stack      *[]string          `json:"stack,omitempty"`
+	ReturnData string             `json:"returnData,omitempty"`
+	Memory     *[]string          `json:"memory,omitempty"`
+	Storage    *map[string]string `json:"storage,omitempty"`
+	Refund     uint64             `json:"refund,omitempty"`
+}
+
+// ExecutionResult is the struct logger output of a transaction as returned by geth's
+// debug_traceTransaction
+type ExecutionResult struct {
+	Gas         uint64      `json:"gas"`
+	Failed      bool        `json:"failed"`
+	ReturnValue string      `json:"returnValue"`
+	StructLogs  []StructLog `json:"structLogs"`
+}
+
+// StructLogger is a vm.Tracer recording the instructions of a transaction in the format
+// of geth's default struct logger. The storage of an SLOAD or SSTORE holds the slots of
+// the executing account accessed so far in the transaction.
+type StructLogger struct {
+	vm.NoopTracer
+
+	cfg     StructLogConfig
+	logs    []StructLog
+	storage map[[20]byte]map[string]string
+
+	// The slot accessed by the last instruction if it is an SLOAD, whose value is only
+	// known once it executed
+	sload *uint256.Int
+
+	output  []byte
+	gasUsed uint64
+	err     error
+}
+
+// NewStructLogger returns a StructLogger with the given options, cfg may be nil
+func NewStructLogger(cfg *StructLogConfig) *StructLogger {
+	l := &StructLogger{storage: make(map[[20]byte]map[string]string)}
+	if cfg != nil {
+		l.cfg = *cfg
+	}
+	return l
+}
+
+func (l *StructLogger) OnStepStart(step *vm.StepState) {
+	log := StructLog{
+		PC:      step.PC,
+		Op:      vm.OpCode(step.Op).String(),
+		Gas:     step.Gas,
+		GasCost: step.Cost,
+		Depth:   step.Depth,
+		Refund:  step.Refund,
+	}
+	if !l.cfg.DisableStack {
+		stack := stackItems(step.Stack)
+		log.Stack = &stack
+	}
+	if !l.cfg.DisableMemory {
+		data := step.Memory.Data()
+		data = data[:min(len(data), step.Memory.Size())]
+		if len(data) > 0 {
+			memory := make([]string, 0, len(data)/32)
+			for i := 0; i+32 <= len(data); i += 32 {
+				memory = append(memory, "0x"+hex.EncodeToString(data[i:i+32]))
+			}
+			log.Memory = &memory
+		}
+	}
+	if l.cfg.EnableReturnData && len(step.ReturnData) > 0 {
+		log.ReturnData = "0x" + hex.EncodeToString(step.ReturnData)
+	}
+
+	l.sload = nil
+	if !l.cfg.DisableStorage {
+		switch vm.OpCode(step.Op) {
+		case vm.SLOAD:
+			if slot, err := step.Stack.Peek(0); err == nil {
+				l.sload = new(uint256.Int).Set(slot)
+			}
+		case vm.SSTORE:
+			slot, err1 := step.Stack.Peek(0)
+			value, err2 := step.Stack.Peek(1)
+			if err1 == nil && err2 == nil {
+				log.Storage = l.storeSlot(step.Address, slot, value)
+			}
+		}
+	}
+	l.logs = append(l.logs, log)
+}
+
+func (l *StructLogger) OnStepEnd(step *vm.StepState, err error) {
+	if len(l.logs) == 0 {
+		return
+	}
+	log := &l.logs[len(l.logs)-1]
+	if err != nil {
+		log.Error = err.Error()
+	}
+	if l.sload != nil && err == nil {
+		// SLOAD replaced the slot with its value on the stack
+		if value, err := step.Stack.Peek(0); err == nil {
+			log.Storage = l.storeSlot(step.Address, l.sload, value)
+		}
+	}
+	l.sload = nil
+}
+
+func (l *StructLogger) OnExit(frame *vm.CallFrame, output []byte, gasUsed uint64, err error) {
+	if frame.Depth == 1 {
+		l.output, l.gasUsed, l.err = output, gasUsed, err
+	}
+}
+
+// storeSlot records the value of a slot of addr and returns a copy of the slots of addr
+// accessed so far
+func (l *StructLogger) storeSlot(addr [20]byte, slot, value *uint256.Int) *map[string]string {
+	slots := l.storage[addr]
+	if slots == nil {
+		slots = make(map[string]string)
+		l.storage[addr] = slots
+	}
+	slots[word(slot)] = word(value)
+	storage := maps.Clone(slots)
+	return &storage
+}
+
+// StructLogs returns the instructions recorded so far
+func (l *StructLogger) StructLogs() []StructLog {
+	return l.logs
+}
+
+// Result returns the output of the transaction once its outermost frame returned. The
+// return value of a failed transaction is only kept if it reverted.
+func (l *StructLogger) Result() *ExecutionResult {
+	result := &ExecutionResult{
+		Gas:        l.gasUsed,
+		Failed:     l.err != nil,
+		StructLogs: l.logs,
+	}
+	if l.err == nil || errors.Is(l.err, vm.ErrExecutionReverted) {
+		result.ReturnValue = "0x" + hex.EncodeToString(l.output)
+	} else {
+		result.ReturnValue = "0x"
+	}
+	if result.StructLogs == nil {
+		result.StructLogs = []StructLog{}
+	}
+	return result
+}
+
+// MarshalJSON encodes the result of the transaction
+func (l *StructLogger) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Result())
+}
+
+// word returns v as 0x prefixed 32-byte hex string
+func word(v *uint256.Int) string {
+	b := v.Bytes32()
+	return "0x" + hex.EncodeToString(b[:])
+}
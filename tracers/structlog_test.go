@@ -0,0 +1,92 @@
+package tracers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/vm"
+)
+
+// structLogCode stores 0x2a in slot 1, loads it and reverts with it
+var structLogCode = []byte{
+	vm.PUSH1, 0x2a, vm.PUSH1, 0x01, vm.SSTORE,
+	vm.PUSH1, 0x01, vm.SLOAD,
+	vm.PUSH0, vm.MSTORE,
+	vm.PUSH1, 0x20, vm.PUSH0, vm.REVERT,
+}
+
+const (
+	slot1   = "0x0000000000000000000000000000000000000000000000000000000000000001"
+	word2a  = "0x000000000000000000000000000000000000000000000000000000000000002a"
+	storage = `{"` + slot1 + `":"` + word2a + `"}`
+)
+
+func marshal(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	return string(b)
+}
+
+func TestStructLogger(t *testing.T) {
+	logger := NewStructLogger(nil)
+	runTraced(t, structLogCode, 100000, logger)
+
+	result := logger.Result()
+	if result.Gas != 43222 || !result.Failed || result.ReturnValue != word2a {
+		t.Errorf("unexpected result: gas=%d failed=%v returnValue=%s", result.Gas, result.Failed, result.ReturnValue)
+	}
+	if len(result.StructLogs) != 10 {
+		t.Fatalf("expected 10 struct logs, got %d", len(result.StructLogs))
+	}
+
+	tests := []struct {
+		index    int
+		expected string
+	}{
+		{0, `{"pc":0,"op":"PUSH1","gas":79000,"gasCost":3,"depth":1,"stack":[]}`},
+		{2, `{"pc":4,"op":"SSTORE","gas":78994,"gasCost":22100,"depth":1,"stack":["0x2a","0x1"],"storage":` + storage + `}`},
+		{4, `{"pc":7,"op":"SLOAD","gas":56891,"gasCost":100,"depth":1,"stack":["0x1"],"storage":` + storage + `}`},
+		{9, `{"pc":13,"op":"REVERT","gas":56778,"gasCost":0,"depth":1,"stack":["0x20","0x0"],"memory":["` + word2a + `"]}`},
+	}
+	for _, tt := range tests {
+		if got := marshal(t, result.StructLogs[tt.index]); got != tt.expected {
+			t.Errorf("struct log %d:\n got %s\nwant %s", tt.index, got, tt.expected)
+		}
+	}
+}
+
+func TestStructLoggerOptions(t *testing.T) {
+	logger := NewStructLogger(&StructLogConfig{DisableStack: true, DisableMemory: true, DisableStorage: true})
+	runTraced(t, structLogCode, 100000, logger)
+
+	for i, log := range logger.StructLogs() {
+		if log.Stack != nil || log.Memory != nil || log.Storage != nil {
+			t.Fatalf("struct log %d: expected no stack, memory and storage, got %s", i, marshal(t, log))
+		}
+	}
+}
+
+func TestStructLoggerReturnData(t *testing.T) {
+	var cfg StructLogConfig
+	if err := json.Unmarshal([]byte(`{"enableReturnData":true,"disableStack":true}`), &cfg); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	logger := NewStructLogger(&cfg)
+	// Calls the identity precompile with 0x2a and stops
+	runTraced(t, []byte{
+		vm.PUSH1, 0x2a, vm.PUSH0, vm.MSTORE8,
+		vm.PUSH0, vm.PUSH0, vm.PUSH1, 0x01, vm.PUSH0, vm.PUSH1, 0x04, vm.GAS, vm.STATICCALL,
+		vm.STOP,
+	}, 100000, logger)
+
+	logs := logger.StructLogs()
+	if expected := `{"pc":13,"op":"STOP","gas":78857,"gasCost":0,"depth":1,"returnData":"0x2a","memory":["0x2a00000000000000000000000000000000000000000000000000000000000000"]}`; marshal(t, logs[len(logs)-1]) != expected {
+		t.Errorf("unexpected last struct log:\n got %s\nwant %s", marshal(t, logs[len(logs)-1]), expected)
+	}
+	if result := logger.Result(); result.Failed || result.ReturnValue != "0x" {
+		t.Errorf("unexpected result: failed=%v returnValue=%s", result.Failed, result.ReturnValue)
+	}
+}
@@ -0,0 +1,235 @@
+package trie
+
+import (
+	"errors"
+
+	"github.com/daniellehrner/evmdbg/rlp"
+	"golang.org/x/crypto/sha3"
+)
+
+var errInvalidNode = errors.New("trie: invalid node encoding")
+
+// node is one of *shortNode, *fullNode, valueNode or hashNode
+type node interface{}
+
+// shortNode is an extension node, or a leaf node if its key ends with the terminator nibble
+type shortNode struct {
+	Key []byte // nibbles
+	Val node
+}
+
+// fullNode is a branch node with 16 children and an optional value at index 16
+type fullNode struct {
+	Children [17]node
+}
+
+// valueNode holds the value stored under a key
+type valueNode []byte
+
+// hashNode is a reference to a node that is not resolved in memory
+type hashNode []byte
+
+// terminator marks the end of a leaf key in nibble form
+const terminator = 16
+
+// encodeNode returns the RLP encoding of a node
+func encodeNode(n node) []byte {
+	switch n := n.(type) {
+	case nil:
+		return rlp.EncodeBytes(nil)
+	case *shortNode:
+		var val []byte
+		if v, ok := n.Val.(valueNode); ok {
+			val = rlp.EncodeBytes(v)
+		} else {
+			val = nodeRef(n.Val)
+		}
+		return rlp.EncodeList(rlp.EncodeBytes(hexToCompact(n.Key)), val)
+	case *fullNode:
+		items := make([][]byte, 17)
+		for i := 0; i < 16; i++ {
+			items[i] = nodeRef(n.Children[i])
+		}
+		if v, ok := n.Children[16].(valueNode); ok {
+			items[16] = rlp.EncodeBytes(v)
+		} else {
+			items[16] = rlp.EncodeBytes(nil)
+		}
+		return rlp.EncodeList(items...)
+	case valueNode:
+		return rlp.EncodeBytes(n)
+	case hashNode:
+		return rlp.EncodeBytes(n)
+	default:
+		panic("trie: unknown node type")
+	}
+}
+
+// nodeRef returns how a child is referenced from its parent: nodes whose encoding is
+// shorter than 32 bytes are embedded, all others are referenced by their hash
+func nodeRef(n node) []byte {
+	switch n := n.(type) {
+	case nil:
+		return rlp.EncodeBytes(nil)
+	case hashNode:
+		return rlp.EncodeBytes(n)
+	}
+
+	enc := encodeNode(n)
+	if len(enc) < 32 {
+		return enc
+	}
+	return rlp.EncodeBytes(keccak256(enc))
+}
+
+// decodeNode decodes an RLP encoded trie node
+func decodeNode(enc []byte) (node, error) {
+	items, err := rlp.ListItems(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(items) {
+	case 2:
+		compact, _, err := rlp.SplitString(items[0])
+		if err != nil {
+			return nil, err
+		}
+		key := compactToHex(compact)
+		if hasTerm(key) {
+			val, _, err := rlp.SplitString(items[1])
+			if err != nil {
+				return nil, err
+			}
+			return &shortNode{Key: key, Val: valueNode(val)}, nil
+		}
+		child, err := decodeRef(items[1])
+		if err != nil {
+			return nil, err
+		}
+		return &shortNode{Key: key, Val: child}, nil
+	case 17:
+		n := &fullNode{}
+		for i := 0; i < 16; i++ {
+			child, err := decodeRef(items[i])
+			if err != nil {
+				return nil, err
+			}
+			n.Children[i] = child
+		}
+		val, _, err := rlp.SplitString(items[16])
+		if err != nil {
+			return nil, err
+		}
+		if len(val) > 0 {
+			n.Children[16] = valueNode(val)
+		}
+		return n, nil
+	default:
+		return nil, errInvalidNode
+	}
+}
+
+// decodeRef decodes a child reference, which is either an embedded node, a hash or empty
+func decodeRef(b []byte) (node, error) {
+	kind, content, _, err := rlp.Split(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == rlp.List {
+		if len(b) >= 32 {
+			return nil, errInvalidNode
+		}
+		return decodeNode(b)
+	}
+
+	switch len(content) {
+	case 0:
+		return nil, nil
+	case 32:
+		return hashNode(content), nil
+	default:
+		return nil, errInvalidNode
+	}
+}
+
+// keybytesToHex converts a key to nibbles, appending the terminator
+func keybytesToHex(key []byte) []byte {
+	nibbles := make([]byte, len(key)*2+1)
+	for i, b := range key {
+		nibbles[i*2] = b / 16
+		nibbles[i*2+1] = b % 16
+	}
+	nibbles[len(nibbles)-1] = terminator
+	return nibbles
+}
+
+// hexToCompact converts nibbles to the hex-prefix encoding used in trie nodes
+func hexToCompact(hex []byte) []byte {
+	flag := byte(0)
+	if hasTerm(hex) {
+		flag = 1
+		hex = hex[:len(hex)-1]
+	}
+
+	buf := make([]byte, len(hex)/2+1)
+	buf[0] = flag << 5
+	if len(hex)&1 == 1 {
+		buf[0] |= 1<<4 | hex[0]
+		hex = hex[1:]
+	}
+	for i := 0; i < len(hex); i += 2 {
+		buf[i/2+1] = hex[i]<<4 | hex[i+1]
+	}
+	return buf
+}
+
+// compactToHex converts the hex-prefix encoding back to nibbles
+func compactToHex(compact []byte) []byte {
+	if len(compact) == 0 {
+		return nil
+	}
+
+	nibbles := make([]byte, 0, len(compact)*2+1)
+	for _, b := range compact {
+		nibbles = append(nibbles, b/16, b%16)
+	}
+
+	// Drop the flag nibble, and the padding nibble for even length keys
+	if nibbles[0]&1 == 1 {
+		nibbles = nibbles[1:]
+	} else {
+		nibbles = nibbles[2:]
+	}
+	if compact[0]&0x20 != 0 {
+		nibbles = append(nibbles, terminator)
+	}
+	return nibbles
+}
+
+func hasTerm(nibbles []byte) bool {
+	return len(nibbles) > 0 && nibbles[len(nibbles)-1] == terminator
+}
+
+func prefixLen(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func concat(a []byte, b ...byte) []byte {
+	out := make([]byte, 0, len(a)+len(b))
+	out = append(out, a...)
+	return append(out, b...)
+}
+
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
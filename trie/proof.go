@@ -0,0 +1,119 @@
+package trie
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrProofMissingNode is returned when a proof does not contain a node referenced on the key path
+var ErrProofMissingNode = errors.New("trie: proof is missing a node")
+
+// Prove returns the encoded nodes on the path to key, starting at the root. Nodes that are
+// embedded in their parent are not included. The proof also works for absent keys, in
+// which case it proves their absence.
+func (t *Trie) Prove(key []byte) [][]byte {
+	path := keybytesToHex(key)
+
+	var proof [][]byte
+	n := t.root
+	for n != nil {
+		var next node
+		switch nn := n.(type) {
+		case *shortNode:
+			if prefixLen(path, nn.Key) == len(nn.Key) {
+				path = path[len(nn.Key):]
+				next = nn.Val
+			}
+		case *fullNode:
+			next = nn.Children[path[0]]
+			path = path[1:]
+		case valueNode, hashNode:
+			return proof
+		}
+
+		enc := encodeNode(n)
+		if len(proof) == 0 || len(enc) >= 32 {
+			proof = append(proof, enc)
+		}
+		n = next
+	}
+	return proof
+}
+
+// Prove returns the proof for keccak256(key), see Trie.Prove
+func (t *SecureTrie) Prove(key []byte) [][]byte {
+	return t.trie.Prove(keccak256(key))
+}
+
+// VerifyProof checks a proof for key against root. It returns the value stored under key,
+// or nil if the proof shows that key is absent. An error is returned if the proof is invalid.
+func VerifyProof(root [32]byte, key []byte, proof [][]byte) ([]byte, error) {
+	if root == EmptyRoot {
+		return nil, nil
+	}
+
+	nodes := make(map[string][]byte, len(proof))
+	for _, enc := range proof {
+		nodes[string(keccak256(enc))] = enc
+	}
+
+	path := keybytesToHex(key)
+	want := root[:]
+	for i := 0; ; i++ {
+		enc, ok := nodes[string(want)]
+		if !ok {
+			return nil, fmt.Errorf("%w: node %d with hash %x", ErrProofMissingNode, i, want)
+		}
+
+		n, err := decodeNode(enc)
+		if err != nil {
+			return nil, fmt.Errorf("trie: bad proof node %d: %w", i, err)
+		}
+
+		var child node
+		path, child = walk(n, path)
+		switch c := child.(type) {
+		case nil:
+			return nil, nil
+		case valueNode:
+			return c, nil
+		case hashNode:
+			want = c
+		}
+	}
+}
+
+// VerifySecureProof checks a proof for keccak256(key) against root, see VerifyProof
+func VerifySecureProof(root [32]byte, key []byte, proof [][]byte) ([]byte, error) {
+	return VerifyProof(root, keccak256(key), proof)
+}
+
+// walk follows path through resolved nodes and returns the remaining path together with
+// the value or unresolved hash reached, or nil if the path leaves the trie
+func walk(n node, path []byte) ([]byte, node) {
+	for {
+		switch nn := n.(type) {
+		case nil:
+			return nil, nil
+		case *shortNode:
+			if prefixLen(path, nn.Key) != len(nn.Key) {
+				return nil, nil
+			}
+			path = path[len(nn.Key):]
+			n = nn.Val
+		case *fullNode:
+			if len(path) == 0 {
+				return nil, nil
+			}
+			n = nn.Children[path[0]]
+			path = path[1:]
+		case valueNode:
+			if len(path) != 0 {
+				return nil, nil
+			}
+			return path, nn
+		case hashNode:
+			return path, nn
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func proofTestTrie() *Trie {
+	tr := New()
+	tr.Update([]byte("doe"), []byte("reindeer"))
+	tr.Update([]byte("dog"), []byte("puppy"))
+	tr.Update([]byte("dogglesworth"), []byte("cat"))
+	tr.Update([]byte("do"), []byte("verb"))
+	for i := 0; i < 50; i++ {
+		tr.Update([]byte(fmt.Sprintf("key%d", i)), bytes.Repeat([]byte{byte(i)}, 40))
+	}
+	return tr
+}
+
+func TestProveAndVerify(t *testing.T) {
+	tr := proofTestTrie()
+	root := tr.Hash()
+
+	keys := []string{"doe", "dog", "dogglesworth", "do", "key0", "key42"}
+	for _, key := range keys {
+		proof := tr.Prove([]byte(key))
+		if len(proof) == 0 {
+			t.Fatalf("expected non-empty proof for %q", key)
+		}
+
+		value, err := VerifyProof(root, []byte(key), proof)
+		if err != nil {
+			t.Fatalf("verification of %q failed: %v", key, err)
+		}
+		if !bytes.Equal(value, tr.Get([]byte(key))) {
+			t.Fatalf("expected %x for %q, got %x", tr.Get([]byte(key)), key, value)
+		}
+	}
+}
+
+func TestProveAbsence(t *testing.T) {
+	tr := proofTestTrie()
+	root := tr.Hash()
+
+	for _, key := range []string{"dogg", "cat", "key100", "d"} {
+		proof := tr.Prove([]byte(key))
+		value, err := VerifyProof(root, []byte(key), proof)
+		if err != nil {
+			t.Fatalf("absence proof of %q failed: %v", key, err)
+		}
+		if value != nil {
+			t.Fatalf("expected nil for absent key %q, got %x", key, value)
+		}
+	}
+}
+
+func TestVerifyProofRejectsTampering(t *testing.T) {
+	tr := proofTestTrie()
+	root := tr.Hash()
+	proof := tr.Prove([]byte("key7"))
+
+	// Dropping a node breaks the hash chain
+	if _, err := VerifyProof(root, []byte("key7"), proof[:len(proof)-1]); !errors.Is(err, ErrProofMissingNode) {
+		t.Fatalf("expected ErrProofMissingNode, got %v", err)
+	}
+
+	// Modifying the leaf changes its hash, so it no longer matches its parent
+	tampered := make([][]byte, len(proof))
+	copy(tampered, proof)
+	last := append([]byte{}, proof[len(proof)-1]...)
+	last[len(last)-1] ^= 0xff
+	tampered[len(tampered)-1] = last
+	if _, err := VerifyProof(root, []byte("key7"), tampered); err == nil {
+		t.Fatal("expected error for tampered proof")
+	}
+
+	// A proof against a different root must fail
+	other := New()
+	other.Update([]byte("key7"), []byte("other"))
+	if _, err := VerifyProof(other.Hash(), []byte("key7"), proof); err == nil {
+		t.Fatal("expected error for wrong root")
+	}
+}
+
+func TestSecureTrieProof(t *testing.T) {
+	tr := NewSecure()
+	tr.Update([]byte{0x01}, []byte("one"))
+	tr.Update([]byte{0x02}, []byte("two"))
+
+	value, err := VerifySecureProof(tr.Hash(), []byte{0x02}, tr.Prove([]byte{0x02}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(value) != "two" {
+		t.Fatalf("expected two, got %q", value)
+	}
+
+	value, err = VerifySecureProof(EmptyRoot, []byte{0x02}, nil)
+	if err != nil || value != nil {
+		t.Fatalf("expected empty result for empty root, got %x (err %v)", value, err)
+	}
+}
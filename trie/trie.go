@@ -0,0 +1,205 @@
+package trie
+
+import (
+	"github.com/daniellehrner/evmdbg/rlp"
+)
+
+// EmptyRoot is the root hash of an empty trie: keccak256(rlp(""))
+var EmptyRoot = [32]byte{
+	0x56, 0xe8, 0x1f, 0x17, 0x1b, 0xcc, 0x55, 0xa6, 0xff, 0x83, 0x45, 0xe6, 0x92, 0xc0, 0xf8, 0x6e,
+	0x5b, 0x48, 0xe0, 0x1b, 0x99, 0x6c, 0xad, 0xc0, 0x01, 0x62, 0x2f, 0xb5, 0xe3, 0x63, 0xb4, 0x21,
+}
+
+// Trie is an in-memory Merkle Patricia Trie
+type Trie struct {
+	root node
+}
+
+// New creates an empty trie
+func New() *Trie {
+	return &Trie{}
+}
+
+// Get returns the value stored under key, or nil if the key is not present
+func (t *Trie) Get(key []byte) []byte {
+	return get(t.root, keybytesToHex(key))
+}
+
+// Update stores value under key. An empty value deletes the key.
+func (t *Trie) Update(key, value []byte) {
+	if len(value) == 0 {
+		t.Delete(key)
+		return
+	}
+	t.root = insert(t.root, keybytesToHex(key), valueNode(value))
+}
+
+// Delete removes key from the trie
+func (t *Trie) Delete(key []byte) {
+	t.root = remove(t.root, keybytesToHex(key))
+}
+
+// Hash returns the root hash of the trie
+func (t *Trie) Hash() [32]byte {
+	var h [32]byte
+	if t.root == nil {
+		return EmptyRoot
+	}
+	if hn, ok := t.root.(hashNode); ok {
+		copy(h[:], hn)
+		return h
+	}
+	copy(h[:], keccak256(encodeNode(t.root)))
+	return h
+}
+
+func get(n node, key []byte) []byte {
+	switch n := n.(type) {
+	case valueNode:
+		if len(key) == 0 {
+			return n
+		}
+	case *shortNode:
+		if prefixLen(key, n.Key) == len(n.Key) {
+			return get(n.Val, key[len(n.Key):])
+		}
+	case *fullNode:
+		if len(key) > 0 {
+			return get(n.Children[key[0]], key[1:])
+		}
+	}
+	return nil
+}
+
+func insert(n node, key []byte, value node) node {
+	if len(key) == 0 {
+		return value
+	}
+
+	switch n := n.(type) {
+	case nil:
+		return &shortNode{Key: key, Val: value}
+	case *shortNode:
+		match := prefixLen(key, n.Key)
+
+		// The whole key of this node matches, continue in its child
+		if match == len(n.Key) {
+			return &shortNode{Key: n.Key, Val: insert(n.Val, key[match:], value)}
+		}
+
+		// Otherwise branch out at the first differing nibble
+		branch := &fullNode{}
+		branch.Children[n.Key[match]] = insert(nil, n.Key[match+1:], n.Val)
+		branch.Children[key[match]] = insert(nil, key[match+1:], value)
+		if match == 0 {
+			return branch
+		}
+		return &shortNode{Key: key[:match], Val: branch}
+	case *fullNode:
+		nn := *n
+		nn.Children[key[0]] = insert(n.Children[key[0]], key[1:], value)
+		return &nn
+	default:
+		panic("trie: cannot insert into unresolved node")
+	}
+}
+
+func remove(n node, key []byte) node {
+	switch n := n.(type) {
+	case nil:
+		return nil
+	case valueNode:
+		if len(key) == 0 {
+			return nil
+		}
+		return n
+	case *shortNode:
+		match := prefixLen(key, n.Key)
+		if match < len(n.Key) {
+			// Key is not in the trie
+			return n
+		}
+		if match == len(key) {
+			// Remove the whole leaf
+			return nil
+		}
+
+		child := remove(n.Val, key[len(n.Key):])
+		switch child := child.(type) {
+		case nil:
+			return nil
+		case *shortNode:
+			// Merge the remaining short child into this node
+			return &shortNode{Key: concat(n.Key, child.Key...), Val: child.Val}
+		default:
+			return &shortNode{Key: n.Key, Val: child}
+		}
+	case *fullNode:
+		nn := *n
+		nn.Children[key[0]] = remove(n.Children[key[0]], key[1:])
+
+		// A branch with a single remaining child collapses into a short node
+		pos := -1
+		for i, child := range nn.Children {
+			if child != nil {
+				if pos != -1 {
+					return &nn
+				}
+				pos = i
+			}
+		}
+		if pos == -1 {
+			return nil
+		}
+		if pos == terminator {
+			return &shortNode{Key: []byte{terminator}, Val: nn.Children[pos]}
+		}
+		if child, ok := nn.Children[pos].(*shortNode); ok {
+			return &shortNode{Key: concat([]byte{byte(pos)}, child.Key...), Val: child.Val}
+		}
+		return &shortNode{Key: []byte{byte(pos)}, Val: nn.Children[pos]}
+	default:
+		panic("trie: cannot delete from unresolved node")
+	}
+}
+
+// SecureTrie is a trie whose keys are hashed with keccak256 before insertion,
+// as used for the state and storage tries
+type SecureTrie struct {
+	trie Trie
+}
+
+// NewSecure creates an empty secure trie
+func NewSecure() *SecureTrie {
+	return &SecureTrie{}
+}
+
+// Get returns the value stored under keccak256(key)
+func (t *SecureTrie) Get(key []byte) []byte {
+	return t.trie.Get(keccak256(key))
+}
+
+// Update stores value under keccak256(key). An empty value deletes the key.
+func (t *SecureTrie) Update(key, value []byte) {
+	t.trie.Update(keccak256(key), value)
+}
+
+// Delete removes keccak256(key) from the trie
+func (t *SecureTrie) Delete(key []byte) {
+	t.trie.Delete(keccak256(key))
+}
+
+// Hash returns the root hash of the trie
+func (t *SecureTrie) Hash() [32]byte {
+	return t.trie.Hash()
+}
+
+// DeriveListRoot computes the root of a trie keyed by the RLP encoded index of each item,
+// as used for the transactions, receipts and withdrawals roots of a block
+func DeriveListRoot(items [][]byte) [32]byte {
+	t := New()
+	for i, item := range items {
+		t.Update(rlp.EncodeUint64(uint64(i)), item)
+	}
+	return t.Hash()
+}
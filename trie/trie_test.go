@@ -0,0 +1,112 @@
+package trie
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func mustHash(t *testing.T, s string) [32]byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 32 {
+		t.Fatalf("invalid hash %q", s)
+	}
+	var h [32]byte
+	copy(h[:], b)
+	return h
+}
+
+func TestEmptyTrie(t *testing.T) {
+	tr := New()
+	if tr.Hash() != EmptyRoot {
+		t.Fatalf("expected empty root, got %x", tr.Hash())
+	}
+	if got := tr.Get([]byte("missing")); got != nil {
+		t.Fatalf("expected nil, got %x", got)
+	}
+}
+
+func TestTrieRoot(t *testing.T) {
+	tr := New()
+	tr.Update([]byte("doe"), []byte("reindeer"))
+	tr.Update([]byte("dog"), []byte("puppy"))
+	tr.Update([]byte("dogglesworth"), []byte("cat"))
+
+	expected := mustHash(t, "8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3")
+	if tr.Hash() != expected {
+		t.Fatalf("expected root %x, got %x", expected, tr.Hash())
+	}
+
+	// "do" is a prefix of other keys and ends up as a branch value
+	tr.Update([]byte("do"), []byte("verb"))
+	tr.Update([]byte("horse"), []byte("stallion"))
+	expected = mustHash(t, "2ef9dafc0d185fe80914221474e19f7ece4640edc14fd0e446439bf2308dd481")
+	if tr.Hash() != expected {
+		t.Fatalf("expected root %x, got %x", expected, tr.Hash())
+	}
+
+	for key, value := range map[string]string{"do": "verb", "dog": "puppy", "doe": "reindeer", "horse": "stallion"} {
+		if got := tr.Get([]byte(key)); !bytes.Equal(got, []byte(value)) {
+			t.Fatalf("expected %q for %q, got %q", value, key, got)
+		}
+	}
+	if got := tr.Get([]byte("dogg")); got != nil {
+		t.Fatalf("expected nil for partial key, got %q", got)
+	}
+}
+
+func TestTrieDelete(t *testing.T) {
+	tr := New()
+	tr.Update([]byte("doe"), []byte("reindeer"))
+	tr.Update([]byte("dog"), []byte("puppy"))
+	tr.Update([]byte("dogglesworth"), []byte("cat"))
+	tr.Update([]byte("do"), []byte("verb"))
+	tr.Update([]byte("horse"), []byte("stallion"))
+
+	tr.Delete([]byte("dog"))
+	tr.Delete([]byte("doe"))
+
+	expected := mustHash(t, "e11a70ef807bcb3d2247d20f3b0179adc61a70566057305fa019dc4a6006d439")
+	if tr.Hash() != expected {
+		t.Fatalf("expected root %x, got %x", expected, tr.Hash())
+	}
+
+	// Deleting everything must return to the empty root
+	tr.Delete([]byte("do"))
+	tr.Update([]byte("horse"), nil)
+	tr.Delete([]byte("dogglesworth"))
+	tr.Delete([]byte("missing"))
+	if tr.Hash() != EmptyRoot {
+		t.Fatalf("expected empty root, got %x", tr.Hash())
+	}
+}
+
+func TestTrieInsertionOrderIndependence(t *testing.T) {
+	a := New()
+	b := New()
+	for i := 0; i < 100; i++ {
+		a.Update([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i)))
+		b.Update([]byte(fmt.Sprintf("key%d", 99-i)), []byte(fmt.Sprintf("value%d", 99-i)))
+	}
+	if a.Hash() != b.Hash() {
+		t.Fatalf("root depends on insertion order: %x != %x", a.Hash(), b.Hash())
+	}
+}
+
+func TestDeriveListRoot(t *testing.T) {
+	if DeriveListRoot(nil) != EmptyRoot {
+		t.Fatal("expected empty root for empty list")
+	}
+
+	var items [][]byte
+	for i := 0; i < 130; i++ {
+		items = append(items, []byte(fmt.Sprintf("item%d", i)))
+	}
+
+	expected := mustHash(t, "6ae7b854976beca8a74315c5657a0f45145619a9109835168ddfca21a4530828")
+	if got := DeriveListRoot(items); got != expected {
+		t.Fatalf("expected root %x, got %x", expected, got)
+	}
+}
@@ -0,0 +1,89 @@
+package vm
+
+import "github.com/holiman/uint256"
+
+// accessList tracks the addresses and storage slots accessed in the current transaction
+// (EIP-2929). Accesses to warm entries are cheaper once a gas schedule with access costs
+// is attached.
+type accessList struct {
+	addresses map[[20]byte]struct{}
+	slots     map[[20]byte]map[uint256.Int]struct{}
+	journal   []accessListEntry // the entries in the order they were added
+}
+
+// accessListEntry is an address, or a storage slot of it if slot is set, added to the
+// access list
+type accessListEntry struct {
+	addr [20]byte
+	slot *uint256.Int
+}
+
+func newAccessList() accessList {
+	return accessList{
+		addresses: make(map[[20]byte]struct{}),
+		slots:     make(map[[20]byte]map[uint256.Int]struct{}),
+	}
+}
+
+// WarmAddress adds addr to the access list and reports whether it was cold before
+func (vm *DebuggerVM) WarmAddress(addr [20]byte) bool {
+	if _, ok := vm.accessList.addresses[addr]; ok {
+		return false
+	}
+	vm.accessList.addresses[addr] = struct{}{}
+	vm.accessList.journal = append(vm.accessList.journal, accessListEntry{addr: addr})
+	return true
+}
+
+// IsAddressWarm reports whether addr has been accessed in the current transaction
+func (vm *DebuggerVM) IsAddressWarm(addr [20]byte) bool {
+	_, ok := vm.accessList.addresses[addr]
+	return ok
+}
+
+// WarmSlot adds a storage slot of addr to the access list and reports whether it was cold before
+func (vm *DebuggerVM) WarmSlot(addr [20]byte, slot *uint256.Int) bool {
+	slots := vm.accessList.slots[addr]
+	if slots == nil {
+		slots = make(map[uint256.Int]struct{})
+		vm.accessList.slots[addr] = slots
+	}
+	if _, ok := slots[*slot]; ok {
+		return false
+	}
+	slots[*slot] = struct{}{}
+	vm.accessList.journal = append(vm.accessList.journal, accessListEntry{addr: addr, slot: new(uint256.Int).Set(slot)})
+	return true
+}
+
+// IsSlotWarm reports whether a storage slot of addr has been accessed in the current transaction
+func (vm *DebuggerVM) IsSlotWarm(addr [20]byte, slot *uint256.Int) bool {
+	_, ok := vm.accessList.slots[addr][*slot]
+	return ok
+}
+
+// ClearAccessList empties the access list (for new transactions)
+func (vm *DebuggerVM) ClearAccessList() {
+	vm.accessList = newAccessList()
+}
+
+// AccessListSnapshot returns an identifier of the current access list to revert to with
+// RevertAccessList
+func (vm *DebuggerVM) AccessListSnapshot() int {
+	return len(vm.accessList.journal)
+}
+
+// RevertAccessList removes the entries added after the snapshot, as a failing call does
+// not keep the addresses and slots it accessed (EIP-2929)
+func (vm *DebuggerVM) RevertAccessList(id int) {
+	journal := vm.accessList.journal
+	for i := len(journal) - 1; i >= id; i-- {
+		entry := journal[i]
+		if entry.slot == nil {
+			delete(vm.accessList.addresses, entry.addr)
+		} else {
+			delete(vm.accessList.slots[entry.addr], *entry.slot)
+		}
+	}
+	vm.accessList.journal = journal[:id]
+}
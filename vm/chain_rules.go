@@ -0,0 +1,43 @@
+package vm
+
+// GasFunc returns the full cost of op, which is about to execute. It replaces both the
+// static cost from the GasSchedule and the built-in dynamic cost.
+type GasFunc func(v *DebuggerVM, op byte) (uint64, error)
+
+// ChainRules customizes the execution rules of a single DebuggerVM, e.g. for L2s and app
+// chains with their own instructions, precompiles and fee logic. All fields are optional.
+type ChainRules struct {
+	Name string
+
+	// Opcodes adds or replaces instructions, taking precedence over the HandlerGetter
+	Opcodes map[byte]Handler
+
+	// Precompiles adds or replaces precompiled contracts by address
+	Precompiles PrecompiledContracts
+
+	// Gas replaces the cost of instructions while gas is metered
+	Gas map[byte]GasFunc
+
+	// BeforeTransaction runs when a Session starts a transaction, before it is validated.
+	// State changes made here survive a failing transaction; an error fails it.
+	BeforeTransaction func(s *Session, tx *Transaction) error
+
+	// AfterTransaction runs when a Session finished a transaction, after the state changes
+	// of a failed transaction were rolled back. It may amend the result, e.g. to charge
+	// additional fees.
+	AfterTransaction func(s *Session, tx *Transaction, result *TransactionResult)
+}
+
+// handler returns the handler of op, preferring the chain rules over the instruction set
+// of the configured fork
+func (vm *DebuggerVM) handler(op byte) Handler {
+	if vm.ChainRules != nil {
+		if h, ok := vm.ChainRules.Opcodes[op]; ok {
+			return h
+		}
+	}
+	if vm.ChainConfig != nil && vm.InstructionSets != nil {
+		return vm.InstructionSets(vm.Fork())(op)
+	}
+	return vm.HandlerGetter(op)
+}
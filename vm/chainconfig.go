@@ -0,0 +1,244 @@
+package vm
+
+import (
+	"github.com/holiman/uint256"
+)
+
+// Fork identifies a protocol upgrade that changes EVM semantics
+type Fork int
+
+const (
+	Frontier Fork = iota
+	Homestead
+	TangerineWhistle // EIP-150
+	SpuriousDragon   // EIP-155, EIP-158
+	Byzantium
+	Constantinople
+	Petersburg
+	Istanbul
+	Berlin
+	London
+	Paris // The Merge
+	Shanghai
+	Cancun
+	Prague
+	Osaka
+
+	// NumForks is the number of known forks
+	NumForks = int(Osaka) + 1
+)
+
+// DefaultFork is the fork used when no chain configuration is set
+const DefaultFork = Prague
+
+var forkNames = [NumForks]string{
+	"Frontier", "Homestead", "TangerineWhistle", "SpuriousDragon", "Byzantium", "Constantinople",
+	"Petersburg", "Istanbul", "Berlin", "London", "Paris", "Shanghai", "Cancun", "Prague", "Osaka",
+}
+
+func (f Fork) String() string {
+	if f < 0 || int(f) >= NumForks {
+		return "Unknown"
+	}
+	return forkNames[f]
+}
+
+// ForkByName returns the fork with the given name, as returned by Fork.String
+func ForkByName(name string) (Fork, bool) {
+	for i, n := range forkNames {
+		if n == name {
+			return Fork(i), true
+		}
+	}
+	return 0, false
+}
+
+// ChainConfig holds the activation points of all forks. Forks up to Paris activate at a
+// block number, later forks at a block timestamp. A nil activation point means the fork
+// is not scheduled.
+type ChainConfig struct {
+	ChainID *uint256.Int
+
+	HomesteadBlock        *uint64
+	TangerineWhistleBlock *uint64
+	SpuriousDragonBlock   *uint64
+	ByzantiumBlock        *uint64
+	ConstantinopleBlock   *uint64
+	PetersburgBlock       *uint64
+	IstanbulBlock         *uint64
+	BerlinBlock           *uint64
+	LondonBlock           *uint64
+	ParisBlock            *uint64 // first proof-of-stake block
+
+	ShanghaiTime *uint64
+	CancunTime   *uint64
+	PragueTime   *uint64
+	OsakaTime    *uint64
+}
+
+func newUint64(v uint64) *uint64 {
+	return &v
+}
+
+// Chain configurations of public networks
+var (
+	MainnetChainConfig = &ChainConfig{
+		ChainID:               uint256.NewInt(1),
+		HomesteadBlock:        newUint64(1_150_000),
+		TangerineWhistleBlock: newUint64(2_463_000),
+		SpuriousDragonBlock:   newUint64(2_675_000),
+		ByzantiumBlock:        newUint64(4_370_000),
+		ConstantinopleBlock:   newUint64(7_280_000),
+		PetersburgBlock:       newUint64(7_280_000),
+		IstanbulBlock:         newUint64(9_069_000),
+		BerlinBlock:           newUint64(12_244_000),
+		LondonBlock:           newUint64(12_965_000),
+		ParisBlock:            newUint64(15_537_394),
+		ShanghaiTime:          newUint64(1_681_338_455),
+		CancunTime:            newUint64(1_710_338_135),
+		PragueTime:            newUint64(1_746_612_311),
+		OsakaTime:             newUint64(1_764_798_551),
+	}
+
+	SepoliaChainConfig = &ChainConfig{
+		ChainID:               uint256.NewInt(11_155_111),
+		HomesteadBlock:        newUint64(0),
+		TangerineWhistleBlock: newUint64(0),
+		SpuriousDragonBlock:   newUint64(0),
+		ByzantiumBlock:        newUint64(0),
+		ConstantinopleBlock:   newUint64(0),
+		PetersburgBlock:       newUint64(0),
+		IstanbulBlock:         newUint64(0),
+		BerlinBlock:           newUint64(0),
+		LondonBlock:           newUint64(0),
+		ParisBlock:            newUint64(1_450_409),
+		ShanghaiTime:          newUint64(1_677_557_088),
+		CancunTime:            newUint64(1_706_655_072),
+		PragueTime:            newUint64(1_741_159_776),
+		OsakaTime:             newUint64(1_760_427_360),
+	}
+
+	HoodiChainConfig = &ChainConfig{
+		ChainID:               uint256.NewInt(560_048),
+		HomesteadBlock:        newUint64(0),
+		TangerineWhistleBlock: newUint64(0),
+		SpuriousDragonBlock:   newUint64(0),
+		ByzantiumBlock:        newUint64(0),
+		ConstantinopleBlock:   newUint64(0),
+		PetersburgBlock:       newUint64(0),
+		IstanbulBlock:         newUint64(0),
+		BerlinBlock:           newUint64(0),
+		LondonBlock:           newUint64(0),
+		ParisBlock:            newUint64(0),
+		ShanghaiTime:          newUint64(0),
+		CancunTime:            newUint64(0),
+		PragueTime:            newUint64(1_742_999_832),
+		OsakaTime:             newUint64(1_761_677_592),
+	}
+)
+
+// ChainConfigByChainID returns the preset configuration of a public network
+func ChainConfigByChainID(chainID uint64) (*ChainConfig, bool) {
+	for _, c := range []*ChainConfig{MainnetChainConfig, SepoliaChainConfig, HoodiChainConfig} {
+		if c.ChainID.Uint64() == chainID {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// ChainConfigForFork returns a configuration with chain id 1 where all forks up to and
+// including fork are active from genesis
+func ChainConfigForFork(fork Fork) *ChainConfig {
+	c := &ChainConfig{ChainID: uint256.NewInt(1)}
+	for f := Homestead; f <= fork && int(f) < NumForks; f++ {
+		*c.activation(f) = newUint64(0)
+	}
+	return c
+}
+
+// activation returns the field holding the activation point of f, nil for Frontier
+func (c *ChainConfig) activation(f Fork) **uint64 {
+	switch f {
+	case Homestead:
+		return &c.HomesteadBlock
+	case TangerineWhistle:
+		return &c.TangerineWhistleBlock
+	case SpuriousDragon:
+		return &c.SpuriousDragonBlock
+	case Byzantium:
+		return &c.ByzantiumBlock
+	case Constantinople:
+		return &c.ConstantinopleBlock
+	case Petersburg:
+		return &c.PetersburgBlock
+	case Istanbul:
+		return &c.IstanbulBlock
+	case Berlin:
+		return &c.BerlinBlock
+	case London:
+		return &c.LondonBlock
+	case Paris:
+		return &c.ParisBlock
+	case Shanghai:
+		return &c.ShanghaiTime
+	case Cancun:
+		return &c.CancunTime
+	case Prague:
+		return &c.PragueTime
+	case Osaka:
+		return &c.OsakaTime
+	}
+	return nil
+}
+
+// IsActive reports whether fork is active in the block with the given number and timestamp
+func (c *ChainConfig) IsActive(fork Fork, number, time uint64) bool {
+	if fork == Frontier {
+		return true
+	}
+	field := c.activation(fork)
+	if field == nil || *field == nil {
+		return false
+	}
+	if fork >= Shanghai {
+		return time >= **field
+	}
+	return number >= **field
+}
+
+// ForkAt returns the fork config activates for block. Without a chain configuration
+// DefaultFork is assumed, without a block the genesis block.
+func ForkAt(config *ChainConfig, block *BlockContext) Fork {
+	if config == nil {
+		return DefaultFork
+	}
+	if block == nil {
+		return config.Fork(0, 0)
+	}
+	return config.Fork(block.Number, block.Timestamp)
+}
+
+// Fork returns the latest fork active in the block with the given number and timestamp
+func (c *ChainConfig) Fork(number, time uint64) Fork {
+	for f := Fork(NumForks - 1); f > Frontier; f-- {
+		if c.IsActive(f, number, time) {
+			return f
+		}
+	}
+	return Frontier
+}
+
+// Fork returns the fork active in the current block, see ForkAt
+func (vm *DebuggerVM) Fork() Fork {
+	var block *BlockContext
+	if vm.Context != nil {
+		block = vm.Context.Block
+	}
+	return ForkAt(vm.ChainConfig, block)
+}
+
+// IsFork reports whether fork is active in the current block
+func (vm *DebuggerVM) IsFork(fork Fork) bool {
+	return vm.Fork() >= fork
+}
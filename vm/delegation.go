@@ -0,0 +1,149 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/daniellehrner/evmdbg/crypto"
+	"github.com/daniellehrner/evmdbg/rlp"
+	"github.com/holiman/uint256"
+)
+
+// DelegationPrefix is the prefix of an EIP-7702 delegation designator: 0xef0100 || address
+var DelegationPrefix = []byte{0xef, 0x01, 0x00}
+
+// SetCodeTxMagic is prepended to the RLP encoded authorization tuple before hashing
+const SetCodeTxMagic = 0x05
+
+// Errors
+var (
+	ErrAuthChainID         = errors.New("authorization chain id mismatch")
+	ErrAuthNonceOverflow   = errors.New("authorization nonce overflow")
+	ErrAuthInvalidSig      = errors.New("authorization has invalid signature")
+	ErrAuthDestinationCode = errors.New("authority has non-delegation code")
+	ErrAuthNonceMismatch   = errors.New("authorization nonce mismatch")
+	ErrSetCodeTxCreate     = errors.New("set code transaction cannot create a contract")
+	ErrSetCodeTxNotActive  = errors.New("set code transactions are not enabled before Prague")
+)
+
+// ParseDelegation returns the delegate address if code is a delegation designator
+func ParseDelegation(code []byte) ([20]byte, bool) {
+	var addr [20]byte
+	if len(code) != len(DelegationPrefix)+20 || !bytes.HasPrefix(code, DelegationPrefix) {
+		return addr, false
+	}
+	copy(addr[:], code[len(DelegationPrefix):])
+	return addr, true
+}
+
+// AddressToDelegation returns the delegation designator pointing to addr
+func AddressToDelegation(addr [20]byte) []byte {
+	return append(append([]byte{}, DelegationPrefix...), addr[:]...)
+}
+
+// ResolveCode returns the code executed when addr is called. For accounts delegated via
+// EIP-7702 this is the code of the delegate; delegation chains are not followed.
+func (vm *DebuggerVM) ResolveCode(addr [20]byte) []byte {
+	if vm.StateProvider == nil {
+		return nil
+	}
+	code := vm.StateProvider.GetCode(addr)
+	if target, ok := ParseDelegation(code); ok {
+		return vm.StateProvider.GetCode(target)
+	}
+	return code
+}
+
+// SetCodeAuthorization is an entry of the authorization list of an EIP-7702 transaction
+type SetCodeAuthorization struct {
+	ChainID *uint256.Int
+	Address [20]byte
+	Nonce   uint64
+	V       uint8 // y parity
+	R       *uint256.Int
+	S       *uint256.Int
+}
+
+// SigHash returns the hash signed by the authority: keccak256(0x05 || rlp([chain_id, address, nonce]))
+func (a *SetCodeAuthorization) SigHash() [32]byte {
+	chainID := a.ChainID
+	if chainID == nil {
+		chainID = uint256.NewInt(0)
+	}
+	enc := rlp.EncodeList(
+		rlp.EncodeUint256(chainID),
+		rlp.EncodeBytes(a.Address[:]),
+		rlp.EncodeUint64(a.Nonce),
+	)
+	return crypto.Keccak256([]byte{SetCodeTxMagic}, enc)
+}
+
+// Authority recovers the address that signed the authorization
+func (a *SetCodeAuthorization) Authority() ([20]byte, error) {
+	if a.R == nil || a.S == nil || !crypto.ValidateSignatureValues(a.V, a.R, a.S, true) {
+		return [20]byte{}, ErrAuthInvalidSig
+	}
+	addr, err := crypto.RecoverAddress(a.SigHash(), a.V, a.R, a.S)
+	if err != nil {
+		return [20]byte{}, fmt.Errorf("%w: %v", ErrAuthInvalidSig, err)
+	}
+	return addr, nil
+}
+
+// ApplyAuthorization validates auth and, if valid, writes the delegation designator to the
+// authority's account and increments its nonce. A zero delegate address clears the
+// delegation. Invalid authorizations leave the state untouched.
+func ApplyAuthorization(sp StateProvider, chainID *uint256.Int, auth *SetCodeAuthorization) ([20]byte, error) {
+	authority, designator, err := validateAuthorization(sp, chainID, auth)
+	if err != nil {
+		return authority, err
+	}
+	SetAccountCode(sp, authority, designator)
+	sp.SetNonce(authority, auth.Nonce+1)
+	return authority, nil
+}
+
+// validateAuthorization checks auth against the state and returns its authority and the
+// code to store at the authority's account
+func validateAuthorization(sp StateProvider, chainID *uint256.Int, auth *SetCodeAuthorization) ([20]byte, []byte, error) {
+	if auth.ChainID != nil && !auth.ChainID.IsZero() && (chainID == nil || !auth.ChainID.Eq(chainID)) {
+		return [20]byte{}, nil, ErrAuthChainID
+	}
+	if auth.Nonce == ^uint64(0) {
+		return [20]byte{}, nil, ErrAuthNonceOverflow
+	}
+
+	authority, err := auth.Authority()
+	if err != nil {
+		return [20]byte{}, nil, err
+	}
+
+	code := sp.GetCode(authority)
+	if _, delegated := ParseDelegation(code); len(code) != 0 && !delegated {
+		return authority, nil, ErrAuthDestinationCode
+	}
+	if nonce := sp.GetNonce(authority); nonce != auth.Nonce {
+		return authority, nil, fmt.Errorf("%w: have %d, want %d", ErrAuthNonceMismatch, nonce, auth.Nonce)
+	}
+
+	var designator []byte
+	if auth.Address != ([20]byte{}) {
+		designator = AddressToDelegation(auth.Address)
+	}
+	return authority, designator, nil
+}
+
+// SetAccountCode stores code at addr, keeping the rest of the account intact where the
+// state provider allows it
+func SetAccountCode(sp StateProvider, addr [20]byte, code []byte) {
+	if cw, ok := sp.(CodeWriter); ok {
+		cw.SetCode(addr, code)
+		return
+	}
+
+	// Without a CodeWriter the account has to be recreated, which drops its storage
+	nonce := sp.GetNonce(addr)
+	_ = sp.CreateAccount(addr, code, sp.GetBalance(addr))
+	sp.SetNonce(addr, nonce)
+}
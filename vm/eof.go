@@ -0,0 +1,322 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// EOF v1 container layout (EIP-3540, EIP-7620)
+const (
+	eofVersion1 = 0x01
+
+	eofKindTypes     = 0x01
+	eofKindCode      = 0x02
+	eofKindContainer = 0x03
+	eofKindData      = 0xff
+
+	EOFNonReturning = 0x80 // outputs value of a code section that never returns
+
+	maxEOFCodeSections      = 1024
+	maxEOFContainerSections = 256
+	maxEOFInputs            = 0x7f
+	maxEOFOutputs           = 0x7f
+	maxEOFStackIncrease     = 0x3ff
+	maxEOFContainerSize     = 2 * 24576 // EIP-3860 initcode size limit
+)
+
+// EOFMagic is the prefix of every EOF container
+var EOFMagic = []byte{0xef, 0x00}
+
+// Errors
+var (
+	ErrInvalidEOF          = errors.New("invalid EOF container")
+	ErrEOFTruncatedData    = errors.New("EOF container data section is truncated")
+	ErrEOFReturnStackLimit = errors.New("EOF return stack limit reached")
+)
+
+// EOFFunctionType is the type section entry of a code section
+type EOFFunctionType struct {
+	Inputs           uint8
+	Outputs          uint8 // EOFNonReturning if the section never returns
+	MaxStackIncrease uint16
+}
+
+// EOFContainer is a parsed EOF v1 container
+type EOFContainer struct {
+	Types         []EOFFunctionType
+	CodeSections  [][]byte
+	SubContainers []*EOFContainer
+	Data          []byte
+	DataSize      int // declared size, larger than len(Data) for truncated data sections
+
+	raw []byte // encoding of the container, including a truncated data section
+}
+
+// HasEOFMagic reports whether code starts with the EOF magic
+func HasEOFMagic(code []byte) bool {
+	return bytes.HasPrefix(code, EOFMagic)
+}
+
+// ParseEOF decodes and validates a top-level EOF container. Initcode containers may only
+// end in RETURNCONTRACT, runtime containers only in STOP or RETURN.
+func ParseEOF(b []byte, initcode bool) (*EOFContainer, error) {
+	c, err := decodeEOF(b, true)
+	if err != nil {
+		return nil, err
+	}
+	kind := eofRuntime
+	if initcode {
+		kind = eofInitcode
+	}
+	if err := c.validate(kind); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SplitEOFInitcode splits creation transaction data into the initcode container and the
+// calldata appended to it
+func SplitEOFInitcode(data []byte) (*EOFContainer, []byte, error) {
+	c, err := decodeEOF(data, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(c.Data) != c.DataSize {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidEOF, ErrEOFTruncatedData)
+	}
+	if err := c.validate(eofInitcode); err != nil {
+		return nil, nil, err
+	}
+	return c, data[len(c.raw):], nil
+}
+
+// Bytes returns the encoding of the container
+func (c *EOFContainer) Bytes() []byte {
+	return c.raw
+}
+
+// eofError wraps a validation failure in ErrInvalidEOF
+func eofError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidEOF, fmt.Sprintf(format, args...))
+}
+
+// eofReader reads the big-endian header fields of a container
+type eofReader struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func (r *eofReader) uint(size int) int {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos+size > len(r.b) {
+		r.err = eofError("header truncated at offset %d", r.pos)
+		return 0
+	}
+	var v int
+	for _, x := range r.b[r.pos : r.pos+size] {
+		v = v<<8 | int(x)
+	}
+	r.pos += size
+	return v
+}
+
+func (r *eofReader) expect(kind int, what string) {
+	if k := r.uint(1); r.err == nil && k != kind {
+		r.err = eofError("expected %s section kind 0x%02x at offset %d, found 0x%02x", what, kind, r.pos-1, k)
+	}
+}
+
+// decodeEOF parses the container structure. Top-level containers must have a complete
+// data section and nothing after it. Otherwise the container may be followed by
+// trailing bytes, and its data section may be truncated at the end of b.
+func decodeEOF(b []byte, topLevel bool) (*EOFContainer, error) {
+	if !HasEOFMagic(b) {
+		return nil, eofError("missing magic")
+	}
+	if len(b) > maxEOFContainerSize {
+		return nil, eofError("container size %d exceeds %d", len(b), maxEOFContainerSize)
+	}
+	if len(b) < 3 || b[2] != eofVersion1 {
+		return nil, eofError("unsupported version")
+	}
+
+	r := &eofReader{b: b, pos: 3}
+
+	r.expect(eofKindTypes, "type")
+	typesSize := r.uint(2)
+
+	r.expect(eofKindCode, "code")
+	numCode := r.uint(2)
+	if r.err == nil && (numCode == 0 || numCode > maxEOFCodeSections) {
+		return nil, eofError("invalid number of code sections %d", numCode)
+	}
+	codeSizes := make([]int, 0, numCode)
+	for i := 0; i < numCode && r.err == nil; i++ {
+		size := r.uint(2)
+		if r.err == nil && size == 0 {
+			return nil, eofError("code section %d is empty", i)
+		}
+		codeSizes = append(codeSizes, size)
+	}
+
+	var containerSizes []int
+	if r.err == nil && r.pos < len(b) && b[r.pos] == eofKindContainer {
+		r.pos++
+		numContainers := r.uint(2)
+		if r.err == nil && (numContainers == 0 || numContainers > maxEOFContainerSections) {
+			return nil, eofError("invalid number of container sections %d", numContainers)
+		}
+		for i := 0; i < numContainers && r.err == nil; i++ {
+			size := r.uint(4)
+			if r.err == nil && size == 0 {
+				return nil, eofError("container section %d is empty", i)
+			}
+			containerSizes = append(containerSizes, size)
+		}
+	}
+
+	r.expect(eofKindData, "data")
+	dataSize := r.uint(2)
+	if term := r.uint(1); r.err == nil && term != 0 {
+		return nil, eofError("missing header terminator")
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if typesSize != 4*numCode {
+		return nil, eofError("type section size %d does not match %d code sections", typesSize, numCode)
+	}
+
+	bodySize := typesSize + sum(codeSizes) + sum(containerSizes)
+	if r.pos+bodySize > len(b) {
+		return nil, eofError("container truncated: body needs %d bytes, have %d", bodySize, len(b)-r.pos)
+	}
+
+	c := &EOFContainer{DataSize: dataSize}
+	pos := r.pos
+	for i := 0; i < numCode; i++ {
+		t := EOFFunctionType{
+			Inputs:           b[pos],
+			Outputs:          b[pos+1],
+			MaxStackIncrease: binary.BigEndian.Uint16(b[pos+2:]),
+		}
+		if t.Inputs > maxEOFInputs {
+			return nil, eofError("code section %d has too many inputs (%d)", i, t.Inputs)
+		}
+		if t.Outputs > maxEOFOutputs && t.Outputs != EOFNonReturning {
+			return nil, eofError("code section %d has too many outputs (%d)", i, t.Outputs)
+		}
+		if t.MaxStackIncrease > maxEOFStackIncrease {
+			return nil, eofError("code section %d max stack increase %d exceeds limit", i, t.MaxStackIncrease)
+		}
+		c.Types = append(c.Types, t)
+		pos += 4
+	}
+	if c.Types[0].Inputs != 0 || c.Types[0].Outputs != EOFNonReturning {
+		return nil, eofError("first code section must have 0 inputs and be non-returning")
+	}
+
+	for _, size := range codeSizes {
+		c.CodeSections = append(c.CodeSections, b[pos:pos+size])
+		pos += size
+	}
+
+	for i, size := range containerSizes {
+		sub, err := decodeEOF(b[pos:pos+size], false)
+		if err != nil {
+			return nil, fmt.Errorf("container section %d: %w", i, err)
+		}
+		if len(sub.raw) != size {
+			return nil, eofError("container section %d has %d trailing bytes", i, size-len(sub.raw))
+		}
+		c.SubContainers = append(c.SubContainers, sub)
+		pos += size
+	}
+
+	end := min(pos+dataSize, len(b))
+	if topLevel && end-pos != dataSize {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, ErrEOFTruncatedData)
+	}
+	if topLevel && len(b) > end {
+		return nil, eofError("%d bytes after the data section", len(b)-end)
+	}
+	c.Data = b[pos:end]
+	c.raw = b[:end]
+	return c, nil
+}
+
+// WithAuxData returns the encoding of the container with aux appended to its data section
+// and the declared data size updated accordingly, as done by RETURNCONTRACT
+func (c *EOFContainer) WithAuxData(aux []byte) ([]byte, error) {
+	newSize := len(c.Data) + len(aux)
+	if newSize < c.DataSize {
+		return nil, fmt.Errorf("%w: data section has %d bytes, %d declared", ErrEOFTruncatedData, newSize, c.DataSize)
+	}
+	if newSize > 0xffff {
+		return nil, fmt.Errorf("data section size %d exceeds the limit", newSize)
+	}
+
+	out := append(append([]byte{}, c.raw...), aux...)
+	binary.BigEndian.PutUint16(out[c.dataSizeOffset():], uint16(newSize))
+	return out, nil
+}
+
+// dataSizeOffset returns the offset of the data size field in the header
+func (c *EOFContainer) dataSizeOffset() int {
+	// magic, version, types header, code header with sizes, data kind
+	offset := 3 + 3 + 3 + 2*len(c.CodeSections) + 1
+	if len(c.SubContainers) > 0 {
+		offset += 3 + 4*len(c.SubContainers)
+	}
+	return offset
+}
+
+// EncodeEOF builds the encoding of a container from its sections. It does not validate the result.
+func EncodeEOF(types []EOFFunctionType, code [][]byte, containers [][]byte, data []byte) []byte {
+	b := append([]byte{}, EOFMagic...)
+	b = append(b, eofVersion1)
+
+	b = append(b, eofKindTypes)
+	b = binary.BigEndian.AppendUint16(b, uint16(4*len(types)))
+	b = append(b, eofKindCode)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(code)))
+	for _, c := range code {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(c)))
+	}
+	if len(containers) > 0 {
+		b = append(b, eofKindContainer)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(containers)))
+		for _, c := range containers {
+			b = binary.BigEndian.AppendUint32(b, uint32(len(c)))
+		}
+	}
+	b = append(b, eofKindData)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	b = append(b, 0)
+
+	for _, t := range types {
+		b = append(b, t.Inputs, t.Outputs)
+		b = binary.BigEndian.AppendUint16(b, t.MaxStackIncrease)
+	}
+	for _, c := range code {
+		b = append(b, c...)
+	}
+	for _, c := range containers {
+		b = append(b, c...)
+	}
+	return append(b, data...)
+}
+
+func sum(list []int) int {
+	total := 0
+	for _, n := range list {
+		total += n
+	}
+	return total
+}
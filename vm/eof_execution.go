@@ -0,0 +1,136 @@
+package vm
+
+import "fmt"
+
+// EOFReturn is an entry of the EOF return stack pushed by CALLF
+type EOFReturn struct {
+	Section int
+	PC      uint64
+}
+
+const maxEOFReturnStack = 1024
+
+// EnableEOF makes the debugger execute code starting with the EOF magic as EOF v1
+// containers. If the root frame already holds such code it is validated and converted.
+func (vm *DebuggerVM) EnableEOF() error {
+	vm.eofEnabled = true
+	if frame := vm.currentFrame(); frame != nil && len(vm.frames) == 1 {
+		return vm.loadEOFFrame(frame)
+	}
+	return nil
+}
+
+// EOFEnabled reports whether EOF execution is enabled
+func (vm *DebuggerVM) EOFEnabled() bool {
+	return vm.eofEnabled
+}
+
+// ResetEOFExecution is ResetExecution for a container that has already been validated
+func (vm *DebuggerVM) ResetEOFExecution(c *EOFContainer) {
+	vm.ResetExecution(nil)
+	vm.frames[0].SetEOF(c)
+}
+
+// loadEOFFrame validates EOF runtime code of a new frame and switches it to section 0
+func (vm *DebuggerVM) loadEOFFrame(frame *MessageFrame) error {
+	if !vm.eofEnabled || frame.EOF != nil || !HasEOFMagic(frame.Code) {
+		return nil
+	}
+	c, err := ParseEOF(frame.Code, false)
+	if err != nil {
+		return err
+	}
+	frame.SetEOF(c)
+	return nil
+}
+
+// SetEOF makes the frame execute the validated container c from the start of section 0
+func (f *MessageFrame) SetEOF(c *EOFContainer) {
+	f.EOF = c
+	f.ReturnStack = nil
+	f.jumpToSection(0)
+}
+
+// IsEOF reports whether the frame executes EOF code
+func (f *MessageFrame) IsEOF() bool {
+	return f.EOF != nil
+}
+
+func (f *MessageFrame) jumpToSection(section int) {
+	f.Section = section
+	f.Code = f.EOF.CodeSections[section]
+	f.PC = 0
+	f.CodeMetadata = scanEOFCodeMetadata(f.Code)
+}
+
+// RequireEOF returns the error of an undefined opcode if the current frame is not EOF code
+func (vm *DebuggerVM) RequireEOF(op byte) error {
+	if frame := vm.currentFrame(); frame == nil || frame.EOF == nil {
+		return fmt.Errorf("unsupported opcode: 0x%x", op)
+	}
+	return nil
+}
+
+// CallSection implements CALLF: it pushes the return position and continues in section
+func (vm *DebuggerVM) CallSection(section int) error {
+	frame := vm.currentFrame()
+	if len(frame.ReturnStack) >= maxEOFReturnStack {
+		return ErrEOFReturnStackLimit
+	}
+	t := frame.EOF.Types[section]
+	if frame.Stack.Len()+int(t.MaxStackIncrease) > eofStackLimit {
+		return fmt.Errorf("stack overflow")
+	}
+
+	frame.ReturnStack = append(frame.ReturnStack, EOFReturn{Section: frame.Section, PC: frame.PC})
+	frame.jumpToSection(section)
+	return nil
+}
+
+// JumpToSection implements JUMPF: it continues in section without returning
+func (vm *DebuggerVM) JumpToSection(section int) error {
+	frame := vm.currentFrame()
+	t := frame.EOF.Types[section]
+	if frame.Stack.Len()+int(t.MaxStackIncrease) > eofStackLimit {
+		return fmt.Errorf("stack overflow")
+	}
+	frame.jumpToSection(section)
+	return nil
+}
+
+// ReturnFromSection implements RETF
+func (vm *DebuggerVM) ReturnFromSection() error {
+	frame := vm.currentFrame()
+	n := len(frame.ReturnStack)
+	if n == 0 {
+		return fmt.Errorf("RETF with empty return stack")
+	}
+	ret := frame.ReturnStack[n-1]
+	frame.ReturnStack = frame.ReturnStack[:n-1]
+	frame.jumpToSection(ret.Section)
+	frame.PC = ret.PC
+	return nil
+}
+
+// scanEOFCodeMetadata marks the instruction boundaries of an EOF code section. EOF code
+// has no JUMPDEST analysis.
+func scanEOFCodeMetadata(code []byte) *CodeMetadata {
+	validPC := make(map[uint64]struct{})
+	for pc := 0; pc < len(code); pc += EOFInstructionSize(code, pc) {
+		validPC[uint64(pc)] = struct{}{}
+	}
+	return &CodeMetadata{
+		ValidPC:   validPC,
+		JumpDests: make(map[uint64]struct{}),
+	}
+}
+
+// ExternalCode returns the code of addr as seen by EXTCODESIZE, EXTCODECOPY and
+// EXTCODEHASH. With EOF enabled, EOF contracts only expose their magic.
+func (vm *DebuggerVM) ExternalCode(addr [20]byte) []byte {
+	code := vm.StateProvider.GetCode(addr)
+	if vm.eofEnabled && HasEOFMagic(code) {
+		return EOFMagic
+	}
+	return code
+}
@@ -0,0 +1,366 @@
+package vm
+
+import (
+	"encoding/binary"
+)
+
+// eofKind is the role a container is validated for
+type eofKind int
+
+const (
+	eofRuntime eofKind = iota + 1
+	eofInitcode
+)
+
+const eofStackLimit = 1024
+
+// eofInstruction describes an instruction that is valid in EOF code
+type eofInstruction struct {
+	defined   bool
+	pops      int
+	pushes    int
+	immediate int // size of the immediate, RJUMPV only reports its count byte
+	terminal  bool
+}
+
+var eofInstructions [256]eofInstruction
+
+func init() {
+	def := func(ops []byte, pops, pushes int) {
+		for _, op := range ops {
+			eofInstructions[op] = eofInstruction{defined: true, pops: pops, pushes: pushes}
+		}
+	}
+
+	def([]byte{STOP, INVALID}, 0, 0)
+	def([]byte{ADD, MUL, SUB, DIV, SDIV, MOD, SMOD, EXP, SIGNEXTEND, LT, GT, SLT, SGT, EQ,
+		AND, OR, XOR, BYTE, SHL, SHR, SAR, SHA3}, 2, 1)
+	def([]byte{ADDMOD, MULMOD}, 3, 1)
+	def([]byte{ISZERO, NOT, CLZ, BALANCE, CALLDATALOAD, BLOCKHASH, BLOBHASH, MLOAD, SLOAD, TLOAD,
+		DATALOAD, RETURNDATALOAD}, 1, 1)
+	def([]byte{ADDRESS, ORIGIN, CALLER, CALLVALUE, CALLDATASIZE, GASPRICE, RETURNDATASIZE,
+		COINBASE, TIMESTAMP, NUMBER, PREVRANDAO, GASLIMIT, CHAINID, SELFBALANCE, BASEFEE,
+		BLOBBASEFEE, MSIZE, PUSH0, DATASIZE}, 0, 1)
+	def([]byte{CALLDATACOPY, RETURNDATACOPY, MCOPY, DATACOPY}, 3, 0)
+	def([]byte{POP}, 1, 0)
+	def([]byte{MSTORE, MSTORE8, SSTORE, TSTORE, RETURN, REVERT, RETURNCONTRACT}, 2, 0)
+	def([]byte{JUMPDEST}, 0, 0) // NOP in EOF
+	def([]byte{DATALOADN}, 0, 1)
+	def([]byte{RJUMP}, 0, 0)
+	def([]byte{RJUMPI, RJUMPV}, 1, 0)
+	def([]byte{EOFCREATE, EXTCALL}, 4, 1)
+	def([]byte{EXTDELEGATECALL, EXTSTATICCALL}, 3, 1)
+	// The stack effects of these depend on their immediates
+	def([]byte{CALLF, RETF, JUMPF, DUPN, SWAPN, EXCHANGE}, 0, 0)
+
+	for i := 0; i < 32; i++ {
+		eofInstructions[PUSH1+i] = eofInstruction{defined: true, pushes: 1, immediate: i + 1}
+	}
+	for i := 0; i < 16; i++ {
+		eofInstructions[DUP1+i] = eofInstruction{defined: true, pops: i + 1, pushes: i + 2}
+		eofInstructions[SWAP1+i] = eofInstruction{defined: true, pops: i + 2, pushes: i + 2}
+	}
+	for i := 0; i <= 4; i++ {
+		eofInstructions[LOG0+i] = eofInstruction{defined: true, pops: i + 2}
+	}
+
+	for _, op := range []byte{DATALOADN, RJUMP, RJUMPI, CALLF, JUMPF} {
+		eofInstructions[op].immediate = 2
+	}
+	for _, op := range []byte{RJUMPV, DUPN, SWAPN, EXCHANGE, EOFCREATE, RETURNCONTRACT} {
+		eofInstructions[op].immediate = 1
+	}
+	for _, op := range []byte{STOP, RETURN, REVERT, INVALID, RETF, JUMPF, RETURNCONTRACT} {
+		eofInstructions[op].terminal = true
+	}
+}
+
+// EOFInstructionSize returns the size of the EOF instruction at pos including its immediates
+func EOFInstructionSize(code []byte, pos int) int {
+	op := code[pos]
+	if op == RJUMPV && pos+1 < len(code) {
+		return 2 + 2*(int(code[pos+1])+1)
+	}
+	return 1 + eofInstructions[op].immediate
+}
+
+func readInt16(b []byte) int {
+	return int(int16(binary.BigEndian.Uint16(b)))
+}
+
+func readUint16(b []byte) int {
+	return int(binary.BigEndian.Uint16(b))
+}
+
+// sectionReferences collects what a code section refers to during validation
+type sectionReferences struct {
+	sections       []int
+	subContainers  map[int]eofKind
+	returnContract bool
+	stopOrReturn   bool
+}
+
+// validate checks all code sections reachable from section 0 and the subcontainers they reference
+func (c *EOFContainer) validate(kind eofKind) error {
+	visited := make([]bool, len(c.CodeSections))
+	visited[0] = true
+	queue := []int{0}
+	subKinds := make(map[int]eofKind)
+
+	for len(queue) > 0 {
+		idx := queue[0]
+		queue = queue[1:]
+
+		refs, err := c.validateSection(idx)
+		if err != nil {
+			return err
+		}
+		if kind == eofInitcode && refs.stopOrReturn {
+			return eofError("initcode container contains STOP or RETURN in code section %d", idx)
+		}
+		if kind == eofRuntime && refs.returnContract {
+			return eofError("runtime container contains RETURNCONTRACT in code section %d", idx)
+		}
+
+		for _, s := range refs.sections {
+			if !visited[s] {
+				visited[s] = true
+				queue = append(queue, s)
+			}
+		}
+		for sub, k := range refs.subContainers {
+			if prev, ok := subKinds[sub]; ok && prev != k {
+				return eofError("container section %d referenced by both EOFCREATE and RETURNCONTRACT", sub)
+			}
+			subKinds[sub] = k
+		}
+	}
+
+	for i, v := range visited {
+		if !v {
+			return eofError("code section %d is unreachable", i)
+		}
+	}
+	for i, sub := range c.SubContainers {
+		k, ok := subKinds[i]
+		if !ok {
+			return eofError("container section %d is never referenced", i)
+		}
+		if k == eofInitcode && len(sub.Data) != sub.DataSize {
+			return eofError("container section %d is created by EOFCREATE but its data section is truncated", i)
+		}
+		if err := sub.validate(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSection checks the instructions, jumps and stack heights of a code section
+func (c *EOFContainer) validateSection(idx int) (*sectionReferences, error) {
+	code := c.CodeSections[idx]
+	refs := &sectionReferences{subContainers: make(map[int]eofKind)}
+
+	// Pass 1: instructions and immediates
+	starts := make([]bool, len(code))
+	var jumps [][2]int // position of the jump, relative destination
+	var last byte
+	for pos := 0; pos < len(code); {
+		op := code[pos]
+		info := eofInstructions[op]
+		if !info.defined {
+			return nil, eofError("undefined instruction 0x%02x at position %d of code section %d", op, pos, idx)
+		}
+		size := EOFInstructionSize(code, pos)
+		if pos+size > len(code) {
+			return nil, eofError("truncated immediate at position %d of code section %d", pos, idx)
+		}
+		starts[pos] = true
+		imm := code[pos+1 : pos+size]
+
+		switch op {
+		case RJUMP, RJUMPI:
+			jumps = append(jumps, [2]int{pos, pos + size + readInt16(imm)})
+		case RJUMPV:
+			for i := 1; i < len(imm); i += 2 {
+				jumps = append(jumps, [2]int{pos, pos + size + readInt16(imm[i:])})
+			}
+		case CALLF, JUMPF:
+			target := readUint16(imm)
+			if target >= len(c.CodeSections) {
+				return nil, eofError("%s to missing code section %d at position %d", eofOpName(op), target, pos)
+			}
+			t, cur := c.Types[target], c.Types[idx]
+			if op == CALLF && t.Outputs == EOFNonReturning {
+				return nil, eofError("CALLF to non-returning code section %d at position %d", target, pos)
+			}
+			if op == JUMPF && t.Outputs != EOFNonReturning && (cur.Outputs == EOFNonReturning || t.Outputs > cur.Outputs) {
+				return nil, eofError("JUMPF to code section %d with incompatible outputs at position %d", target, pos)
+			}
+			refs.sections = append(refs.sections, target)
+		case DATALOADN:
+			if offset := readUint16(imm); offset+32 > c.DataSize {
+				return nil, eofError("DATALOADN offset %d out of data section bounds at position %d", offset, pos)
+			}
+		case EOFCREATE, RETURNCONTRACT:
+			sub := int(imm[0])
+			if sub >= len(c.SubContainers) {
+				return nil, eofError("%s of missing container section %d at position %d", eofOpName(op), sub, pos)
+			}
+			k := eofInitcode
+			if op == RETURNCONTRACT {
+				k = eofRuntime
+				refs.returnContract = true
+			}
+			if prev, ok := refs.subContainers[sub]; ok && prev != k {
+				return nil, eofError("container section %d referenced by both EOFCREATE and RETURNCONTRACT", sub)
+			}
+			refs.subContainers[sub] = k
+		case STOP, RETURN:
+			refs.stopOrReturn = true
+		}
+
+		last = op
+		pos += size
+	}
+
+	if !eofInstructions[last].terminal && last != RJUMP {
+		return nil, eofError("code section %d does not end with a terminating instruction", idx)
+	}
+	for _, j := range jumps {
+		if j[1] < 0 || j[1] >= len(code) || !starts[j[1]] {
+			return nil, eofError("invalid relative jump destination %d at position %d of code section %d", j[1], j[0], idx)
+		}
+	}
+
+	// Pass 2: stack heights (EIP-5450)
+	if err := c.validateStack(idx); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// validateStack computes the operand stack height range at every instruction in a single
+// forward pass and checks it against the type section
+func (c *EOFContainer) validateStack(idx int) error {
+	code := c.CodeSections[idx]
+	typ := c.Types[idx]
+
+	minH := make([]int, len(code))
+	maxH := make([]int, len(code))
+	for i := range minH {
+		minH[i] = -1
+	}
+	minH[0], maxH[0] = int(typ.Inputs), int(typ.Inputs)
+	maxHeight := int(typ.Inputs)
+	returning := false
+
+	for pos := 0; pos < len(code); {
+		op := code[pos]
+		size := EOFInstructionSize(code, pos)
+		if minH[pos] < 0 {
+			return eofError("unreachable instruction at position %d of code section %d", pos, idx)
+		}
+		curMin, curMax := minH[pos], maxH[pos]
+
+		info := eofInstructions[op]
+		pops, pushes := info.pops, info.pushes
+		switch op {
+		case CALLF, JUMPF:
+			t := c.Types[readUint16(code[pos+1:])]
+			if curMax+int(t.MaxStackIncrease) > eofStackLimit {
+				return eofError("%s at position %d of code section %d may overflow the stack", eofOpName(op), pos, idx)
+			}
+			pops = int(t.Inputs)
+			if op == CALLF {
+				pushes = int(t.Outputs)
+			} else if t.Outputs != EOFNonReturning {
+				want := int(typ.Outputs) + int(t.Inputs) - int(t.Outputs)
+				if curMin != want || curMax != want {
+					return eofError("JUMPF at position %d of code section %d requires stack height %d", pos, idx, want)
+				}
+				returning = true
+			}
+		case RETF:
+			if typ.Outputs == EOFNonReturning {
+				return eofError("RETF in non-returning code section %d", idx)
+			}
+			if curMin != curMax || curMin != int(typ.Outputs) {
+				return eofError("RETF at position %d of code section %d requires stack height %d", pos, idx, typ.Outputs)
+			}
+			returning = true
+		case DUPN:
+			n := int(code[pos+1]) + 1
+			pops, pushes = n, n+1
+		case SWAPN:
+			n := int(code[pos+1]) + 2
+			pops, pushes = n, n
+		case EXCHANGE:
+			n := int(code[pos+1]>>4) + int(code[pos+1]&0x0f) + 3
+			pops, pushes = n, n
+		}
+		if curMin < pops {
+			return eofError("stack underflow at position %d of code section %d", pos, idx)
+		}
+
+		nextMin, nextMax := curMin-pops+pushes, curMax-pops+pushes
+		maxHeight = max(maxHeight, nextMax)
+
+		var successors []int
+		switch {
+		case op == RJUMP:
+			successors = []int{pos + size + readInt16(code[pos+1:])}
+		case op == RJUMPI:
+			successors = []int{pos + size, pos + size + readInt16(code[pos+1:])}
+		case op == RJUMPV:
+			successors = []int{pos + size}
+			for i := pos + 2; i < pos+size; i += 2 {
+				successors = append(successors, pos+size+readInt16(code[i:]))
+			}
+		case !info.terminal:
+			successors = []int{pos + size}
+		}
+
+		for _, s := range successors {
+			if s >= len(code) {
+				return eofError("code section %d falls off the end after position %d", idx, pos)
+			}
+			switch {
+			case s <= pos:
+				if minH[s] != nextMin || maxH[s] != nextMax {
+					return eofError("backward jump at position %d of code section %d changes the stack height", pos, idx)
+				}
+			case minH[s] < 0:
+				minH[s], maxH[s] = nextMin, nextMax
+			default:
+				minH[s], maxH[s] = min(minH[s], nextMin), max(maxH[s], nextMax)
+			}
+		}
+		pos += size
+	}
+
+	if maxHeight > eofStackLimit {
+		return eofError("code section %d exceeds the stack limit", idx)
+	}
+	if maxHeight-int(typ.Inputs) != int(typ.MaxStackIncrease) {
+		return eofError("code section %d declares max stack increase %d, computed %d", idx, typ.MaxStackIncrease, maxHeight-int(typ.Inputs))
+	}
+	if returning != (typ.Outputs != EOFNonReturning) {
+		return eofError("code section %d returning flag does not match its instructions", idx)
+	}
+	return nil
+}
+
+func eofOpName(op byte) string {
+	switch op {
+	case CALLF:
+		return "CALLF"
+	case JUMPF:
+		return "JUMPF"
+	case EOFCREATE:
+		return "EOFCREATE"
+	case RETURNCONTRACT:
+		return "RETURNCONTRACT"
+	}
+	return "instruction"
+}
@@ -0,0 +1,435 @@
+package vm
+
+import (
+	"errors"
+	"math"
+
+	"github.com/holiman/uint256"
+)
+
+// Errors
+var (
+	ErrIntrinsicGas = errors.New("intrinsic gas exceeds the gas limit")
+)
+
+// GasUsed returns the gas charged in the current transaction. Gas forwarded to a callee is
+// charged with the call instruction and its unused part is returned to the caller.
+func (vm *DebuggerVM) GasUsed() uint64 {
+	return vm.gasUsed
+}
+
+// ClearGasUsed resets the gas counter, the access list and the original storage values
+// (for new transactions)
+func (vm *DebuggerVM) ClearGasUsed() {
+	vm.gasUsed = 0
+	vm.ClearAccessList()
+	vm.originalStorage = make(map[[20]byte]map[uint256.Int]*uint256.Int)
+}
+
+// IntrinsicGas returns the gas charged for tx before any code runs
+func (s *GasSchedule) IntrinsicGas(tx *Transaction) uint64 {
+	gas := s.Tx
+	if tx.To == nil {
+		gas = s.TxCreate + toWords(uint64(len(tx.Data)))*s.InitCodeWord
+	}
+	for _, b := range tx.Data {
+		if b == 0 {
+			gas += s.TxDataZero
+		} else {
+			gas += s.TxDataNonZero
+		}
+	}
+	for _, tuple := range tx.AccessList {
+		gas += s.TxAccessListAddress + uint64(len(tuple.StorageKeys))*s.TxAccessListStorageKey
+	}
+	return gas + uint64(len(tx.AuthorizationList))*s.TxAuthorization
+}
+
+// MemoryCost returns the total cost of a memory of the given number of words
+func (s *GasSchedule) MemoryCost(words uint64) uint64 {
+	return s.Memory*words + words*words/s.QuadCoeffDiv
+}
+
+// chargeGas charges the static and dynamic cost of op, which is about to execute, and
+// returns the cost. If there is not enough gas, the cost is returned with ErrOutOfGas.
+func (vm *DebuggerVM) chargeGas(op byte) (uint64, error) {
+	if vm.Context == nil {
+		return 0, errors.New("gas metering requires the execution context to be set")
+	}
+	vm.forwardedGas = 0
+	if vm.ChainRules != nil {
+		if gasFunc, ok := vm.ChainRules.Gas[op]; ok {
+			cost, err := gasFunc(vm, op)
+			if err != nil {
+				return 0, err
+			}
+			return cost, vm.UseGas(cost)
+		}
+	}
+
+	cost, err := vm.dynamicGas(op)
+	if err != nil {
+		return 0, err
+	}
+	if cost > math.MaxUint64-vm.GasSchedule.StaticCost(op) {
+		return 0, ErrOutOfGas
+	}
+	cost += vm.GasSchedule.StaticCost(op)
+	return cost, vm.UseGas(cost)
+}
+
+// dynamicGas returns the cost of op on top of its static cost. Missing stack items are
+// priced as zero, the instruction fails with a stack underflow afterwards.
+func (vm *DebuggerVM) dynamicGas(op byte) (uint64, error) {
+	s := vm.GasSchedule
+	frame := vm.currentFrame()
+
+	arg := func(n int) *uint256.Int {
+		if v, err := frame.Stack.Peek(n); err == nil {
+			return v
+		}
+		return new(uint256.Int)
+	}
+	address := func(n int) [20]byte {
+		return arg(n).Bytes20()
+	}
+
+	// Memory expansion
+	end, ok := memoryEnd(op, arg)
+	if !ok {
+		return 0, ErrOutOfGas
+	}
+	var cost uint64
+	if current := uint64(frame.Memory.Size()); end > current {
+		cost = s.MemoryCost(toWords(end)) - s.MemoryCost(toWords(current))
+	}
+
+	words := func(size *uint256.Int, perWord uint64) (uint64, bool) {
+		if !size.IsUint64() {
+			return 0, false
+		}
+		return toWords(size.Uint64()) * perWord, true
+	}
+
+	var extra uint64
+	switch op {
+	case EXP:
+		extra = uint64((arg(1).BitLen()+7)/8) * s.ExpByte
+	case SHA3:
+		extra, ok = words(arg(1), s.Keccak256Word)
+	case CALLDATACOPY, CODECOPY, RETURNDATACOPY, MCOPY, DATACOPY:
+		extra, ok = words(arg(2), s.CopyWord)
+	case EXTCODECOPY:
+		extra, ok = words(arg(3), s.CopyWord)
+		extra += vm.accountAccessGas(address(0))
+	case CREATE:
+		extra, ok = words(arg(2), s.InitCodeWord)
+	case CREATE2:
+		extra, ok = words(arg(2), s.Keccak256Word+s.InitCodeWord)
+	case LOG0, LOG1, LOG2, LOG3, LOG4:
+		if !arg(1).IsUint64() {
+			return 0, ErrOutOfGas
+		}
+		extra = arg(1).Uint64() * s.LogByte
+	case BALANCE, EXTCODESIZE, EXTCODEHASH:
+		extra = vm.accountAccessGas(address(0))
+	case SLOAD:
+		extra = vm.slotAccessGas(arg(0))
+	case SSTORE:
+		return vm.sstoreGas(cost, arg(0), arg(1))
+	case CALL, CALLCODE:
+		extra = vm.accountAccessGas(address(1)) + vm.callValueGas(op, address(1), arg(2))
+	case DELEGATECALL, STATICCALL:
+		extra = vm.accountAccessGas(address(1))
+	case SELFDESTRUCT:
+		extra = vm.selfDestructGas(address(0))
+	}
+	if !ok || extra > math.MaxUint64-cost {
+		return 0, ErrOutOfGas
+	}
+	cost += extra
+
+	switch op {
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		return vm.forwardGas(op, cost, arg(0))
+	case EXTCALL, EXTDELEGATECALL, EXTSTATICCALL, EOFCREATE:
+		return vm.forwardGas(op, cost, nil)
+	}
+	return cost, nil
+}
+
+// forwardGas returns cost plus the gas op forwards to its callee and records the latter
+// for the handler. requested is the gas operand of the CALL family, the EOF instructions
+// pass nil and forward all they can. The gas left after the cost of op can be forwarded,
+// since EIP-150 all but one 64th of it.
+func (vm *DebuggerVM) forwardGas(op byte, cost uint64, requested *uint256.Int) (uint64, error) {
+	s := vm.GasSchedule
+	static := s.StaticCost(op)
+	if cost > math.MaxUint64-static || vm.Context.Gas < cost+static {
+		// Not enough gas for op itself
+		return cost, nil
+	}
+	available := vm.Context.Gas - cost - static
+	if s.Call.AllButOne64th {
+		available -= available / 64
+	}
+
+	gas := available
+	if requested != nil {
+		switch {
+		case requested.IsUint64() && requested.Uint64() <= available:
+			gas = requested.Uint64()
+		case !s.Call.AllButOne64th:
+			// Before EIP-150 the requested gas is charged in full
+			return 0, ErrOutOfGas
+		}
+	}
+	vm.forwardedGas = gas
+	return cost + gas, nil
+}
+
+// ForwardedGas returns the gas of the callee of the executing call instruction: the gas
+// forwarded when the instruction was charged plus the stipend if value is not zero.
+// Without a GasSchedule it is the requested gas, or all but one 64th of the gas left if
+// requested is nil.
+func (vm *DebuggerVM) ForwardedGas(requested, value *uint256.Int) uint64 {
+	if vm.GasSchedule == nil {
+		switch {
+		case requested == nil:
+			return vm.Context.Gas - vm.Context.Gas/64
+		case !requested.IsUint64():
+			return math.MaxUint64
+		}
+		return requested.Uint64()
+	}
+	gas := vm.forwardedGas
+	if value != nil && !value.IsZero() {
+		gas += vm.GasSchedule.Call.Stipend
+	}
+	return gas
+}
+
+// SetForwardedGas sets the gas forwarded by the call instruction about to execute. A
+// GasFunc pricing a call instruction includes the forwarded gas in the cost and sets it.
+func (vm *DebuggerVM) SetForwardedGas(gas uint64) {
+	vm.forwardedGas = gas
+}
+
+// ReturnGas settles the gas of a callee that was given gas and returned with remaining
+// left and the error err. Its gas was charged to the caller with the call instruction, so
+// its consumption is not counted again. The remaining gas is credited back to the caller,
+// unless the callee halted exceptionally, which consumes all of its gas.
+func (vm *DebuggerVM) ReturnGas(gas, remaining uint64, err error) {
+	if vm.GasSchedule == nil {
+		return
+	}
+	remaining = min(remaining, gas)
+	vm.gasUsed -= min(gas-remaining, vm.gasUsed)
+	if err == nil || errors.Is(err, ErrExecutionReverted) {
+		vm.Context.Gas += remaining
+		vm.gasUsed -= min(remaining, vm.gasUsed)
+	}
+}
+
+// accountAccessGas returns the cold access surcharge for addr and warms it up
+func (vm *DebuggerVM) accountAccessGas(addr [20]byte) uint64 {
+	s := vm.GasSchedule
+	if vm.WarmAddress(addr) && s.ColdAccountAccess > s.WarmStorageRead {
+		return s.ColdAccountAccess - s.WarmStorageRead
+	}
+	return 0
+}
+
+// slotAccessGas returns the cold access surcharge for a slot of the executing account and
+// warms it up
+func (vm *DebuggerVM) slotAccessGas(slot *uint256.Int) uint64 {
+	s := vm.GasSchedule
+	if vm.WarmSlot(vm.Context.Address, slot) && s.ColdSload > s.WarmStorageRead {
+		return s.ColdSload - s.WarmStorageRead
+	}
+	return 0
+}
+
+// callValueGas returns the cost of transferring value with CALL or CALLCODE, and of the
+// account a CALL creates
+func (vm *DebuggerVM) callValueGas(op byte, to [20]byte, value *uint256.Int) uint64 {
+	var gas uint64
+	if !value.IsZero() {
+		gas = vm.GasSchedule.Call.Value
+	}
+	if op != CALL || vm.StateProvider == nil {
+		return gas
+	}
+	// Since EIP-161 only a transfer of value to an empty account creates one, before any
+	// call to an account that does not exist
+	if vm.IsFork(SpuriousDragon) {
+		if !value.IsZero() && vm.isEmpty(to) {
+			gas += vm.GasSchedule.Call.NewAccount
+		}
+	} else if !vm.StateProvider.AccountExists(to) {
+		gas += vm.GasSchedule.Call.NewAccount
+	}
+	return gas
+}
+
+// selfDestructGas returns the dynamic cost of SELFDESTRUCT to beneficiary
+func (vm *DebuggerVM) selfDestructGas(beneficiary [20]byte) uint64 {
+	var gas uint64
+	if vm.WarmAddress(beneficiary) {
+		// SELFDESTRUCT has no warm base cost, a cold beneficiary costs the full cold access
+		gas = vm.GasSchedule.ColdAccountAccess
+	}
+	sp := vm.StateProvider
+	if sp == nil {
+		return gas
+	}
+	// EIP-150 charges for a beneficiary that does not exist, EIP-161 only for sending a
+	// balance to an empty one
+	switch {
+	case vm.IsFork(SpuriousDragon):
+		if vm.isEmpty(beneficiary) && !sp.GetBalance(vm.Context.Address).IsZero() {
+			gas += vm.GasSchedule.Call.NewAccount
+		}
+	case vm.IsFork(TangerineWhistle):
+		if !sp.AccountExists(beneficiary) {
+			gas += vm.GasSchedule.Call.NewAccount
+		}
+	}
+	return gas
+}
+
+// isEmpty reports whether addr has no nonce, balance or code (EIP-161)
+func (vm *DebuggerVM) isEmpty(addr [20]byte) bool {
+	sp := vm.StateProvider
+	return sp.GetNonce(addr) == 0 && sp.GetBalance(addr).IsZero() && len(sp.GetCode(addr)) == 0
+}
+
+// originalValue returns the value of a slot of the executing account at the start of the
+// transaction. It must be called before the first write to the slot.
+func (vm *DebuggerVM) originalValue(slot *uint256.Int) *uint256.Int {
+	addr := vm.Context.Address
+	slots := vm.originalStorage[addr]
+	if slots == nil {
+		slots = make(map[uint256.Int]*uint256.Int)
+		vm.originalStorage[addr] = slots
+	}
+	if v, ok := slots[*slot]; ok {
+		return v
+	}
+	v := vm.ReadStorage(slot)
+	slots[*slot] = v
+	return v
+}
+
+// sstoreGas returns memoryCost plus the cost of writing value to slot, and updates the
+// refund counter
+func (vm *DebuggerVM) sstoreGas(memoryCost uint64, slot, value *uint256.Int) (uint64, error) {
+	s := vm.GasSchedule.Sstore
+	if s.NetMetering && vm.Context.Gas <= s.Sentry {
+		return 0, ErrOutOfGas
+	}
+
+	original := vm.originalValue(slot)
+	current := vm.ReadStorage(slot)
+	cost := memoryCost
+	if vm.WarmSlot(vm.Context.Address, slot) {
+		// SSTORE has no warm base cost, a cold slot costs the full cold access (EIP-2929)
+		cost += vm.GasSchedule.ColdSload
+	}
+
+	if !s.NetMetering {
+		switch {
+		case current.IsZero() && !value.IsZero():
+			cost += s.Set
+		case !current.IsZero() && value.IsZero():
+			vm.AddRefund(s.ClearRefund)
+			cost += s.Reset
+		default:
+			cost += s.Reset
+		}
+		return cost, nil
+	}
+
+	// EIP-2200 net gas metering
+	if current.Eq(value) {
+		return cost + s.Noop, nil
+	}
+	if original.Eq(current) {
+		if original.IsZero() {
+			return cost + s.Set, nil
+		}
+		if value.IsZero() {
+			vm.AddRefund(s.ClearRefund)
+		}
+		return cost + s.Reset, nil
+	}
+	if !original.IsZero() {
+		if current.IsZero() {
+			vm.SubRefund(s.ClearRefund)
+		} else if value.IsZero() {
+			vm.AddRefund(s.ClearRefund)
+		}
+	}
+	if original.Eq(value) {
+		if original.IsZero() {
+			vm.AddRefund(s.Set - s.Noop)
+		} else {
+			vm.AddRefund(s.Reset - s.Noop)
+		}
+	}
+	return cost + s.Noop, nil
+}
+
+// memoryEnd returns the memory size op needs, reading its operands with arg. It reports
+// false if the size does not fit into 64 bits.
+func memoryEnd(op byte, arg func(int) *uint256.Int) (uint64, bool) {
+	rangeEnd := func(offset, size *uint256.Int) (uint64, bool) {
+		if size.IsZero() {
+			return 0, true
+		}
+		if !offset.IsUint64() || !size.IsUint64() {
+			return 0, false
+		}
+		end := offset.Uint64() + size.Uint64()
+		return end, end >= offset.Uint64() && end <= math.MaxUint32
+	}
+	fixed := func(offset *uint256.Int, size uint64) (uint64, bool) {
+		return rangeEnd(offset, uint256.NewInt(size))
+	}
+	two := func(a, b, c, d int) (uint64, bool) {
+		end1, ok1 := rangeEnd(arg(a), arg(b))
+		end2, ok2 := rangeEnd(arg(c), arg(d))
+		return max(end1, end2), ok1 && ok2
+	}
+
+	switch op {
+	case MLOAD, MSTORE:
+		return fixed(arg(0), 32)
+	case MSTORE8:
+		return fixed(arg(0), 1)
+	case SHA3, RETURN, REVERT, LOG0, LOG1, LOG2, LOG3, LOG4, RETURNCONTRACT:
+		return rangeEnd(arg(0), arg(1))
+	case CALLDATACOPY, CODECOPY, RETURNDATACOPY, DATACOPY:
+		return rangeEnd(arg(0), arg(2))
+	case EXTCODECOPY:
+		return rangeEnd(arg(1), arg(3))
+	case MCOPY:
+		return two(0, 2, 1, 2)
+	case CREATE, CREATE2:
+		return rangeEnd(arg(1), arg(2))
+	case CALL, CALLCODE:
+		return two(3, 4, 5, 6)
+	case DELEGATECALL, STATICCALL:
+		return two(2, 3, 4, 5)
+	case EXTCALL, EXTDELEGATECALL, EXTSTATICCALL:
+		return rangeEnd(arg(1), arg(2))
+	case EOFCREATE:
+		return rangeEnd(arg(2), arg(3))
+	}
+	return 0, true
+}
+
+// toWords returns the number of 32-byte words needed for size bytes
+func toWords(size uint64) uint64 {
+	return (size + 31) / 32
+}
@@ -0,0 +1,372 @@
+package vm
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+)
+
+// GasSchedule holds the gas costs charged while a schedule is attached to a DebuggerVM.
+// Schedules are plain data: they can be loaded from JSON and modified to model repricing
+// proposals.
+type GasSchedule struct {
+	Name string `json:"name"`
+
+	// Static costs by opcode mnemonic, charged before the instruction executes.
+	// Opcodes that are not listed cost nothing.
+	Static map[string]uint64 `json:"static"`
+
+	// Memory expansion costs memory*words + words²/quadCoeffDiv
+	Memory       uint64 `json:"memory"`
+	QuadCoeffDiv uint64 `json:"quadCoeffDiv"`
+
+	// Dynamic costs per 32-byte word or byte of the operands
+	CopyWord      uint64 `json:"copyWord"`      // *COPY instructions
+	Keccak256Word uint64 `json:"keccak256Word"` // KECCAK256 and CREATE2
+	ExpByte       uint64 `json:"expByte"`       // bytes of the EXP exponent
+	LogByte       uint64 `json:"logByte"`       // LOG data
+	CodeDeposit   uint64 `json:"codeDeposit"`   // bytes of the code deployed by a creation
+
+	// Access costs (EIP-2929). Accessing a cold account or slot costs the difference
+	// between the cold cost and warmStorageRead on top of the static cost, SSTORE and
+	// SELFDESTRUCT pay the full cold cost. Zero before Berlin.
+	ColdAccountAccess uint64 `json:"coldAccountAccess"`
+	ColdSload         uint64 `json:"coldSload"`
+	WarmStorageRead   uint64 `json:"warmStorageRead"`
+
+	Sstore SstoreGas `json:"sstore"`
+	Call   CallGas   `json:"call"`
+
+	// Intrinsic costs of a transaction
+	Tx                     uint64 `json:"tx"`
+	TxCreate               uint64 `json:"txCreate"`
+	TxDataZero             uint64 `json:"txDataZero"`
+	TxDataNonZero          uint64 `json:"txDataNonZero"`
+	TxAccessListAddress    uint64 `json:"txAccessListAddress"`    // EIP-2930
+	TxAccessListStorageKey uint64 `json:"txAccessListStorageKey"` // EIP-2930
+	TxAuthorization        uint64 `json:"txAuthorization"`        // EIP-7702
+	InitCodeWord           uint64 `json:"initCodeWord"`           // EIP-3860, also charged by CREATE and CREATE2
+
+	// static is Static indexed by opcode
+	static [256]uint64
+}
+
+// SstoreGas holds the parameters of SSTORE pricing
+type SstoreGas struct {
+	// NetMetering selects EIP-2200 net gas metering, otherwise the Frontier rules apply
+	NetMetering bool   `json:"netMetering"`
+	Set         uint64 `json:"set"`         // zero to non-zero
+	Reset       uint64 `json:"reset"`       // any other change
+	ClearRefund uint64 `json:"clearRefund"` // refund for clearing a slot
+	Noop        uint64 `json:"noop"`        // no-op and dirty writes under net metering
+	Sentry      uint64 `json:"sentry"`      // SSTORE fails if no more gas than this is left
+}
+
+// CallGas holds the dynamic costs of the CALL family and SELFDESTRUCT and the gas passed
+// on to callees
+type CallGas struct {
+	Value      uint64 `json:"value"`      // transferring a non-zero value
+	NewAccount uint64 `json:"newAccount"` // sending value to or creating an account that does not exist
+	Stipend    uint64 `json:"stipend"`    // added to the gas of a callee receiving value
+
+	// AllButOne64th caps the gas forwarded to callees at all but one 64th of the gas left
+	// (EIP-150), otherwise the requested gas is forwarded
+	AllButOne64th bool `json:"allButOne64th"`
+}
+
+// Built-in gas schedules
+var (
+	FrontierGasSchedule         = newGasSchedule("frontier", nil, frontierGas)
+	TangerineWhistleGasSchedule = newGasSchedule("tangerineWhistle", FrontierGasSchedule, tangerineWhistleGas)
+	ByzantiumGasSchedule        = newGasSchedule("byzantium", TangerineWhistleGasSchedule, byzantiumGas)
+	ConstantinopleGasSchedule   = newGasSchedule("constantinople", ByzantiumGasSchedule, constantinopleGas)
+	IstanbulGasSchedule         = newGasSchedule("istanbul", ConstantinopleGasSchedule, istanbulGas)
+	BerlinGasSchedule           = newGasSchedule("berlin", IstanbulGasSchedule, berlinGas)
+	LondonGasSchedule           = newGasSchedule("london", BerlinGasSchedule, londonGas)
+	ShanghaiGasSchedule         = newGasSchedule("shanghai", LondonGasSchedule, shanghaiGas)
+)
+
+var builtinGasSchedules = []*GasSchedule{
+	FrontierGasSchedule,
+	TangerineWhistleGasSchedule,
+	ByzantiumGasSchedule,
+	ConstantinopleGasSchedule,
+	IstanbulGasSchedule,
+	BerlinGasSchedule,
+	LondonGasSchedule,
+	ShanghaiGasSchedule,
+}
+
+// newGasSchedule returns a copy of parent modified by apply
+func newGasSchedule(name string, parent *GasSchedule, apply func(*GasSchedule)) *GasSchedule {
+	s := &GasSchedule{}
+	if parent != nil {
+		s = parent.Copy()
+	}
+	s.Name = name
+	apply(s)
+	if err := s.compile(); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func frontierGas(s *GasSchedule) {
+	s.Static = make(map[string]uint64)
+	set := func(cost uint64, ops ...OpCode) {
+		for _, op := range ops {
+			s.Static[op.String()] = cost
+		}
+	}
+
+	set(2, ADDRESS, ORIGIN, CALLER, CALLVALUE, CALLDATASIZE, CODESIZE, GASPRICE, COINBASE,
+		TIMESTAMP, NUMBER, DIFFICULTY, GASLIMIT, POP, PC, MSIZE, GAS)
+	set(3, ADD, SUB, NOT, LT, GT, SLT, SGT, EQ, ISZERO, AND, OR, XOR, BYTE, CALLDATALOAD,
+		MLOAD, MSTORE, MSTORE8, CALLDATACOPY, CODECOPY)
+	set(5, MUL, DIV, SDIV, MOD, SMOD, SIGNEXTEND)
+	set(8, ADDMOD, MULMOD, JUMP)
+	set(10, JUMPI, EXP)
+	set(20, BALANCE, EXTCODESIZE, EXTCODECOPY, BLOCKHASH)
+	set(30, SHA3)
+	set(40, CALL, CALLCODE, DELEGATECALL)
+	set(50, SLOAD)
+	set(1, JUMPDEST)
+	set(32000, CREATE)
+	for i := 0; i < 32; i++ {
+		set(3, OpCode(PUSH1+i))
+	}
+	for i := 0; i < 16; i++ {
+		set(3, OpCode(DUP1+i), OpCode(SWAP1+i))
+	}
+	for i := 0; i <= 4; i++ {
+		set(375*uint64(i+1), OpCode(LOG0+i))
+	}
+
+	s.Memory = 3
+	s.QuadCoeffDiv = 512
+	s.CopyWord = 3
+	s.Keccak256Word = 6
+	s.ExpByte = 10
+	s.LogByte = 8
+	s.CodeDeposit = 200
+
+	s.Sstore = SstoreGas{Set: 20000, Reset: 5000, ClearRefund: 15000}
+	s.Call = CallGas{Value: 9000, NewAccount: 25000, Stipend: 2300}
+
+	s.Tx = 21000
+	s.TxCreate = 21000
+	s.TxDataZero = 4
+	s.TxDataNonZero = 68
+}
+
+// tangerineWhistleGas applies EIP-150 and the Homestead contract creation cost. The EIP-160
+// EXP repricing of Spurious Dragon is included.
+func tangerineWhistleGas(s *GasSchedule) {
+	for op, cost := range map[OpCode]uint64{
+		BALANCE:      400,
+		EXTCODESIZE:  700,
+		EXTCODECOPY:  700,
+		SLOAD:        200,
+		CALL:         700,
+		CALLCODE:     700,
+		DELEGATECALL: 700,
+		SELFDESTRUCT: 5000,
+	} {
+		s.Static[op.String()] = cost
+	}
+	s.ExpByte = 50
+	s.Call.AllButOne64th = true
+	s.TxCreate = 53000 // Homestead
+}
+
+// byzantiumGas adds the Byzantium opcodes
+func byzantiumGas(s *GasSchedule) {
+	for op, cost := range map[OpCode]uint64{
+		RETURNDATASIZE: 2,
+		RETURNDATACOPY: 3,
+		STATICCALL:     700,
+	} {
+		s.Static[op.String()] = cost
+	}
+}
+
+// constantinopleGas adds the Constantinople opcodes. The EIP-1283 SSTORE metering was
+// removed again by Petersburg and is not applied, so the schedule serves both forks.
+func constantinopleGas(s *GasSchedule) {
+	for op, cost := range map[OpCode]uint64{
+		SHL:         3,
+		SHR:         3,
+		SAR:         3,
+		CREATE2:     32000,
+		EXTCODEHASH: 400,
+	} {
+		s.Static[op.String()] = cost
+	}
+}
+
+// istanbulGas applies EIP-1884, EIP-2028 and EIP-2200 and adds CHAINID and SELFBALANCE
+func istanbulGas(s *GasSchedule) {
+	for op, cost := range map[OpCode]uint64{
+		CHAINID:     2,
+		SELFBALANCE: 5,
+		BALANCE:     700,
+		EXTCODEHASH: 700,
+		SLOAD:       800,
+	} {
+		s.Static[op.String()] = cost
+	}
+	s.Sstore = SstoreGas{NetMetering: true, Set: 20000, Reset: 5000, ClearRefund: 15000, Noop: 800, Sentry: 2300}
+	s.TxDataNonZero = 16
+}
+
+// berlinGas applies EIP-2929: accesses are priced by the access list, and EIP-2930
+func berlinGas(s *GasSchedule) {
+	for _, op := range []OpCode{SLOAD, BALANCE, EXTCODESIZE, EXTCODECOPY, EXTCODEHASH,
+		CALL, CALLCODE, DELEGATECALL, STATICCALL} {
+		s.Static[op.String()] = 100
+	}
+	s.ColdAccountAccess = 2600
+	s.ColdSload = 2100
+	s.WarmStorageRead = 100
+	s.Sstore.Reset = 5000 - 2100
+	s.Sstore.Noop = 100
+	s.TxAccessListAddress = 2400
+	s.TxAccessListStorageKey = 1900
+}
+
+// londonGas applies EIP-3529 and adds BASEFEE. Later forks only add opcodes and
+// transaction types without repricing existing ones, except for EIP-3860 in Shanghai, so
+// they are priced here as well.
+func londonGas(s *GasSchedule) {
+	for op, cost := range map[OpCode]uint64{
+		BASEFEE:     2,
+		PUSH0:       2,
+		TLOAD:       100,
+		TSTORE:      100,
+		MCOPY:       3,
+		BLOBHASH:    3,
+		BLOBBASEFEE: 2,
+		CLZ:         5,
+	} {
+		s.Static[op.String()] = cost
+	}
+	s.Sstore.ClearRefund = 4800
+	s.TxAuthorization = 25000
+}
+
+// shanghaiGas applies EIP-3860: initcode is charged per word
+func shanghaiGas(s *GasSchedule) {
+	s.InitCodeWord = 2
+}
+
+// GasScheduleForFork returns the latest built-in schedule that does not postdate fork
+func GasScheduleForFork(fork Fork) *GasSchedule {
+	switch {
+	case fork >= Shanghai:
+		return ShanghaiGasSchedule
+	case fork >= London:
+		return LondonGasSchedule
+	case fork >= Berlin:
+		return BerlinGasSchedule
+	case fork >= Istanbul:
+		return IstanbulGasSchedule
+	case fork >= Constantinople:
+		return ConstantinopleGasSchedule
+	case fork >= Byzantium:
+		return ByzantiumGasSchedule
+	case fork >= TangerineWhistle:
+		return TangerineWhistleGasSchedule
+	}
+	return FrontierGasSchedule
+}
+
+// GasScheduleByName returns the built-in schedule with the given name, ignoring case
+func GasScheduleByName(name string) (*GasSchedule, bool) {
+	for _, s := range builtinGasSchedules {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Copy returns a deep copy of the schedule that can be modified independently
+func (s *GasSchedule) Copy() *GasSchedule {
+	c := *s
+	c.Static = maps.Clone(s.Static)
+	return &c
+}
+
+// StaticCost returns the static cost of op
+func (s *GasSchedule) StaticCost(op byte) uint64 {
+	return s.static[op]
+}
+
+// SetStaticCost changes the static cost of op
+func (s *GasSchedule) SetStaticCost(op OpCode, cost uint64) {
+	if s.Static == nil {
+		s.Static = make(map[string]uint64)
+	}
+	s.Static[op.String()] = cost
+	s.static[op] = cost
+}
+
+// compile indexes the static costs by opcode
+func (s *GasSchedule) compile() error {
+	s.static = [256]uint64{}
+	for name, cost := range s.Static {
+		op, ok := OpCodeByName(name)
+		if !ok {
+			return fmt.Errorf("gas schedule %s: unknown opcode %q", s.Name, name)
+		}
+		s.static[op] = cost
+	}
+	return nil
+}
+
+// gasScheduleJSON is the JSON form of a schedule, which may extend a built-in schedule
+type gasScheduleJSON struct {
+	Extends string `json:"extends"`
+}
+
+// ParseGasSchedule decodes a schedule from JSON. If the "extends" field names a built-in
+// schedule, the JSON only needs to hold the values that differ from it.
+func ParseGasSchedule(data []byte) (*GasSchedule, error) {
+	var header gasScheduleJSON
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	s := &GasSchedule{}
+	if header.Extends != "" {
+		parent, ok := GasScheduleByName(header.Extends)
+		if !ok {
+			return nil, fmt.Errorf("unknown gas schedule %q", header.Extends)
+		}
+		s = parent.Copy()
+		s.Name = ""
+	}
+
+	// Unmarshalling into the copy merges the static costs and keeps unset fields
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.QuadCoeffDiv == 0 {
+		return nil, fmt.Errorf("gas schedule %s: quadCoeffDiv must not be zero", s.Name)
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadGasSchedule reads a schedule from a JSON file, see ParseGasSchedule
+func LoadGasSchedule(path string) (*GasSchedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGasSchedule(data)
+}
@@ -3,6 +3,7 @@ package opcode_handlers
 import (
 	"testing"
 
+	"github.com/daniellehrner/evmdbg/state"
 	"github.com/daniellehrner/evmdbg/vm"
 	"github.com/holiman/uint256"
 )
@@ -122,7 +123,7 @@ func TestCallWithoutStateProvider(t *testing.T) {
 		vm.PUSH1, 0x00, // argsOffset
 		vm.PUSH1, 0x00, // value
 		vm.PUSH1, 0x01, // address (0x01)
-		vm.PUSH1, 0x64, // gas (100)
+		vm.PUSH2, 0x0b, 0xb8, // gas (3000, the cost of ECRECOVER at 0x01)
 		vm.CALL, // CALL
 	}
 
@@ -174,7 +175,7 @@ func TestCallWithStateProvider(t *testing.T) {
 		vm.PUSH1, 0x00, // argsOffset
 		vm.PUSH1, 0x00, // value
 		vm.PUSH1, 0x01, // address (0x01)
-		vm.PUSH1, 0x64, // gas (100)
+		vm.PUSH2, 0x0b, 0xb8, // gas (3000, the cost of ECRECOVER at 0x01)
 		vm.CALL, // CALL
 	}
 
@@ -229,7 +230,7 @@ func TestDelegateCallStackArgs(t *testing.T) {
 		vm.PUSH1, 0x00, // argsSize
 		vm.PUSH1, 0x00, // argsOffset
 		vm.PUSH1, 0x01, // address (0x01)
-		vm.PUSH1, 0x64, // gas (100)
+		vm.PUSH2, 0x0b, 0xb8, // gas (3000, the cost of ECRECOVER at 0x01)
 		vm.DELEGATECALL, // DELEGATECALL
 	}
 
@@ -262,7 +263,7 @@ func TestStaticCallStackArgs(t *testing.T) {
 		vm.PUSH1, 0x00, // argsSize
 		vm.PUSH1, 0x00, // argsOffset
 		vm.PUSH1, 0x01, // address (0x01)
-		vm.PUSH1, 0x64, // gas (100)
+		vm.PUSH2, 0x0b, 0xb8, // gas (3000, the cost of ECRECOVER at 0x01)
 		vm.STATICCALL, // STATICCALL
 	}
 
@@ -296,7 +297,7 @@ func TestCallDepth(t *testing.T) {
 		vm.PUSH1, 0x00, // argsOffset
 		vm.PUSH1, 0x00, // value
 		vm.PUSH1, 0x01, // address (0x01)
-		vm.PUSH1, 0x64, // gas (100)
+		vm.PUSH2, 0x0b, 0xb8, // gas (3000, the cost of ECRECOVER at 0x01)
 		vm.CALL, // CALL
 	}
 
@@ -329,3 +330,95 @@ func TestCallDepth(t *testing.T) {
 		t.Fatalf("call depth after CALL should be 1, got %d", d.CallDepth())
 	}
 }
+
+func TestRevertingSubcallRollsBackStorage(t *testing.T) {
+	caller := [20]byte{0xaa}
+	callee := [20]byte{19: 0xbb}
+	// SSTORE(0, 1), then REVERT(0, 0) or STOP
+	reverting := []byte{vm.PUSH1, 0x01, vm.PUSH0, vm.SSTORE, vm.PUSH0, vm.PUSH0, vm.REVERT}
+	succeeding := []byte{vm.PUSH1, 0x01, vm.PUSH0, vm.SSTORE, vm.STOP}
+
+	for _, op := range []byte{vm.CALL, vm.CALLCODE, vm.DELEGATECALL} {
+		for _, revert := range []bool{true, false} {
+			code := []byte{vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0}
+			if op != vm.DELEGATECALL {
+				code = append(code, vm.PUSH0) // value
+			}
+			code = append(code, vm.PUSH1, 0xbb, vm.GAS, op, vm.STOP)
+
+			calleeCode := succeeding
+			if revert {
+				calleeCode = reverting
+			}
+			sp := state.NewMemoryState()
+			sp.AddAccount([20]byte{0x01}, nil, uint256.NewInt(1000))
+			sp.AddAccount(caller, code, uint256.NewInt(0))
+			sp.AddAccount(callee, calleeCode, uint256.NewInt(0))
+
+			s := vm.NewSession(sp, nil, GetHandler, []vm.Transaction{{From: [20]byte{0x01}, To: &caller, Gas: 100000}})
+			if err := s.Run(); err != nil {
+				t.Fatalf("%s: session error: %v", vm.OpCode(op), err)
+			}
+			if result := s.Results[0]; result.Failed() {
+				t.Fatalf("%s: expected the transaction to succeed, got %v", vm.OpCode(op), result.Err)
+			}
+
+			// CALL writes to the storage of the callee, CALLCODE and DELEGATECALL to the caller's
+			written := callee
+			if op != vm.CALL {
+				written = caller
+			}
+			expected := uint64(1)
+			if revert {
+				expected = 0
+			}
+			if got := sp.GetStorage(written, uint256.NewInt(0)); got.Uint64() != expected {
+				t.Errorf("%s (revert %v): expected slot 0 to be %d, got %s", vm.OpCode(op), revert, expected, got.Hex())
+			}
+		}
+	}
+}
+
+func TestRevertingSubcallDropsLogs(t *testing.T) {
+	caller := [20]byte{0xaa}
+	callee := [20]byte{19: 0xbb}
+	// LOG0(0, 0), then REVERT(0, 0)
+	calleeCode := []byte{vm.PUSH0, vm.PUSH0, vm.LOG0, vm.PUSH0, vm.PUSH0, vm.REVERT}
+
+	for _, revert := range []bool{false, true} {
+		// CALL the callee, then LOG0(0, 0) and STOP or REVERT(0, 0)
+		code := []byte{
+			vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0xbb, vm.GAS, vm.CALL, vm.POP,
+			vm.PUSH0, vm.PUSH0, vm.LOG0,
+		}
+		if revert {
+			code = append(code, vm.PUSH0, vm.PUSH0, vm.REVERT)
+		} else {
+			code = append(code, vm.STOP)
+		}
+		sp := state.NewMemoryState()
+		sp.AddAccount([20]byte{0x01}, nil, uint256.NewInt(0))
+		sp.AddAccount(caller, code, uint256.NewInt(0))
+		sp.AddAccount(callee, calleeCode, uint256.NewInt(0))
+
+		s := vm.NewSession(sp, nil, GetHandler, []vm.Transaction{{From: [20]byte{0x01}, To: &caller, Gas: 100000}})
+		if err := s.Run(); err != nil {
+			t.Fatalf("session error: %v", err)
+		}
+		result := s.Results[0]
+		if result.Reverted != revert {
+			t.Fatalf("expected reverted %v, got %v (err %v)", revert, result.Reverted, result.Err)
+		}
+
+		// Only the log of the caller is kept, and none if the transaction reverted
+		if revert {
+			if len(result.Logs) != 0 {
+				t.Errorf("expected no logs for the reverted transaction, got %d", len(result.Logs))
+			}
+			continue
+		}
+		if len(result.Logs) != 1 || result.Logs[0].Address != caller {
+			t.Errorf("expected the caller's log only, got %+v", result.Logs)
+		}
+	}
+}
@@ -51,22 +51,43 @@ func (*CallCodeOpCode) Execute(v *vm.DebuggerVM) error {
 		copy(addr[20-len(addressBytes):], addressBytes)
 	}
 
+	// The callee gets the gas forwarded when the instruction was charged
+	callGas := v.ForwardedGas(gas, value)
+
+	// Precompiled contracts run natively
+	if p, ok := v.Precompile(addr); ok {
+		return callPrecompile(v, addr, p, vm.CallTypeCallCode, value, callGas, argsOffset, argsSize, retOffset, retSize)
+	}
+
 	// For now, if no StateProvider is set, return success but do nothing
 	if v.StateProvider == nil {
+		v.ReturnGas(callGas, callGas, nil)
 		// Push success result (1) onto stack
 		return v.Push(uint256.NewInt(1))
 	}
 
 	// Check if the account exists
 	if !v.StateProvider.AccountExists(addr) {
+		v.ReturnGas(callGas, callGas, nil)
 		// Push failure result (0) onto stack
 		return v.Push(uint256.NewInt(0))
 	}
 
-	// Get the target code
-	targetCode := v.StateProvider.GetCode(addr)
+	// Prepare call data
+	var callData []byte
+	if !argsSize.IsZero() && !argsOffset.IsZero() {
+		argsSizeInt := int(argsSize.Uint64())
+		argsOffsetInt := int(argsOffset.Uint64())
+		callData = v.Memory().Read(argsOffsetInt, argsSizeInt)
+	}
+
+	// Get the target code, following an EIP-7702 delegation
+	targetCode := v.ResolveCode(addr)
 	if len(targetCode) == 0 {
 		// Empty code means successful call with no execution
+		v.ExitFrame(v.EnterFrame(vm.CallTypeCallCode, addr, callData, value, callGas), nil, nil)
+		v.ReturnGas(callGas, callGas, nil)
+
 		// Clear return data area if specified
 		if !retSize.IsZero() && !retOffset.IsZero() {
 			retSizeInt := int(retSize.Uint64())
@@ -77,14 +98,6 @@ func (*CallCodeOpCode) Execute(v *vm.DebuggerVM) error {
 		return v.Push(uint256.NewInt(1))
 	}
 
-	// Prepare call data
-	var callData []byte
-	if !argsSize.IsZero() && !argsOffset.IsZero() {
-		argsSizeInt := int(argsSize.Uint64())
-		argsOffsetInt := int(argsOffset.Uint64())
-		callData = v.Memory().Read(argsOffsetInt, argsSizeInt)
-	}
-
 	// CALLCODE executes external code in the current context
 	// This means storage writes go to the current contract
 	newFrame := vm.MessageFrame{
@@ -93,7 +106,7 @@ func (*CallCodeOpCode) Execute(v *vm.DebuggerVM) error {
 		Stack:        vm.NewStack(),
 		Memory:       vm.NewMemory(),
 		ReturnData:   nil,
-		Gas:          gas.Uint64(),
+		Gas:          callGas,
 		CallType:     vm.CallTypeCallCode,
 		IsStatic:     false,
 		CodeMetadata: vm.ScanCodeMetadata(targetCode),
@@ -109,13 +122,19 @@ func (*CallCodeOpCode) Execute(v *vm.DebuggerVM) error {
 		Value:    new(uint256.Int).Set(value),
 		CallData: callData,
 		GasPrice: oldContext.GasPrice,
-		Gas:      gas.Uint64(),
+		Gas:      callGas,
 		Balance:  oldContext.Balance, // Same balance (current contract)
 		Block:    oldContext.Block,
 	}
 
+	// Changes of a failing call are rolled back if the state provider supports it
+	snap := snapshot(v)
+
 	// Push the new frame
+	callFrame := v.EnterFrame(vm.CallTypeCallCode, addr, callData, value, callGas)
 	if err := v.PushFrame(newFrame); err != nil {
+		v.ExitFrame(callFrame, nil, err)
+		endSnapshot(v, snap, true)
 		return err
 	}
 
@@ -133,8 +152,12 @@ func (*CallCodeOpCode) Execute(v *vm.DebuggerVM) error {
 		// Continue with cleanup
 	}
 
-	// Restore context and pop frame
+	v.ExitFrame(callFrame, v.CurrentFrame().ReturnData, err)
+
+	// Restore context and pop frame, the caller gets back the gas the callee left
 	v.Context = oldContext
+	v.ReturnGas(callGas, newContext.Gas, err)
+	endSnapshot(v, snap, err != nil)
 	if popErr := v.PopFrame(); popErr != nil {
 		return popErr
 	}
@@ -0,0 +1,24 @@
+package opcode_handlers
+
+import (
+	"encoding/binary"
+
+	"github.com/daniellehrner/evmdbg/vm"
+)
+
+type CallFOpCode struct{}
+
+func (*CallFOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.CALLF); err != nil {
+		return err
+	}
+
+	// Read the target section and return to the instruction after the immediate
+	imm, err := v.ReadCodeSlice(2)
+	if err != nil {
+		return err
+	}
+	v.AdvancePC(2)
+
+	return v.CallSection(int(binary.BigEndian.Uint16(imm)))
+}
@@ -0,0 +1,150 @@
+package opcode_handlers
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// pushFortyTwoOpCode is a custom instruction pushing 42
+type pushFortyTwoOpCode struct{}
+
+func (*pushFortyTwoOpCode) Execute(v *vm.DebuggerVM) error {
+	return v.PushUint64(42)
+}
+
+// reversePrecompile returns its input reversed for 10 gas per byte
+type reversePrecompile struct{}
+
+func (reversePrecompile) RequiredGas(input []byte) uint64 {
+	return 10 * uint64(len(input))
+}
+
+func (reversePrecompile) Run(input []byte) ([]byte, error) {
+	if len(input) == 0 {
+		return nil, errors.New("empty input")
+	}
+	output := make([]byte, len(input))
+	for i, b := range input {
+		output[len(input)-1-i] = b
+	}
+	return output, nil
+}
+
+func TestChainRulesCustomOpcode(t *testing.T) {
+	d := vm.NewDebuggerVM([]byte{0x0c, vm.STOP}, GetHandler)
+	d.ChainRules = &vm.ChainRules{Opcodes: map[byte]vm.Handler{0x0c: &pushFortyTwoOpCode{}}}
+	if err := d.Step(); err != nil {
+		t.Fatalf("execution error: %v", err)
+	}
+	if top, _ := d.Stack().Peek(0); top.Uint64() != 42 {
+		t.Fatalf("expected 42, got %d", top.Uint64())
+	}
+
+	// Other VMs are not affected
+	other := vm.NewDebuggerVM([]byte{0x0c}, GetHandler)
+	if err := other.Step(); err == nil {
+		t.Fatal("expected 0x0c to be undefined without chain rules")
+	}
+}
+
+func TestChainRulesGasFunction(t *testing.T) {
+	rules := &vm.ChainRules{Gas: map[byte]vm.GasFunc{
+		vm.ADD: func(v *vm.DebuggerVM, op byte) (uint64, error) { return 1000, nil },
+	}}
+	code := []byte{vm.PUSH1, 0x01, vm.PUSH1, 0x02, vm.ADD, vm.STOP}
+
+	d := vm.NewDebuggerVM(code, GetHandler)
+	d.ChainRules = rules
+	d.GasSchedule = vm.LondonGasSchedule
+	d.Context = &vm.ExecutionContext{Value: uint256.NewInt(0), Gas: 10000}
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			t.Fatalf("execution error: %v", err)
+		}
+	}
+	if d.GasUsed() != 1006 {
+		t.Fatalf("expected 1006 gas used, got %d", d.GasUsed())
+	}
+}
+
+func TestChainRulesPrecompile(t *testing.T) {
+	precompile := [20]byte{19: 0x99}
+	// MSTORE(0, 0x0102), CALL the precompile with the last 2 bytes of the word as input and
+	// 0x40 as the return area, then RETURN(0x40, 2)
+	code := []byte{
+		vm.PUSH2, 0x01, 0x02, vm.PUSH0, vm.MSTORE,
+		vm.PUSH1, 0x02, vm.PUSH1, 0x40, vm.PUSH1, 0x02, vm.PUSH1, 0x1e, vm.PUSH0, vm.PUSH1, 0x99, vm.GAS, vm.CALL,
+		vm.PUSH1, 0x02, vm.PUSH1, 0x40, vm.RETURN,
+	}
+
+	d := vm.NewDebuggerVM(code, GetHandler)
+	d.StateProvider = state.NewMemoryState()
+	d.ChainRules = &vm.ChainRules{Precompiles: map[[20]byte]vm.PrecompiledContract{precompile: reversePrecompile{}}}
+	d.Context = &vm.ExecutionContext{Address: [20]byte{19: 0xaa}, Value: uint256.NewInt(0), Gas: 100000}
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			t.Fatalf("execution error: %v", err)
+		}
+	}
+
+	if !bytes.Equal(d.ReturnValue, []byte{0x02, 0x01}) {
+		t.Fatalf("expected reversed output 0201, got %x", d.ReturnValue)
+	}
+	if !bytes.Equal(d.ReturnData(), []byte{0x02, 0x01}) {
+		t.Fatalf("expected return data 0201, got %x", d.ReturnData())
+	}
+	if success, _ := d.Stack().Peek(0); success.Uint64() != 1 {
+		t.Fatalf("expected the precompile call to succeed, got %d", success.Uint64())
+	}
+}
+
+func TestChainRulesTransactionHooks(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	vault := [20]byte{0xcc}
+
+	rules := &vm.ChainRules{
+		// Mint the transferred value before the transaction is validated
+		BeforeTransaction: func(s *vm.Session, tx *vm.Transaction) error {
+			if tx.Value.Uint64() > 500 {
+				return errors.New("mint limit exceeded")
+			}
+			s.VM.StateProvider.SetBalance(tx.From, tx.Value)
+			return nil
+		},
+		// Credit a fixed fee to the vault
+		AfterTransaction: func(s *vm.Session, tx *vm.Transaction, result *vm.TransactionResult) {
+			sp := s.VM.StateProvider
+			sp.SetBalance(vault, new(uint256.Int).AddUint64(sp.GetBalance(vault), 1))
+		},
+	}
+
+	txs := []vm.Transaction{
+		{From: sender, To: &target, Value: uint256.NewInt(100)},
+		{From: sender, To: &target, Value: uint256.NewInt(1000)},
+	}
+	s := vm.NewSession(sp, nil, GetHandler, txs)
+	s.VM.ChainRules = rules
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	if s.Results[0].Failed() {
+		t.Fatalf("expected first transaction to succeed, got %v", s.Results[0].Err)
+	}
+	if s.Results[1].Err == nil {
+		t.Fatal("expected the hook to fail the second transaction")
+	}
+	if got := sp.GetBalance(target).Uint64(); got != 100 {
+		t.Fatalf("expected target balance 100, got %d", got)
+	}
+	if got := sp.GetBalance(vault).Uint64(); got != 2 {
+		t.Fatalf("expected vault balance 2, got %d", got)
+	}
+}
@@ -0,0 +1,24 @@
+package opcode_handlers
+
+import (
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+type ClzOpCode struct{}
+
+func (*ClzOpCode) Execute(v *vm.DebuggerVM) error {
+	// CLZ requires one value on the stack.
+	if err := v.RequireStack(1); err != nil {
+		return err
+	}
+
+	// Pop the top item from the stack.
+	x, err := v.Stack().Pop()
+	if err != nil {
+		return err
+	}
+
+	// Count the leading zero bits, a zero value has 256 of them (EIP-7939).
+	return v.Push(uint256.NewInt(uint64(256 - x.BitLen())))
+}
@@ -0,0 +1,41 @@
+package opcode_handlers
+
+import (
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+func TestClzOpCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    *uint256.Int
+		expected uint64
+	}{
+		{"zero", uint256.NewInt(0), 256},
+		{"one", uint256.NewInt(1), 255},
+		{"top bit set", new(uint256.Int).Lsh(uint256.NewInt(1), 255), 0},
+		{"max", new(uint256.Int).SetAllOne(), 0},
+		{"0xff", uint256.NewInt(0xff), 248},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := append([]byte{vm.PUSH32}, bytes32WithValue(tt.value)...)
+			code = append(code, vm.CLZ)
+			d := vm.NewDebuggerVM(code, GetHandlerForFork(vm.Osaka))
+
+			for !d.Stopped {
+				if err := d.Step(); err != nil {
+					t.Fatalf("execution error: %v", err)
+				}
+			}
+
+			result, _ := d.Stack().Pop()
+			if result.Uint64() != tt.expected {
+				t.Fatalf("expected %d, got %d", tt.expected, result.Uint64())
+			}
+		})
+	}
+}
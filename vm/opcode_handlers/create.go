@@ -59,7 +59,7 @@ func (*CreateOpCode) Execute(v *vm.DebuggerVM) error {
 	copy(newAddr[:], hashResult[12:32]) // Take last 20 bytes
 
 	// Increment sender's nonce
-	v.StateProvider.SetNonce(senderAddr, nonce+1)
+	v.SetNonce(senderAddr, nonce+1)
 
 	// Check if account already exists
 	if v.StateProvider.AccountExists(newAddr) {
@@ -67,8 +67,11 @@ func (*CreateOpCode) Execute(v *vm.DebuggerVM) error {
 		return v.Push(uint256.NewInt(0))
 	}
 
-	// Create the new contract account
-	err = v.StateProvider.CreateAccount(newAddr, initCode, value)
+	// Create the new contract account. The init code is stored as the contract code
+	// without being executed.
+	callFrame := v.EnterFrame(vm.CallTypeCreate, newAddr, initCode, value, 0)
+	err = v.CreateAccount(newAddr, initCode, value)
+	v.ExitFrame(callFrame, initCode, err)
 	if err != nil {
 		// Push 0 to indicate failure
 		return v.Push(uint256.NewInt(0))
@@ -85,7 +85,7 @@ func (*Create2OpCode) Execute(v *vm.DebuggerVM) error {
 
 	// Increment sender's nonce (CREATE2 also increments nonce)
 	nonce := v.StateProvider.GetNonce(senderAddr)
-	v.StateProvider.SetNonce(senderAddr, nonce+1)
+	v.SetNonce(senderAddr, nonce+1)
 
 	// Check if account already exists
 	if v.StateProvider.AccountExists(newAddr) {
@@ -93,8 +93,11 @@ func (*Create2OpCode) Execute(v *vm.DebuggerVM) error {
 		return v.Push(uint256.NewInt(0))
 	}
 
-	// Create the new contract account
-	err = v.StateProvider.CreateAccount(newAddr, initCode, value)
+	// Create the new contract account. The init code is stored as the contract code
+	// without being executed.
+	callFrame := v.EnterFrame(vm.CallTypeCreate2, newAddr, initCode, value, 0)
+	err = v.CreateAccount(newAddr, initCode, value)
+	v.ExitFrame(callFrame, initCode, err)
 	if err != nil {
 		// Push 0 to indicate failure
 		return v.Push(uint256.NewInt(0))
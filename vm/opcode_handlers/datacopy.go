@@ -0,0 +1,31 @@
+package opcode_handlers
+
+import "github.com/daniellehrner/evmdbg/vm"
+
+type DataCopyOpCode struct{}
+
+func (*DataCopyOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.DATACOPY); err != nil {
+		return err
+	}
+
+	// DATACOPY requires three items on the stack: memOffset, offset, size
+	if err := v.RequireStack(3); err != nil {
+		return err
+	}
+
+	memOffset, offset, size, err := v.Pop3()
+	if err != nil {
+		return err
+	}
+
+	// Copy from the data section, reads beyond its end yield zeroes
+	data := v.CurrentFrame().EOF.Data
+	buf := make([]byte, size.Uint64())
+	if offset.IsUint64() && offset.Uint64() < uint64(len(data)) {
+		copy(buf, data[offset.Uint64():])
+	}
+
+	v.Memory().Write(int(memOffset.Uint64()), buf)
+	return nil
+}
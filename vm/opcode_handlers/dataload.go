@@ -0,0 +1,33 @@
+package opcode_handlers
+
+import (
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+type DataLoadOpCode struct{}
+
+func (*DataLoadOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.DATALOAD); err != nil {
+		return err
+	}
+
+	// DATALOAD requires the offset on the stack
+	if err := v.RequireStack(1); err != nil {
+		return err
+	}
+
+	offset, err := v.Stack().Pop()
+	if err != nil {
+		return err
+	}
+
+	// Read 32 bytes of the data section, padded with zeroes
+	data := v.CurrentFrame().EOF.Data
+	word := make([]byte, 32)
+	if offset.IsUint64() && offset.Uint64() < uint64(len(data)) {
+		copy(word, data[offset.Uint64():])
+	}
+
+	return v.Push(new(uint256.Int).SetBytes(word))
+}
@@ -0,0 +1,34 @@
+package opcode_handlers
+
+import (
+	"encoding/binary"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+type DataLoadNOpCode struct{}
+
+func (*DataLoadNOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.DATALOADN); err != nil {
+		return err
+	}
+
+	// Read the offset from the immediate
+	imm, err := v.ReadCodeSlice(2)
+	if err != nil {
+		return err
+	}
+	v.AdvancePC(2)
+	offset := int(binary.BigEndian.Uint16(imm))
+
+	// Validation guarantees offset+32 is within the declared data size. Data of a
+	// deployed container without its aux data is still padded with zeroes.
+	data := v.CurrentFrame().EOF.Data
+	word := make([]byte, 32)
+	if offset < len(data) {
+		copy(word, data[offset:])
+	}
+
+	return v.Push(new(uint256.Int).SetBytes(word))
+}
@@ -0,0 +1,14 @@
+package opcode_handlers
+
+import "github.com/daniellehrner/evmdbg/vm"
+
+type DataSizeOpCode struct{}
+
+func (*DataSizeOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.DATASIZE); err != nil {
+		return err
+	}
+
+	// Push the size of the data section of the executing container
+	return v.PushUint64(uint64(len(v.CurrentFrame().EOF.Data)))
+}
@@ -47,22 +47,43 @@ func (*DelegateCallOpCode) Execute(v *vm.DebuggerVM) error {
 		copy(addr[20-len(addressBytes):], addressBytes)
 	}
 
+	// The callee gets the gas forwarded when the instruction was charged
+	callGas := v.ForwardedGas(gas, nil)
+
+	// Precompiled contracts run natively
+	if p, ok := v.Precompile(addr); ok {
+		return callPrecompile(v, addr, p, vm.CallTypeDelegateCall, nil, callGas, argsOffset, argsSize, retOffset, retSize)
+	}
+
 	// For now, if no StateProvider is set, return success but do nothing
 	if v.StateProvider == nil {
+		v.ReturnGas(callGas, callGas, nil)
 		// Push success result (1) onto stack
 		return v.Push(uint256.NewInt(1))
 	}
 
 	// Check if the account exists
 	if !v.StateProvider.AccountExists(addr) {
+		v.ReturnGas(callGas, callGas, nil)
 		// Push failure result (0) onto stack
 		return v.Push(uint256.NewInt(0))
 	}
 
-	// Get the target code
-	targetCode := v.StateProvider.GetCode(addr)
+	// Prepare call data
+	var callData []byte
+	if !argsSize.IsZero() && !argsOffset.IsZero() {
+		argsSizeInt := int(argsSize.Uint64())
+		argsOffsetInt := int(argsOffset.Uint64())
+		callData = v.Memory().Read(argsOffsetInt, argsSizeInt)
+	}
+
+	// Get the target code, following an EIP-7702 delegation
+	targetCode := v.ResolveCode(addr)
 	if len(targetCode) == 0 {
 		// Empty code means successful call with no execution
+		v.ExitFrame(v.EnterFrame(vm.CallTypeDelegateCall, addr, callData, nil, callGas), nil, nil)
+		v.ReturnGas(callGas, callGas, nil)
+
 		// Clear return data area if specified
 		if !retSize.IsZero() && !retOffset.IsZero() {
 			retSizeInt := int(retSize.Uint64())
@@ -73,14 +94,6 @@ func (*DelegateCallOpCode) Execute(v *vm.DebuggerVM) error {
 		return v.Push(uint256.NewInt(1))
 	}
 
-	// Prepare call data
-	var callData []byte
-	if !argsSize.IsZero() && !argsOffset.IsZero() {
-		argsSizeInt := int(argsSize.Uint64())
-		argsOffsetInt := int(argsOffset.Uint64())
-		callData = v.Memory().Read(argsOffsetInt, argsSizeInt)
-	}
-
 	// DELEGATECALL executes external code but preserves the original caller
 	// The code executes in the current context (same address, storage)
 	// but msg.sender and msg.value are preserved from the original call
@@ -90,7 +103,7 @@ func (*DelegateCallOpCode) Execute(v *vm.DebuggerVM) error {
 		Stack:        vm.NewStack(),
 		Memory:       vm.NewMemory(),
 		ReturnData:   nil,
-		Gas:          gas.Uint64(),
+		Gas:          callGas,
 		CallType:     vm.CallTypeDelegateCall,
 		IsStatic:     false,
 		CodeMetadata: vm.ScanCodeMetadata(targetCode),
@@ -106,13 +119,19 @@ func (*DelegateCallOpCode) Execute(v *vm.DebuggerVM) error {
 		Value:    oldContext.Value,   // Preserve original value
 		CallData: callData,
 		GasPrice: oldContext.GasPrice,
-		Gas:      gas.Uint64(),
+		Gas:      callGas,
 		Balance:  oldContext.Balance, // Same balance (current contract)
 		Block:    oldContext.Block,
 	}
 
+	// Changes of a failing call are rolled back if the state provider supports it
+	snap := snapshot(v)
+
 	// Push the new frame
+	callFrame := v.EnterFrame(vm.CallTypeDelegateCall, addr, callData, nil, callGas)
 	if err := v.PushFrame(newFrame); err != nil {
+		v.ExitFrame(callFrame, nil, err)
+		endSnapshot(v, snap, true)
 		return err
 	}
 
@@ -130,8 +149,12 @@ func (*DelegateCallOpCode) Execute(v *vm.DebuggerVM) error {
 		// Continue with cleanup
 	}
 
-	// Restore context and pop frame
+	v.ExitFrame(callFrame, v.CurrentFrame().ReturnData, err)
+
+	// Restore context and pop frame, the caller gets back the gas the callee left
 	v.Context = oldContext
+	v.ReturnGas(callGas, newContext.Gas, err)
+	endSnapshot(v, snap, err != nil)
 	if popErr := v.PopFrame(); popErr != nil {
 		return popErr
 	}
@@ -0,0 +1,304 @@
+package opcode_handlers
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/crypto"
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/holiman/uint256"
+)
+
+// delegateCode stores 0x2a in slot 0 and returns ADDRESS
+var delegateCode = []byte{
+	vm.PUSH1, 0x2a, vm.PUSH1, 0x00, vm.SSTORE,
+	vm.ADDRESS, vm.PUSH1, 0x00, vm.MSTORE,
+	vm.PUSH1, 0x20, vm.PUSH1, 0x00, vm.RETURN,
+}
+
+func testKey(seed byte) (*secp256k1.PrivateKey, [20]byte) {
+	var b [32]byte
+	b[31] = seed
+	key := secp256k1.PrivKeyFromBytes(b[:])
+	return key, crypto.PubkeyToAddress(key.PubKey())
+}
+
+func signAuthorization(key *secp256k1.PrivateKey, auth vm.SetCodeAuthorization) vm.SetCodeAuthorization {
+	auth.V, auth.R, auth.S = crypto.Sign(auth.SigHash(), key)
+	return auth
+}
+
+func push20(addr [20]byte) []byte {
+	return append([]byte{vm.PUSH20}, addr[:]...)
+}
+
+func TestSetCodeTransactionDelegatesExecution(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	delegate := [20]byte{0xde}
+	caller := [20]byte{0xca}
+	key, eoa := testKey(1)
+
+	// caller CALLs the EOA and returns [EXTCODESIZE, EXTCODEHASH, returned ADDRESS, success]
+	callerCode := []byte{
+		vm.PUSH1, 0x20, vm.PUSH1, 0x40, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00,
+	}
+	callerCode = append(callerCode, push20(eoa)...)
+	callerCode = append(callerCode, vm.PUSH1, 0x00, vm.CALL, vm.PUSH1, 0x60, vm.MSTORE)
+	callerCode = append(callerCode, push20(eoa)...)
+	callerCode = append(callerCode, vm.EXTCODESIZE, vm.PUSH1, 0x00, vm.MSTORE)
+	callerCode = append(callerCode, push20(eoa)...)
+	callerCode = append(callerCode, vm.EXTCODEHASH, vm.PUSH1, 0x20, vm.MSTORE)
+	callerCode = append(callerCode, vm.PUSH1, 0x80, vm.PUSH1, 0x00, vm.RETURN)
+
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	sp.AddAccount(delegate, delegateCode, nil)
+	sp.AddAccount(caller, callerCode, nil)
+	sp.AddAccount(eoa, nil, uint256.NewInt(1))
+
+	chainID := uint256.NewInt(1)
+	auth := signAuthorization(key, vm.SetCodeAuthorization{ChainID: chainID, Address: delegate, Nonce: 0})
+	txs := []vm.Transaction{
+		{From: sender, To: &eoa, AuthorizationList: []vm.SetCodeAuthorization{auth}},
+		{From: sender, To: &caller},
+	}
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1, ChainID: chainID}, GetHandler, txs)
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	for i, r := range s.Results {
+		if r.Failed() {
+			t.Fatalf("transaction %d failed: reverted=%v err=%v", i, r.Reverted, r.Err)
+		}
+	}
+	if err := s.Results[0].AuthorizationErrors[0]; err != nil {
+		t.Fatalf("authorization was not applied: %v", err)
+	}
+
+	designator := vm.AddressToDelegation(delegate)
+	if !bytes.Equal(sp.GetCode(eoa), designator) {
+		t.Fatalf("expected designator %x, got %x", designator, sp.GetCode(eoa))
+	}
+	if got := sp.GetNonce(eoa); got != 1 {
+		t.Fatalf("expected authority nonce 1, got %d", got)
+	}
+
+	// The delegate code ran in the context of the EOA, both for the transaction and the CALL
+	if got := sp.GetStorage(eoa, uint256.NewInt(0)); got.Uint64() != 0x2a {
+		t.Fatalf("expected slot 0 of the EOA to be 0x2a, got %s", got.Hex())
+	}
+	if got := sp.GetStorage(delegate, uint256.NewInt(0)); !got.IsZero() {
+		t.Fatalf("delegate storage should be untouched, got %s", got.Hex())
+	}
+
+	out := s.Results[1].ReturnValue
+	if len(out) != 128 {
+		t.Fatalf("expected 128 bytes of return data, got %d", len(out))
+	}
+	if size := new(uint256.Int).SetBytes(out[:32]); size.Uint64() != 23 {
+		t.Fatalf("EXTCODESIZE should observe the 23 byte designator, got %d", size.Uint64())
+	}
+	if hash := crypto.Keccak256(designator); !bytes.Equal(out[32:64], hash[:]) {
+		t.Fatalf("EXTCODEHASH should hash the designator, got %x", out[32:64])
+	}
+	if !bytes.Equal(out[76:96], eoa[:]) {
+		t.Fatalf("delegate should execute with ADDRESS of the EOA, got %x", out[64:96])
+	}
+	if out[127] != 1 {
+		t.Fatal("CALL to the delegated EOA should succeed")
+	}
+}
+
+func TestSetCodeInvalidAuthorizationsAreSkipped(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	delegate := [20]byte{0xde}
+	target := [20]byte{0x01}
+	chainID := uint256.NewInt(1)
+
+	key1, eoa1 := testKey(1)
+	key2, eoa2 := testKey(2)
+	key3, eoa3 := testKey(3)
+	key4, contract := testKey(4)
+
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	sp.AddAccount(contract, []byte{vm.STOP}, nil)
+
+	invalidSig := signAuthorization(key1, vm.SetCodeAuthorization{ChainID: chainID, Address: delegate})
+	invalidSig.S = uint256.MustFromHex("0x7fffffffffffffffffffffffffffffff5d576e7357a4501ddfe92f46681b20a1") // N/2 + 1
+
+	list := []vm.SetCodeAuthorization{
+		signAuthorization(key1, vm.SetCodeAuthorization{ChainID: uint256.NewInt(5), Address: delegate}),
+		signAuthorization(key2, vm.SetCodeAuthorization{ChainID: chainID, Address: delegate, Nonce: 7}),
+		signAuthorization(key4, vm.SetCodeAuthorization{ChainID: chainID, Address: delegate}),
+		invalidSig,
+		signAuthorization(key3, vm.SetCodeAuthorization{Address: delegate}), // chain id 0 is valid on any chain
+		signAuthorization(key3, vm.SetCodeAuthorization{ChainID: chainID, Address: delegate}),
+	}
+	txs := []vm.Transaction{{From: sender, To: &target, AuthorizationList: list}}
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1, ChainID: chainID}, GetHandler, txs)
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	expected := []error{vm.ErrAuthChainID, vm.ErrAuthNonceMismatch, vm.ErrAuthDestinationCode, vm.ErrAuthInvalidSig, nil, vm.ErrAuthNonceMismatch}
+	errs := s.Results[0].AuthorizationErrors
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d authorization results, got %d", len(expected), len(errs))
+	}
+	for i, want := range expected {
+		if want == nil && errs[i] != nil || want != nil && !errors.Is(errs[i], want) {
+			t.Errorf("authorization %d: expected %v, got %v", i, want, errs[i])
+		}
+	}
+
+	for _, addr := range [][20]byte{eoa1, eoa2} {
+		if sp.AccountExists(addr) {
+			t.Errorf("account %x should not be touched by an invalid authorization", addr)
+		}
+	}
+	if !bytes.Equal(sp.GetCode(contract), []byte{vm.STOP}) {
+		t.Errorf("contract code should not be replaced, got %x", sp.GetCode(contract))
+	}
+	if got := sp.GetNonce(eoa3); got != 1 {
+		t.Errorf("expected nonce 1 for the only applied authorization, got %d", got)
+	}
+}
+
+func TestSetCodeZeroAddressClearsDelegation(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	delegate := [20]byte{0xde}
+	key, eoa := testKey(1)
+
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	sp.AddAccount(delegate, delegateCode, nil)
+
+	txs := []vm.Transaction{
+		{From: sender, To: &eoa, AuthorizationList: []vm.SetCodeAuthorization{
+			signAuthorization(key, vm.SetCodeAuthorization{Address: delegate, Nonce: 0}),
+		}},
+		{From: sender, To: &eoa, AuthorizationList: []vm.SetCodeAuthorization{
+			signAuthorization(key, vm.SetCodeAuthorization{Nonce: 1}),
+		}},
+	}
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1, ChainID: uint256.NewInt(1)}, GetHandler, txs)
+	if err := s.RunUntil(1, map[uint64]struct{}{0: {}}); !errors.Is(err, vm.ErrSessionDone) {
+		t.Fatalf("expected ErrSessionDone, got %v", err)
+	}
+
+	// The first transaction executed the delegate, the second one runs no code at all
+	if s.Results[0].ReturnValue == nil || s.Results[1].ReturnValue != nil {
+		t.Fatalf("unexpected return values %x and %x", s.Results[0].ReturnValue, s.Results[1].ReturnValue)
+	}
+	if code := sp.GetCode(eoa); len(code) != 0 {
+		t.Fatalf("expected delegation to be cleared, got code %x", code)
+	}
+	if got := sp.GetNonce(eoa); got != 2 {
+		t.Fatalf("expected authority nonce 2, got %d", got)
+	}
+}
+
+func TestSetCodeTransactionCannotCreate(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	key, _ := testKey(1)
+
+	txs := []vm.Transaction{{From: sender, AuthorizationList: []vm.SetCodeAuthorization{
+		signAuthorization(key, vm.SetCodeAuthorization{Address: [20]byte{0xde}}),
+	}}}
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1}, GetHandler, txs)
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	if !errors.Is(s.Results[0].Err, vm.ErrSetCodeTxCreate) {
+		t.Fatalf("expected ErrSetCodeTxCreate, got %v", s.Results[0].Err)
+	}
+}
+
+func TestSetCodeAuthorizationRefundAndAccessList(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	delegate := [20]byte{0xde}
+	target := [20]byte{0xbb}
+	reverting := [20]byte{0xcc}
+	chainID := uint256.NewInt(1)
+
+	key1, existing := testKey(1)
+	key2, created := testKey(2)
+	key3, invalid := testKey(3)
+
+	// BALANCE of each authority
+	var code []byte
+	for _, addr := range [][20]byte{existing, created, invalid} {
+		code = append(code, push20(addr)...)
+		code = append(code, vm.BALANCE, vm.POP)
+	}
+	code = append(code, vm.STOP)
+
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	sp.AddAccount(target, code, nil)
+	sp.AddAccount(reverting, []byte{vm.PUSH0, vm.PUSH0, vm.REVERT}, nil)
+	sp.AddAccount(existing, nil, uint256.NewInt(1))
+
+	txs := []vm.Transaction{
+		{From: sender, To: &target, Gas: 200000, AuthorizationList: []vm.SetCodeAuthorization{
+			signAuthorization(key1, vm.SetCodeAuthorization{ChainID: chainID, Address: delegate}),
+			signAuthorization(key2, vm.SetCodeAuthorization{ChainID: chainID, Address: delegate}),
+			signAuthorization(key3, vm.SetCodeAuthorization{ChainID: chainID, Address: delegate, Nonce: 7}),
+		}},
+		{From: sender, To: &reverting, Gas: 200000, AuthorizationList: []vm.SetCodeAuthorization{
+			signAuthorization(key1, vm.SetCodeAuthorization{ChainID: chainID, Address: delegate, Nonce: 1}),
+		}},
+	}
+
+	tracer := &eventTracer{}
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1, ChainID: chainID}, GetHandler, txs)
+	s.VM.GasSchedule = vm.ShanghaiGasSchedule
+	s.VM.Tracer = tracer
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	// Every recovered authority is warm, also the one of the invalid authorization
+	balances := 0
+	for _, step := range tracer.steps {
+		if vm.OpCode(step.Op) != vm.BALANCE {
+			continue
+		}
+		balances++
+		if step.Cost != 100 {
+			t.Errorf("expected a warm BALANCE, got cost %d", step.Cost)
+		}
+	}
+	if balances != 3 {
+		t.Fatalf("expected 3 BALANCE steps, got %d", balances)
+	}
+
+	// Only the authorization of the existing account is refunded
+	result := s.Results[0]
+	if result.Failed() || result.Refund != vm.AuthorizationRefundGas {
+		t.Fatalf("expected refund %d, got %d (%v)", vm.AuthorizationRefundGas, result.Refund, result.Err)
+	}
+	if expected := uint64(21000 + 3*25000 + 3*(3+100+2) - 12500); result.GasUsed != expected {
+		t.Errorf("expected %d gas used, got %d", expected, result.GasUsed)
+	}
+
+	// A reverted transaction keeps the refund of its authorizations, capped at a fifth
+	result = s.Results[1]
+	if !result.Reverted || result.Refund != vm.AuthorizationRefundGas {
+		t.Fatalf("expected a reverted transaction with refund %d, got %d", vm.AuthorizationRefundGas, result.Refund)
+	}
+	used := uint64(21000 + 25000 + 2 + 2)
+	if expected := used - used/5; result.GasUsed != expected {
+		t.Errorf("expected %d gas used, got %d", expected, result.GasUsed)
+	}
+}
@@ -7,18 +7,33 @@ import (
 	"github.com/holiman/uint256"
 )
 
-type DifficultyOpCode struct{}
+// DifficultyOpCode implements 0x44, which returns PREVRANDAO since the merge (EIP-4399)
+// and the block difficulty before
+type DifficultyOpCode struct {
+	PreMerge bool
+}
 
-func (*DifficultyOpCode) Execute(v *vm.DebuggerVM) error {
+func (op *DifficultyOpCode) Execute(v *vm.DebuggerVM) error {
 	err := v.RequireContext()
 	if err != nil {
-		return fmt.Errorf("address op code requires the execution context to be set")
+		return fmt.Errorf("difficulty op code requires the execution context to be set")
+	}
+
+	block := v.Context.Block
+	if block == nil {
+		return v.Push(new(uint256.Int))
+	}
+
+	// After the merge prefer the RANDAO mix, falling back to Difficulty for callers
+	// that store the mix there
+	if !op.PreMerge && block.PrevRandao != nil {
+		return v.Push(block.PrevRandao)
 	}
 
 	// If the block difficulty is not set, return 0
-	if v.Context.Block == nil || v.Context.Block.Difficulty == nil {
+	if block.Difficulty == nil {
 		return v.Push(new(uint256.Int))
 	}
 
-	return v.Push(v.Context.Block.Difficulty)
+	return v.Push(block.Difficulty)
 }
@@ -0,0 +1,50 @@
+package opcode_handlers
+
+import (
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+func TestDifficultyOpCode_PrevRandao(t *testing.T) {
+	block := &vm.BlockContext{
+		Difficulty: uint256.NewInt(0x1234),
+		PrevRandao: uint256.NewInt(0xabcd),
+	}
+
+	tests := []struct {
+		fork     vm.Fork
+		expected uint64
+	}{
+		{vm.London, 0x1234},
+		{vm.Paris, 0xabcd},
+		{vm.Prague, 0xabcd},
+	}
+
+	for _, tt := range tests {
+		v := vm.NewDebuggerVM([]byte{vm.PREVRANDAO}, GetHandlerForFork(tt.fork))
+		v.Context = &vm.ExecutionContext{Block: block}
+
+		if err := v.Step(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.fork, err)
+		}
+		val, _ := v.Stack().Pop()
+		if val.Uint64() != tt.expected {
+			t.Errorf("%s: expected 0x%x, got %s", tt.fork, tt.expected, val.Hex())
+		}
+	}
+}
+
+func TestDifficultyOpCode_FallsBackToDifficulty(t *testing.T) {
+	v := vm.NewDebuggerVM([]byte{vm.DIFFICULTY}, GetHandler)
+	v.Context = &vm.ExecutionContext{Block: &vm.BlockContext{Difficulty: uint256.NewInt(7)}}
+
+	if err := v.Step(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	val, _ := v.Stack().Pop()
+	if val.Uint64() != 7 {
+		t.Errorf("expected 7, got %s", val.Hex())
+	}
+}
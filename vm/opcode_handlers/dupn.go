@@ -0,0 +1,33 @@
+package opcode_handlers
+
+import (
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+type DupNOpCode struct{}
+
+func (*DupNOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.DUPN); err != nil {
+		return err
+	}
+
+	// DUPN duplicates the stack item at depth imm, so DUPN 0 behaves like DUP1
+	imm, err := v.ReadCodeByte(0)
+	if err != nil {
+		return err
+	}
+	v.AdvancePC(1)
+
+	n := int(imm) + 1
+	if err := v.RequireStack(n); err != nil {
+		return err
+	}
+
+	val, err := v.Stack().Peek(n - 1)
+	if err != nil {
+		return err
+	}
+
+	return v.Stack().Push(new(uint256.Int).Set(val))
+}
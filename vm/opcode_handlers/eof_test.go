@@ -0,0 +1,237 @@
+package opcode_handlers
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// eofContainer encodes a container with a single non-returning code section
+func eofContainer(maxStackIncrease uint16, code []byte, containers [][]byte, data []byte) []byte {
+	types := []vm.EOFFunctionType{{Outputs: vm.EOFNonReturning, MaxStackIncrease: maxStackIncrease}}
+	return vm.EncodeEOF(types, [][]byte{code}, containers, data)
+}
+
+// runEOF executes an EOF container at address 0xaa until it stops
+func runEOF(t *testing.T, container []byte, sp vm.StateProvider) *vm.DebuggerVM {
+	t.Helper()
+
+	d := vm.NewDebuggerVM(container, GetHandler)
+	d.StateProvider = sp
+	d.Context = &vm.ExecutionContext{Address: [20]byte{19: 0xaa}, Value: uint256.NewInt(0)}
+	if err := d.EnableEOF(); err != nil {
+		t.Fatalf("EnableEOF error: %v", err)
+	}
+
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			t.Fatalf("execution error: %v", err)
+		}
+	}
+	return d
+}
+
+func TestParseEOFRejectsInvalidContainers(t *testing.T) {
+	valid := eofContainer(0, []byte{vm.STOP}, nil, []byte{0x01, 0x02})
+
+	tests := []struct {
+		name      string
+		container []byte
+	}{
+		{"legacy opcode", eofContainer(1, []byte{vm.PUSH0, vm.JUMP}, nil, nil)},
+		{"jump into immediate", eofContainer(1, []byte{vm.PUSH1, 0x00, vm.RJUMP, 0xff, 0xfc}, nil, nil)},
+		{"stack underflow", eofContainer(0, []byte{vm.ADD, vm.STOP}, nil, nil)},
+		{"missing terminating instruction", eofContainer(1, []byte{vm.PUSH0}, nil, nil)},
+		{"wrong max stack increase", eofContainer(0, []byte{vm.PUSH0, vm.POP, vm.STOP}, nil, nil)},
+		{"truncated data section", valid[:len(valid)-1]},
+		{"trailing bytes", append(append([]byte{}, valid...), 0x00)},
+	}
+
+	if _, err := vm.ParseEOF(valid, false); err != nil {
+		t.Fatalf("expected valid container, got %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := vm.ParseEOF(tt.container, false); !errors.Is(err, vm.ErrInvalidEOF) {
+				t.Fatalf("expected ErrInvalidEOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEOFFunctionsAndRelativeJumps(t *testing.T) {
+	// Section 0 calls section 1 to double 3, skips an INVALID with RJUMPI and returns the result
+	section0 := []byte{
+		vm.PUSH1, 0x03,
+		vm.CALLF, 0x00, 0x01,
+		vm.PUSH1, 0x01,
+		vm.RJUMPI, 0x00, 0x01,
+		vm.INVALID,
+		vm.PUSH0, vm.MSTORE,
+		vm.PUSH1, 0x20, vm.PUSH0, vm.RETURN,
+	}
+	section1 := []byte{vm.DUP1, vm.ADD, vm.RETF}
+
+	types := []vm.EOFFunctionType{
+		{Outputs: vm.EOFNonReturning, MaxStackIncrease: 2},
+		{Inputs: 1, Outputs: 1, MaxStackIncrease: 1},
+	}
+	container := vm.EncodeEOF(types, [][]byte{section0, section1}, nil, nil)
+
+	d := runEOF(t, container, nil)
+	if !bytes.Equal(d.ReturnValue, bytes32WithValue(uint256.NewInt(6))) {
+		t.Fatalf("expected 6, got %x", d.ReturnValue)
+	}
+}
+
+func TestEOFRJumpv(t *testing.T) {
+	// Case 1 of the jump table pushes 0x22, out of range cases fall through to 0x33
+	code := []byte{
+		vm.PUSH1, 0x01,
+		vm.RJUMPV, 0x01, 0x00, 0x07, 0x00, 0x0e, // PC 2, immediates end at 8
+		vm.PUSH1, 0x33, vm.PUSH0, vm.MSTORE8, vm.RJUMP, 0x00, 0x0b, // PC 8
+		vm.PUSH1, 0x11, vm.PUSH0, vm.MSTORE8, vm.RJUMP, 0x00, 0x04, // PC 15
+		vm.PUSH1, 0x22, vm.PUSH0, vm.MSTORE8, // PC 22
+		vm.PUSH1, 0x01, vm.PUSH0, vm.RETURN, // PC 26
+	}
+
+	for index, expected := range map[byte]byte{0x01: 0x22, 0x07: 0x33} {
+		code[1] = index
+		d := runEOF(t, eofContainer(2, code, nil, nil), nil)
+		if !bytes.Equal(d.ReturnValue, []byte{expected}) {
+			t.Fatalf("case %d: expected %x, got %x", index, expected, d.ReturnValue)
+		}
+	}
+}
+
+func TestEOFStackInstructions(t *testing.T) {
+	code := []byte{
+		vm.PUSH1, 0x01, vm.PUSH1, 0x02, vm.PUSH1, 0x03,
+		vm.DUPN, 0x02, // 1 2 3 1
+		vm.SWAPN, 0x00, // 1 2 1 3
+		vm.EXCHANGE, 0x00, // 1 1 2 3
+		vm.STOP,
+	}
+
+	d := runEOF(t, eofContainer(4, code, nil, nil), nil)
+	for i, expected := range []uint64{3, 2, 1, 1} {
+		val, err := d.Stack().Peek(i)
+		if err != nil {
+			t.Fatalf("stack error: %v", err)
+		}
+		if val.Uint64() != expected {
+			t.Fatalf("expected %d at depth %d, got %d", expected, i, val.Uint64())
+		}
+	}
+}
+
+func TestEOFDataInstructions(t *testing.T) {
+	data := bytes.Repeat([]byte{0x11}, 33)
+	code := []byte{
+		vm.DATALOADN, 0x00, 0x01, vm.PUSH0, vm.MSTORE,
+		vm.DATASIZE, vm.PUSH1, 0x20, vm.MSTORE,
+		vm.PUSH1, 0x40, vm.PUSH0, vm.RETURN,
+	}
+
+	d := runEOF(t, eofContainer(2, code, nil, data), nil)
+	expected := append(data[1:], bytes32WithValue(uint256.NewInt(33))...)
+	if !bytes.Equal(d.ReturnValue, expected) {
+		t.Fatalf("expected %x, got %x", expected, d.ReturnValue)
+	}
+}
+
+func TestEOFCreateDeploysContainerWithAuxData(t *testing.T) {
+	// The runtime container declares 2 data bytes but only carries one, RETURNCONTRACT
+	// appends the second
+	deployed := eofContainer(0, []byte{vm.STOP}, nil, []byte{0xaa, 0xbb})
+	runtime := deployed[:len(deployed)-1]
+
+	initcode := eofContainer(2, []byte{
+		vm.PUSH1, 0xbb, vm.PUSH0, vm.MSTORE8,
+		vm.PUSH1, 0x01, vm.PUSH0, vm.RETURNCONTRACT, 0x00,
+	}, [][]byte{runtime}, nil)
+
+	factory := eofContainer(4, []byte{
+		vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.EOFCREATE, 0x00,
+		vm.PUSH0, vm.MSTORE,
+		vm.PUSH1, 0x20, vm.PUSH0, vm.RETURN,
+	}, [][]byte{initcode}, nil)
+
+	sp := state.NewMemoryState()
+	factoryAddr := [20]byte{19: 0xaa}
+	sp.AddAccount(factoryAddr, factory, uint256.NewInt(0))
+
+	d := runEOF(t, factory, sp)
+
+	var senderWord [32]byte
+	copy(senderWord[12:], factoryAddr[:])
+	var expected [20]byte
+	copy(expected[:], keccak256([]byte{0xff}, senderWord[:], make([]byte, 32))[12:])
+
+	if !bytes.Equal(d.ReturnValue[12:], expected[:]) {
+		t.Fatalf("expected address %x, got %x", expected, d.ReturnValue)
+	}
+	if !bytes.Equal(sp.GetCode(expected), deployed) {
+		t.Fatalf("expected deployed code %x, got %x", deployed, sp.GetCode(expected))
+	}
+	if got := sp.GetNonce(expected); got != 1 {
+		t.Fatalf("expected nonce 1 for the new contract, got %d", got)
+	}
+}
+
+func TestEOFExtCallToLegacyContract(t *testing.T) {
+	sp := state.NewMemoryState()
+	callee := [20]byte{19: 0xbb}
+
+	// MSTORE(0, 0x2a) then REVERT(0, 32)
+	sp.AddAccount(callee, []byte{
+		vm.PUSH1, 0x2a, vm.PUSH0, vm.MSTORE,
+		vm.PUSH1, 0x20, vm.PUSH0, vm.REVERT,
+	}, uint256.NewInt(0))
+
+	code := []byte{
+		vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0xbb, vm.EXTCALL,
+		vm.PUSH0, vm.MSTORE,
+		vm.PUSH0, vm.RETURNDATALOAD, vm.PUSH1, 0x20, vm.MSTORE,
+		vm.PUSH1, 0x40, vm.PUSH0, vm.RETURN,
+	}
+
+	d := runEOF(t, eofContainer(4, code, nil, nil), sp)
+	expected := append(bytes32WithValue(uint256.NewInt(1)), bytes32WithValue(uint256.NewInt(0x2a))...)
+	if !bytes.Equal(d.ReturnValue, expected) {
+		t.Fatalf("expected revert status and data %x, got %x", expected, d.ReturnValue)
+	}
+}
+
+func TestEOFCodeIsHiddenFromLegacyCode(t *testing.T) {
+	sp := state.NewMemoryState()
+	target := [20]byte{19: 0xbb}
+	sp.AddAccount(target, eofContainer(0, []byte{vm.STOP}, nil, nil), uint256.NewInt(0))
+
+	d := vm.NewDebuggerVM([]byte{vm.PUSH1, 0xbb, vm.EXTCODESIZE}, GetHandler)
+	d.StateProvider = sp
+	if err := d.EnableEOF(); err != nil {
+		t.Fatalf("EnableEOF error: %v", err)
+	}
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			t.Fatalf("execution error: %v", err)
+		}
+	}
+
+	size, _ := d.Stack().Pop()
+	if size.Uint64() != 2 {
+		t.Fatalf("expected EXTCODESIZE 2 for an EOF contract, got %d", size.Uint64())
+	}
+}
+
+func TestEOFInstructionsUndefinedInLegacyCode(t *testing.T) {
+	d := vm.NewDebuggerVM([]byte{vm.RJUMP, 0x00, 0x00}, GetHandler)
+	if err := d.Step(); err == nil {
+		t.Fatal("expected RJUMP to be undefined in legacy code")
+	}
+}
@@ -0,0 +1,155 @@
+package opcode_handlers
+
+import (
+	"fmt"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+type EOFCreateOpCode struct{}
+
+func (*EOFCreateOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.EOFCREATE); err != nil {
+		return err
+	}
+
+	err := v.RequireContext()
+	if err != nil {
+		return fmt.Errorf("eofcreate op code requires the execution context to be set")
+	}
+
+	if v.StateProvider == nil {
+		return fmt.Errorf("eofcreate op code requires state provider to be set")
+	}
+
+	// EOFCREATE requires four values on the stack (value, salt, inputOffset, inputSize)
+	if err := v.RequireStack(4); err != nil {
+		return err
+	}
+
+	// The immediate selects the initcode subcontainer
+	imm, err := v.ReadCodeByte(0)
+	if err != nil {
+		return err
+	}
+	v.AdvancePC(1)
+
+	value, err := v.Stack().Pop()
+	if err != nil {
+		return err
+	}
+	salt, inputOffset, inputSize, err := v.Pop3()
+	if err != nil {
+		return err
+	}
+
+	// Check for static call context - EOFCREATE not allowed in static calls
+	frame := v.CurrentFrame()
+	if frame.IsStatic {
+		return vm.ErrStaticCallStateChange
+	}
+
+	initContainer := frame.EOF.SubContainers[imm]
+	input := v.Memory().Read(int(inputOffset.Uint64()), int(inputSize.Uint64()))
+	v.ClearReturnData()
+
+	sp := v.StateProvider
+	sender := v.Context.Address
+
+	// The initcode runs with all but 1/64th of the remaining gas
+	gas := v.ForwardedGas(nil, nil)
+
+	// A value exceeding the balance fails without touching the nonce
+	if sp.GetBalance(sender).Cmp(value) < 0 {
+		v.ReturnGas(gas, gas, nil)
+		return v.Push(uint256.NewInt(0))
+	}
+
+	// The address is keccak256(0xff || sender32 || salt)[12:]
+	var senderWord, saltBytes [32]byte
+	copy(senderWord[12:], sender[:])
+	salt.WriteToSlice(saltBytes[:])
+	var newAddr [20]byte
+	copy(newAddr[:], keccak256([]byte{0xff}, senderWord[:], saltBytes[:])[12:])
+
+	nonce := sp.GetNonce(sender)
+	v.SetNonce(sender, nonce+1)
+
+	// Check if the address is already in use
+	if sp.AccountExists(newAddr) && (sp.GetNonce(newAddr) != 0 || len(sp.GetCode(newAddr)) != 0) {
+		v.ReturnGas(gas, gas, nil)
+		return v.Push(uint256.NewInt(0))
+	}
+
+	// Changes of a failing initcode are rolled back if the state provider supports it
+	snap := snapshot(v)
+
+	if err := v.CreateAccount(newAddr, nil, sp.GetBalance(newAddr)); err != nil {
+		v.ReturnGas(gas, gas, nil)
+		endSnapshot(v, snap, true)
+		return v.Push(uint256.NewInt(0))
+	}
+	v.SetNonce(newAddr, 1) // EIP-161: contracts start with nonce 1
+	v.MarkAccountCreatedInTransaction(newAddr)
+
+	// Transfer value from the creator to the new contract
+	if !value.IsZero() {
+		v.SetBalance(sender, new(uint256.Int).Sub(sp.GetBalance(sender), value))
+		v.SetBalance(newAddr, new(uint256.Int).Add(sp.GetBalance(newAddr), value))
+	}
+
+	// Run the initcode container, the input is passed as call data
+	oldContext := v.Context
+	newFrame := vm.MessageFrame{
+		Stack:    vm.NewStack(),
+		Memory:   vm.NewMemory(),
+		Gas:      gas,
+		CallType: vm.CallTypeCreate,
+	}
+	newFrame.SetEOF(initContainer)
+
+	newContext := &vm.ExecutionContext{
+		Caller:   oldContext.Address,
+		Address:  newAddr,
+		Origin:   oldContext.Origin,
+		Value:    new(uint256.Int).Set(value),
+		CallData: input,
+		GasPrice: oldContext.GasPrice,
+		Gas:      gas,
+		Balance:  sp.GetBalance(newAddr),
+		Block:    oldContext.Block,
+	}
+
+	callFrame := v.EnterFrame(vm.CallTypeCreate, newAddr, input, value, gas)
+	if err := v.PushFrame(newFrame); err != nil {
+		v.ExitFrame(callFrame, nil, err)
+		endSnapshot(v, snap, true)
+		return err
+	}
+	v.Context = newContext
+
+	// RETURNCONTRACT hands the deployed container over as the frame's return data
+	execErr := v.ExecuteCall()
+	initFrame := v.CurrentFrame()
+	deployed := initFrame.ReturnData
+	if execErr == nil {
+		// Return data is only kept for a failing initcode
+		initFrame.ReturnData = nil
+	}
+	v.ExitFrame(callFrame, deployed, execErr)
+
+	v.Context = oldContext
+	v.ReturnGas(gas, newContext.Gas, execErr)
+	if err := v.PopFrame(); err != nil {
+		return err
+	}
+
+	endSnapshot(v, snap, execErr != nil)
+	if execErr != nil {
+		return v.Push(uint256.NewInt(0))
+	}
+
+	v.SetCode(newAddr, deployed)
+	return v.Push(new(uint256.Int).SetBytes(newAddr[:]))
+}
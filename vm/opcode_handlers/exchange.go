@@ -0,0 +1,27 @@
+package opcode_handlers
+
+import "github.com/daniellehrner/evmdbg/vm"
+
+type ExchangeOpCode struct{}
+
+func (*ExchangeOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.EXCHANGE); err != nil {
+		return err
+	}
+
+	// The immediate encodes n-1 in the high and m-1 in the low nibble. The items at
+	// depth n and n+m are swapped, where the top of the stack has depth 0.
+	imm, err := v.ReadCodeByte(0)
+	if err != nil {
+		return err
+	}
+	v.AdvancePC(1)
+
+	n := int(imm>>4) + 1
+	m := int(imm&0x0f) + 1
+	if err := v.RequireStack(n + m + 1); err != nil {
+		return err
+	}
+
+	return v.Stack().Exchange(n, m)
+}
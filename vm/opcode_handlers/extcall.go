@@ -0,0 +1,208 @@
+package opcode_handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// Status codes pushed by EXTCALL, EXTDELEGATECALL and EXTSTATICCALL (EIP-7069)
+const (
+	extCallSuccess = 0
+	extCallRevert  = 1
+	extCallFailure = 2
+)
+
+type ExtCallOpCode struct{}
+
+func (*ExtCallOpCode) Execute(v *vm.DebuggerVM) error {
+	return extCall(v, vm.EXTCALL, vm.CallTypeCall)
+}
+
+type ExtDelegateCallOpCode struct{}
+
+func (*ExtDelegateCallOpCode) Execute(v *vm.DebuggerVM) error {
+	return extCall(v, vm.EXTDELEGATECALL, vm.CallTypeDelegateCall)
+}
+
+type ExtStaticCallOpCode struct{}
+
+func (*ExtStaticCallOpCode) Execute(v *vm.DebuggerVM) error {
+	return extCall(v, vm.EXTSTATICCALL, vm.CallTypeStaticCall)
+}
+
+// extCall implements the EOF call instructions. They take no gas and no output area and
+// push a status code instead of a success flag.
+func extCall(v *vm.DebuggerVM, op byte, callType vm.CallType) error {
+	if err := v.RequireEOF(op); err != nil {
+		return err
+	}
+
+	err := v.RequireContext()
+	if err != nil {
+		return fmt.Errorf("ext call op codes require the execution context to be set")
+	}
+
+	// EXTCALL requires 4 values on the stack (address, inputOffset, inputSize, value),
+	// EXTDELEGATECALL and EXTSTATICCALL have no value
+	n := 3
+	if callType == vm.CallTypeCall {
+		n = 4
+	}
+	if err := v.RequireStack(n); err != nil {
+		return err
+	}
+
+	address, inputOffset, inputSize, err := v.Pop3()
+	if err != nil {
+		return err
+	}
+	value := uint256.NewInt(0)
+	var callValue *uint256.Int // only EXTCALL carries value
+	if callType == vm.CallTypeCall {
+		if value, err = v.Stack().Pop(); err != nil {
+			return err
+		}
+		callValue = value
+	}
+
+	// The address must not have any of its high 12 bytes set
+	addrBytes := address.Bytes32()
+	for _, b := range addrBytes[:12] {
+		if b != 0 {
+			return fmt.Errorf("invalid address for 0x%x: 0x%x", op, addrBytes)
+		}
+	}
+	var addr [20]byte
+	copy(addr[:], addrBytes[12:])
+
+	frame := v.CurrentFrame()
+	if frame.IsStatic && !value.IsZero() {
+		return vm.ErrStaticCallStateChange
+	}
+
+	input := v.Memory().Read(int(inputOffset.Uint64()), int(inputSize.Uint64()))
+	v.ClearReturnData()
+
+	// EXT*CALL forwards all but 1/64th of the remaining gas (EIP-150), without a stipend
+	gas := v.ForwardedGas(nil, nil)
+
+	// For now, if no StateProvider is set, return success but do nothing
+	sp := v.StateProvider
+	if sp == nil {
+		if p, ok := v.Precompile(addr); ok {
+			return v.PushUint64(extCallPrecompile(v, addr, p, callType, input, callValue, gas))
+		}
+		v.ReturnGas(gas, gas, nil)
+		return v.PushUint64(extCallSuccess)
+	}
+
+	// A value exceeding the balance and the call depth limit are light failures
+	if sp.GetBalance(v.Context.Address).Cmp(value) < 0 || v.CallDepth() >= 1024 {
+		v.ReturnGas(gas, gas, nil)
+		return v.PushUint64(extCallRevert)
+	}
+
+	// Get the target code, following an EIP-7702 delegation
+	targetCode := v.ResolveCode(addr)
+	if callType == vm.CallTypeDelegateCall && !vm.HasEOFMagic(targetCode) {
+		// Legacy code cannot be executed in the context of EOF code
+		v.ReturnGas(gas, gas, nil)
+		return v.PushUint64(extCallRevert)
+	}
+
+	// Changes of a failing call are rolled back if the state provider supports it
+	snap := snapshot(v)
+
+	// Transfer value to the target
+	if !value.IsZero() {
+		v.SetBalance(v.Context.Address, new(uint256.Int).Sub(sp.GetBalance(v.Context.Address), value))
+		v.SetBalance(addr, new(uint256.Int).Add(sp.GetBalance(addr), value))
+	}
+
+	// Precompiled contracts run natively
+	if p, ok := v.Precompile(addr); ok {
+		status := extCallPrecompile(v, addr, p, callType, input, callValue, gas)
+		endSnapshot(v, snap, status != extCallSuccess)
+		return v.PushUint64(status)
+	}
+
+	if len(targetCode) == 0 {
+		// Empty code means successful call with no execution
+		v.ExitFrame(v.EnterFrame(callType, addr, input, callValue, gas), nil, nil)
+		v.ReturnGas(gas, gas, nil)
+		endSnapshot(v, snap, false)
+		return v.PushUint64(extCallSuccess)
+	}
+
+	oldContext := v.Context
+	newContext := &vm.ExecutionContext{
+		Caller:   oldContext.Address,
+		Address:  addr,
+		Origin:   oldContext.Origin,
+		Value:    new(uint256.Int).Set(value),
+		CallData: input,
+		GasPrice: oldContext.GasPrice,
+		Gas:      gas,
+		Block:    oldContext.Block,
+	}
+	if callType == vm.CallTypeDelegateCall {
+		// The delegate runs with the caller, address and value of the current context
+		newContext.Caller = oldContext.Caller
+		newContext.Address = oldContext.Address
+		newContext.Value = oldContext.Value
+	}
+	newContext.Balance = sp.GetBalance(newContext.Address)
+
+	newFrame := vm.MessageFrame{
+		Code:         targetCode,
+		Stack:        vm.NewStack(),
+		Memory:       vm.NewMemory(),
+		Gas:          gas,
+		CallType:     callType,
+		IsStatic:     frame.IsStatic || callType == vm.CallTypeStaticCall,
+		CodeMetadata: vm.ScanCodeMetadata(targetCode),
+	}
+
+	status := uint64(extCallSuccess)
+	callFrame := v.EnterFrame(callType, addr, input, callValue, gas)
+	if err := v.PushFrame(newFrame); err != nil {
+		// Code failing EOF validation cannot be executed
+		status = extCallFailure
+		v.ExitFrame(callFrame, nil, err)
+		v.ReturnGas(gas, 0, err)
+	} else {
+		v.Context = newContext
+
+		err := v.ExecuteCall()
+		switch {
+		case errors.Is(err, vm.ErrExecutionReverted):
+			status = extCallRevert
+		case err != nil:
+			status = extCallFailure
+		}
+		v.ExitFrame(callFrame, v.CurrentFrame().ReturnData, err)
+
+		v.Context = oldContext
+		v.ReturnGas(gas, newContext.Gas, err)
+		if popErr := v.PopFrame(); popErr != nil {
+			return popErr
+		}
+	}
+
+	endSnapshot(v, snap, status != extCallSuccess)
+	return v.PushUint64(status)
+}
+
+// extCallPrecompile runs a precompiled contract with the forwarded gas and returns the
+// status code of the call
+func extCallPrecompile(v *vm.DebuggerVM, addr [20]byte, p vm.PrecompiledContract, callType vm.CallType, input []byte, value *uint256.Int, gas uint64) uint64 {
+	// The forwarded gas goes back to the caller, which is charged what the precompile uses
+	v.ReturnGas(gas, gas, nil)
+	if _, err := v.RunPrecompile(addr, p, callType, input, value, gas); err != nil {
+		return extCallFailure
+	}
+	return extCallSuccess
+}
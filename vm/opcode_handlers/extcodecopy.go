@@ -48,8 +48,9 @@ func (*ExtCodeCopyOpCode) Execute(v *vm.DebuggerVM) error {
 
 	var code []byte
 	if v.StateProvider != nil {
-		// Get code from state provider
-		code = v.StateProvider.GetCode(addr)
+		// Get code from state provider. Delegated accounts (EIP-7702) expose their designator,
+		// EOF contracts only their magic.
+		code = v.ExternalCode(addr)
 	} else {
 		// If no state provider, treat as empty code
 		code = []byte{}
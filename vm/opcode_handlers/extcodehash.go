@@ -34,8 +34,9 @@ func (*ExtCodeHashOpCode) Execute(v *vm.DebuggerVM) error {
 	var codeHash *uint256.Int
 
 	if v.StateProvider != nil {
-		// Get code from state provider
-		code := v.StateProvider.GetCode(addr)
+		// Get code from state provider. Delegated accounts (EIP-7702) expose their designator,
+		// EOF contracts only their magic.
+		code := v.ExternalCode(addr)
 
 		if len(code) == 0 && !v.StateProvider.AccountExists(addr) {
 			// Non-existent account returns 0
@@ -32,8 +32,9 @@ func (*ExtCodeSizeOpCode) Execute(v *vm.DebuggerVM) error {
 
 	var codeSize *uint256.Int
 	if v.StateProvider != nil {
-		// Get code from state provider
-		code := v.StateProvider.GetCode(addr)
+		// Get code from state provider. Delegated accounts (EIP-7702) expose their designator,
+		// EOF contracts only their magic.
+		code := v.ExternalCode(addr)
 		codeSize = uint256.NewInt(uint64(len(code)))
 	} else {
 		// If no state provider, return 0 (account doesn't exist)
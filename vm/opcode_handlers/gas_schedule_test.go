@@ -0,0 +1,491 @@
+package opcode_handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// runMetered executes code at address 0xaa with the given schedule and gas limit and
+// returns the VM and the first execution error
+func runMetered(code []byte, schedule *vm.GasSchedule, gas uint64, sp vm.StateProvider) (*vm.DebuggerVM, error) {
+	d := vm.NewDebuggerVM(code, GetHandler)
+	d.StateProvider = sp
+	d.GasSchedule = schedule
+	d.Context = &vm.ExecutionContext{Address: [20]byte{19: 0xaa}, Value: uint256.NewInt(0), Gas: gas}
+
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			return d, err
+		}
+	}
+	return d, nil
+}
+
+func TestBuiltinGasSchedules(t *testing.T) {
+	tests := []struct {
+		schedule *vm.GasSchedule
+		op       vm.OpCode
+		cost     uint64
+	}{
+		{vm.FrontierGasSchedule, vm.SLOAD, 50},
+		{vm.FrontierGasSchedule, vm.LOG2, 1125},
+		{vm.TangerineWhistleGasSchedule, vm.CALL, 700},
+		{vm.TangerineWhistleGasSchedule, vm.STATICCALL, 0},
+		{vm.ByzantiumGasSchedule, vm.RETURNDATASIZE, 2},
+		{vm.ByzantiumGasSchedule, vm.STATICCALL, 700},
+		{vm.ByzantiumGasSchedule, vm.SHL, 0},
+		{vm.ConstantinopleGasSchedule, vm.SHR, 3},
+		{vm.ConstantinopleGasSchedule, vm.CREATE2, 32000},
+		{vm.ConstantinopleGasSchedule, vm.EXTCODEHASH, 400},
+		{vm.ConstantinopleGasSchedule, vm.SLOAD, 200},
+		{vm.IstanbulGasSchedule, vm.EXTCODEHASH, 700},
+		{vm.IstanbulGasSchedule, vm.SLOAD, 800},
+		{vm.IstanbulGasSchedule, vm.BALANCE, 700},
+		{vm.BerlinGasSchedule, vm.SLOAD, 100},
+		{vm.LondonGasSchedule, vm.BASEFEE, 2},
+		{vm.LondonGasSchedule, vm.ADD, 3},
+		{vm.LondonGasSchedule, vm.PUSH0, 2},
+	}
+
+	for _, tt := range tests {
+		if got := tt.schedule.StaticCost(byte(tt.op)); got != tt.cost {
+			t.Errorf("%s %s: expected %d, got %d", tt.schedule.Name, tt.op, tt.cost, got)
+		}
+	}
+	for fork, schedule := range map[vm.Fork]*vm.GasSchedule{
+		vm.SpuriousDragon: vm.TangerineWhistleGasSchedule,
+		vm.Byzantium:      vm.ByzantiumGasSchedule,
+		vm.Petersburg:     vm.ConstantinopleGasSchedule,
+		vm.Paris:          vm.LondonGasSchedule,
+		vm.Cancun:         vm.ShanghaiGasSchedule,
+	} {
+		if got := vm.GasScheduleForFork(fork); got != schedule {
+			t.Errorf("expected the %s schedule for %s, got %s", schedule.Name, fork, got.Name)
+		}
+	}
+	if vm.FrontierGasSchedule.StaticCost(vm.BASEFEE) != 0 {
+		t.Error("expected BASEFEE to be free before London")
+	}
+}
+
+func TestParseGasSchedule(t *testing.T) {
+	s, err := vm.ParseGasSchedule([]byte(`{"name":"cheap","extends":"Berlin","static":{"SLOAD":50},"coldSload":1000}`))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if s.Name != "cheap" || s.StaticCost(vm.SLOAD) != 50 || s.ColdSload != 1000 {
+		t.Fatalf("overrides not applied: %+v", s)
+	}
+	if s.StaticCost(vm.ADD) != 3 || s.ColdAccountAccess != 2600 {
+		t.Fatal("expected unset values to be inherited")
+	}
+	if vm.BerlinGasSchedule.StaticCost(vm.SLOAD) != 100 {
+		t.Fatal("parsing modified the built-in schedule")
+	}
+
+	if _, err := vm.ParseGasSchedule([]byte(`{"extends":"london","static":{"NOPE":1}}`)); err == nil {
+		t.Fatal("expected an error for an unknown opcode")
+	}
+	if _, err := vm.ParseGasSchedule([]byte(`{"extends":"homestead"}`)); err == nil {
+		t.Fatal("expected an error for an unknown parent schedule")
+	}
+}
+
+func TestGasMetering(t *testing.T) {
+	// 3 + 3 + 3 for the addition, MSTORE costs 3 plus 3 for the first memory word
+	code := []byte{vm.PUSH1, 0x01, vm.PUSH1, 0x02, vm.ADD, vm.PUSH0, vm.MSTORE, vm.STOP}
+
+	d, err := runMetered(code, vm.LondonGasSchedule, 100, nil)
+	if err != nil {
+		t.Fatalf("execution error: %v", err)
+	}
+	if d.GasUsed() != 17 {
+		t.Fatalf("expected 17 gas used, got %d", d.GasUsed())
+	}
+	if d.Context.Gas != 83 {
+		t.Fatalf("expected 83 gas left, got %d", d.Context.Gas)
+	}
+
+	if _, err := runMetered(code, vm.LondonGasSchedule, 16, nil); !errors.Is(err, vm.ErrOutOfGas) {
+		t.Fatalf("expected ErrOutOfGas, got %v", err)
+	}
+}
+
+func TestGasMeteringColdAndWarmSload(t *testing.T) {
+	code := []byte{vm.PUSH0, vm.SLOAD, vm.PUSH0, vm.SLOAD, vm.STOP}
+
+	d, err := runMetered(code, vm.LondonGasSchedule, 10000, state.NewMemoryState())
+	if err != nil {
+		t.Fatalf("execution error: %v", err)
+	}
+	// Two PUSH0, a cold SLOAD and a warm SLOAD
+	if expected := uint64(2 + 2100 + 2 + 100); d.GasUsed() != expected {
+		t.Fatalf("expected %d gas used, got %d", expected, d.GasUsed())
+	}
+}
+
+func TestGasMeteringNetSstore(t *testing.T) {
+	// Set slot 0 from 1 to 2 and back to 1
+	code := []byte{vm.PUSH1, 0x02, vm.PUSH1, 0x00, vm.SSTORE, vm.PUSH1, 0x01, vm.PUSH1, 0x00, vm.SSTORE, vm.STOP}
+
+	sp := state.NewMemoryState()
+	addr := [20]byte{19: 0xaa}
+	sp.AddAccount(addr, code, uint256.NewInt(0))
+	sp.SetStorage(addr, uint256.NewInt(0), uint256.NewInt(1))
+
+	d, err := runMetered(code, vm.IstanbulGasSchedule, 100000, sp)
+	if err != nil {
+		t.Fatalf("execution error: %v", err)
+	}
+	// The first write resets a clean slot, the second one restores the original value
+	if expected := uint64(3 + 3 + 5000 + 3 + 3 + 800); d.GasUsed() != expected {
+		t.Fatalf("expected %d gas used, got %d", expected, d.GasUsed())
+	}
+	if d.Refund() != 5000-800 {
+		t.Fatalf("expected refund %d, got %d", 5000-800, d.Refund())
+	}
+
+	// Net metering fails if no more than the sentry is left
+	if _, err := runMetered(code, vm.IstanbulGasSchedule, 2306, sp); !errors.Is(err, vm.ErrOutOfGas) {
+		t.Fatalf("expected ErrOutOfGas below the sentry, got %v", err)
+	}
+}
+
+func TestGasMeteringColdSstore(t *testing.T) {
+	// Set the empty slot 0 to 1 and slot 1 from 1 to 2, both slots are cold
+	code := []byte{vm.PUSH1, 0x01, vm.PUSH1, 0x00, vm.SSTORE, vm.PUSH1, 0x02, vm.PUSH1, 0x01, vm.SSTORE, vm.STOP}
+
+	for _, schedule := range []*vm.GasSchedule{vm.BerlinGasSchedule, vm.LondonGasSchedule} {
+		sp := state.NewMemoryState()
+		addr := [20]byte{19: 0xaa}
+		sp.AddAccount(addr, code, uint256.NewInt(0))
+		sp.SetStorage(addr, uint256.NewInt(1), uint256.NewInt(1))
+
+		d, err := runMetered(code, schedule, 100000, sp)
+		if err != nil {
+			t.Fatalf("%s: execution error: %v", schedule.Name, err)
+		}
+		// A cold set costs 2100 + 20000 and a cold reset 2100 + 2900
+		if expected := uint64(3 + 3 + 22100 + 3 + 3 + 5000); d.GasUsed() != expected {
+			t.Errorf("%s: expected %d gas used, got %d", schedule.Name, expected, d.GasUsed())
+		}
+	}
+}
+
+func TestGasMeteringColdSelfDestruct(t *testing.T) {
+	code := []byte{vm.PUSH1, 0xbb, vm.SELFDESTRUCT}
+
+	sp := state.NewMemoryState()
+	sp.AddAccount([20]byte{19: 0xaa}, code, uint256.NewInt(0))
+	sp.AddAccount([20]byte{19: 0xbb}, nil, uint256.NewInt(0))
+
+	d, err := runMetered(code, vm.LondonGasSchedule, 100000, sp)
+	if err != nil {
+		t.Fatalf("execution error: %v", err)
+	}
+	// PUSH1, SELFDESTRUCT and the full cold access of the beneficiary
+	if expected := uint64(3 + 5000 + 2600); d.GasUsed() != expected {
+		t.Fatalf("expected %d gas used, got %d", expected, d.GasUsed())
+	}
+}
+
+func TestGasMeteringNewAccount(t *testing.T) {
+	// CALL 0xbb with no gas and the given value
+	callCode := func(value byte) []byte {
+		return []byte{
+			vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00,
+			vm.PUSH1, value, vm.PUSH1, 0xbb, vm.PUSH1, 0x00, vm.CALL, vm.STOP,
+		}
+	}
+	// run returns the gas used by code under fork with 0xbb in the given state
+	run := func(fork vm.Fork, schedule *vm.GasSchedule, code []byte, target *uint256.Int) uint64 {
+		sp := state.NewMemoryState()
+		sp.AddAccount([20]byte{19: 0xaa}, code, uint256.NewInt(10))
+		if target != nil {
+			sp.AddAccount([20]byte{19: 0xbb}, nil, target)
+		}
+		d := vm.NewDebuggerVM(code, GetHandler)
+		d.StateProvider = sp
+		d.GasSchedule = schedule
+		d.ChainConfig = vm.ChainConfigForFork(fork)
+		d.Context = &vm.ExecutionContext{Address: [20]byte{19: 0xaa}, Value: uint256.NewInt(0), Gas: 100000}
+		for !d.Stopped {
+			if err := d.Step(); err != nil {
+				t.Fatalf("%s: execution error: %v", fork, err)
+			}
+		}
+		return d.GasUsed()
+	}
+	empty, funded := uint256.NewInt(0), uint256.NewInt(1)
+
+	// Since EIP-161 sending value to an existing but empty account creates it
+	if diff := run(vm.London, vm.LondonGasSchedule, callCode(1), empty) - run(vm.London, vm.LondonGasSchedule, callCode(1), funded); diff != 25000 {
+		t.Errorf("expected a CALL with value to an empty account to cost 25000 more, got %d", diff)
+	}
+	// Before, any call to an account that does not exist creates it
+	schedule := vm.TangerineWhistleGasSchedule
+	if diff := run(vm.TangerineWhistle, schedule, callCode(0), nil) - run(vm.TangerineWhistle, schedule, callCode(0), funded); diff != 25000 {
+		t.Errorf("expected a CALL to a missing account to cost 25000 more before Spurious Dragon, got %d", diff)
+	}
+	if diff := run(vm.SpuriousDragon, schedule, callCode(0), nil) - run(vm.SpuriousDragon, schedule, callCode(0), funded); diff != 0 {
+		t.Errorf("expected a CALL without value to a missing account to cost the same since Spurious Dragon, got %d", diff)
+	}
+
+	// SELFDESTRUCT sending a balance to an existing but empty account
+	code := []byte{vm.PUSH1, 0xbb, vm.SELFDESTRUCT}
+	if used := run(vm.London, vm.LondonGasSchedule, code, empty); used != 3+5000+2600+25000 {
+		t.Errorf("expected SELFDESTRUCT to an empty beneficiary to use %d gas, got %d", 3+5000+2600+25000, used)
+	}
+}
+
+func TestIntrinsicGas(t *testing.T) {
+	to := [20]byte{0xbb}
+	initCode := make([]byte, 33)
+	accessList := []vm.AccessTuple{{Address: to, StorageKeys: [][32]byte{{}, {31: 1}}}, {Address: [20]byte{0xcc}}}
+	auths := []vm.SetCodeAuthorization{{}, {}}
+
+	tests := []struct {
+		name     string
+		schedule *vm.GasSchedule
+		tx       vm.Transaction
+		gas      uint64
+	}{
+		{"create", vm.LondonGasSchedule, vm.Transaction{Data: initCode}, 53000 + 33*4},
+		// Two words of initcode since Shanghai
+		{"create with initcode words", vm.ShanghaiGasSchedule, vm.Transaction{Data: initCode}, 53000 + 33*4 + 2*2},
+		{"access list", vm.BerlinGasSchedule, vm.Transaction{To: &to, AccessList: accessList}, 21000 + 2*2400 + 2*1900},
+		{"authorizations", vm.ShanghaiGasSchedule, vm.Transaction{To: &to, AuthorizationList: auths}, 21000 + 2*25000},
+	}
+	for _, tt := range tests {
+		if got := tt.schedule.IntrinsicGas(&tt.tx); got != tt.gas {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.gas, got)
+		}
+	}
+}
+
+func TestSessionAccessList(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	sp.AddAccount(target, []byte{vm.PUSH1, 0x01, vm.SLOAD, vm.STOP}, uint256.NewInt(0))
+
+	tx := vm.Transaction{From: sender, To: &target, Gas: 30000, AccessList: []vm.AccessTuple{{Address: target, StorageKeys: [][32]byte{{31: 1}}}}}
+	s := vm.NewSession(sp, nil, GetHandler, []vm.Transaction{tx})
+	s.VM.ChainConfig = vm.ChainConfigForFork(vm.London)
+	s.VM.GasSchedule = vm.LondonGasSchedule
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	// The listed slot is loaded warm
+	if expected := uint64(21000 + 2400 + 1900 + 3 + 100); s.Results[0].GasUsed != expected {
+		t.Fatalf("expected %d gas used, got %d (%v)", expected, s.Results[0].GasUsed, s.Results[0].Err)
+	}
+
+	s = vm.NewSession(sp, nil, GetHandler, []vm.Transaction{tx})
+	s.VM.ChainConfig = vm.ChainConfigForFork(vm.Istanbul)
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	if !errors.Is(s.Results[0].Err, vm.ErrAccessListNotActive) {
+		t.Fatalf("expected ErrAccessListNotActive, got %v", s.Results[0].Err)
+	}
+}
+
+func TestGasMeteringCallForwardsGas(t *testing.T) {
+	caller := [20]byte{19: 0xaa}
+	tests := []struct {
+		name     string
+		code     []byte
+		schedule *vm.GasSchedule
+		gasUsed  uint64
+	}{
+		// All but one 64th of the 88384 gas left after the pushes and the cold CALL with value
+		// is forwarded, the callee gets the stipend on top and returns all but 5 gas
+		{"capped with stipend", []byte{vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0x01, vm.PUSH1, 0xbb, vm.GAS, vm.CALL, vm.STOP},
+			vm.LondonGasSchedule, 16 + 11600 + 87003 + 5 - (87003 + 2300)},
+		// A callee halting exceptionally consumes the 1000 gas it was given
+		{"exceptional halt", []byte{vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0xcc, vm.PUSH2, 0x03, 0xe8, vm.STATICCALL, vm.STOP},
+			vm.LondonGasSchedule, 14 + 2600 + 1000},
+	}
+	for _, tt := range tests {
+		sp := state.NewMemoryState()
+		sp.AddAccount(caller, tt.code, uint256.NewInt(10))
+		sp.AddAccount([20]byte{19: 0xbb}, []byte{vm.PUSH1, 0x01, vm.POP, vm.STOP}, uint256.NewInt(0))
+		sp.AddAccount([20]byte{19: 0xcc}, []byte{vm.INVALID}, uint256.NewInt(0))
+
+		d, err := runMetered(tt.code, tt.schedule, 100000, sp)
+		if err != nil {
+			t.Fatalf("%s: execution error: %v", tt.name, err)
+		}
+		if d.GasUsed() != tt.gasUsed || d.Context.Gas != 100000-tt.gasUsed {
+			t.Errorf("%s: expected %d gas used, got %d with %d left", tt.name, tt.gasUsed, d.GasUsed(), d.Context.Gas)
+		}
+	}
+
+	// Before EIP-150 the requested gas is charged in full
+	code := []byte{vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0xbb, vm.GAS, vm.CALL}
+	sp := state.NewMemoryState()
+	sp.AddAccount(caller, code, uint256.NewInt(0))
+	if _, err := runMetered(code, vm.FrontierGasSchedule, 100000, sp); !errors.Is(err, vm.ErrOutOfGas) {
+		t.Fatalf("expected ErrOutOfGas, got %v", err)
+	}
+}
+
+func TestSessionGasUsedWithCalls(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	callee := [20]byte{19: 0xcc}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	// Calls a callee halting exceptionally with all the gas left
+	sp.AddAccount(target, []byte{vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0xcc, vm.GAS, vm.CALL, vm.STOP}, uint256.NewInt(0))
+	sp.AddAccount(callee, []byte{vm.INVALID}, uint256.NewInt(0))
+
+	s := vm.NewSession(sp, nil, GetHandler, []vm.Transaction{{From: sender, To: &target, Gas: 30000}})
+	s.VM.GasSchedule = vm.LondonGasSchedule
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	// The callee consumes all but one 64th of the 6385 gas left after the cold CALL
+	result := s.Results[0]
+	if expected := uint64(21000 + 15 + 2600 + 6286); result.Failed() || result.GasUsed != expected {
+		t.Fatalf("expected %d gas used, got %d (%v)", expected, result.GasUsed, result.Err)
+	}
+}
+
+func TestSessionCodeDeposit(t *testing.T) {
+	sender := [20]byte{0xaa}
+	// initCode returns size zero bytes
+	initCode := func(size uint16) []byte {
+		return []byte{vm.PUSH2, byte(size >> 8), byte(size), vm.PUSH1, 0x00, vm.RETURN}
+	}
+
+	tests := []struct {
+		name string
+		size uint16
+		gas  uint64
+		err  error
+	}{
+		// Intrinsic gas of 53000 plus the 72 of the data, 9 for the initcode and 200 per byte
+		{"deposit", 10, 100000, nil},
+		{"deposit out of gas", 10, 53072 + 9 + 1999, vm.ErrCodeStoreOutOfGas},
+		{"code size limit", vm.MaxCodeSize + 1, 10000000, vm.ErrMaxCodeSizeExceeded},
+	}
+	for _, tt := range tests {
+		sp := state.NewMemoryState()
+		sp.AddAccount(sender, nil, uint256.NewInt(0))
+
+		s := vm.NewSession(sp, nil, GetHandler, []vm.Transaction{{From: sender, Data: initCode(tt.size), Gas: tt.gas}})
+		s.VM.ChainConfig = vm.ChainConfigForFork(vm.London)
+		s.VM.GasSchedule = vm.LondonGasSchedule
+		if err := s.Run(); err != nil {
+			t.Fatalf("%s: session error: %v", tt.name, err)
+		}
+
+		result := s.Results[0]
+		created := vm.CreateAddress(sender, 0)
+		if tt.err != nil {
+			if !errors.Is(result.Err, tt.err) || result.GasUsed != tt.gas {
+				t.Errorf("%s: expected %v using all gas, got %v using %d", tt.name, tt.err, result.Err, result.GasUsed)
+			}
+			if len(sp.GetCode(created)) != 0 {
+				t.Errorf("%s: expected no code to be deployed", tt.name)
+			}
+			continue
+		}
+		if expected := uint64(53072 + 9 + 2000); result.Err != nil || result.GasUsed != expected {
+			t.Errorf("%s: expected %d gas used, got %d (%v)", tt.name, expected, result.GasUsed, result.Err)
+		}
+		if len(sp.GetCode(created)) != int(tt.size) {
+			t.Errorf("%s: expected %d bytes of code, got %d", tt.name, tt.size, len(sp.GetCode(created)))
+		}
+	}
+}
+
+func TestSessionIntrinsicGas(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	sp.AddAccount(target, []byte{vm.PUSH1, 0x01, vm.POP, vm.STOP}, uint256.NewInt(0))
+
+	txs := []vm.Transaction{
+		{From: sender, To: &target, Data: []byte{0x00, 0x01}, Gas: 21000},
+		{From: sender, To: &target, Data: []byte{0x00, 0x01}, Gas: 30000},
+	}
+	s := vm.NewSession(sp, nil, GetHandler, txs)
+	s.VM.GasSchedule = vm.IstanbulGasSchedule
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	if !errors.Is(s.Results[0].Err, vm.ErrIntrinsicGas) {
+		t.Fatalf("expected ErrIntrinsicGas, got %v", s.Results[0].Err)
+	}
+	if s.Results[1].Failed() {
+		t.Fatalf("expected second transaction to succeed, got %v", s.Results[1].Err)
+	}
+	// 21000 + 4 for the zero byte + 16 for the non-zero byte + PUSH1 and POP
+	if expected := uint64(21000 + 4 + 16 + 3 + 2); s.Results[1].GasUsed != expected {
+		t.Fatalf("expected %d gas used, got %d", expected, s.Results[1].GasUsed)
+	}
+}
+
+func TestFailedCallRevertsAccessList(t *testing.T) {
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	callee := [20]byte{19: 0xcc}
+	// DELEGATECALL the callee, then BALANCE(0xdd) and SLOAD(5)
+	code := []byte{
+		vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0xcc, vm.GAS, vm.DELEGATECALL, vm.POP,
+		vm.PUSH1, 0xdd, vm.BALANCE, vm.POP, vm.PUSH1, 0x05, vm.SLOAD, vm.POP, vm.STOP,
+	}
+	// The callee accesses the same address and slot, then stops or reverts
+	access := []byte{vm.PUSH1, 0xdd, vm.BALANCE, vm.POP, vm.PUSH1, 0x05, vm.SLOAD, vm.POP}
+
+	for _, revert := range []bool{false, true} {
+		calleeCode := append(append([]byte{}, access...), vm.STOP)
+		if revert {
+			calleeCode = append(append([]byte{}, access...), vm.PUSH0, vm.PUSH0, vm.REVERT)
+		}
+		sp := state.NewMemoryState()
+		sp.AddAccount(sender, nil, uint256.NewInt(0))
+		sp.AddAccount(target, code, uint256.NewInt(0))
+		sp.AddAccount(callee, calleeCode, uint256.NewInt(0))
+
+		tracer := &eventTracer{}
+		s := vm.NewSession(sp, nil, GetHandler, []vm.Transaction{{From: sender, To: &target, Gas: 100000}})
+		s.VM.GasSchedule = vm.LondonGasSchedule
+		s.VM.Tracer = tracer
+		if err := s.Run(); err != nil {
+			t.Fatalf("session error: %v", err)
+		}
+
+		// The accesses of a reverted callee do not stay warm
+		expectedBalance, expectedSload := uint64(100), uint64(100)
+		if revert {
+			expectedBalance, expectedSload = 2600, 2100
+		}
+		for _, step := range tracer.steps {
+			if step.Depth != 1 {
+				continue
+			}
+			switch vm.OpCode(step.Op) {
+			case vm.BALANCE:
+				if step.Cost != expectedBalance {
+					t.Errorf("revert %v: expected BALANCE to cost %d, got %d", revert, expectedBalance, step.Cost)
+				}
+			case vm.SLOAD:
+				if step.Cost != expectedSload {
+					t.Errorf("revert %v: expected SLOAD to cost %d, got %d", revert, expectedSload, step.Cost)
+				}
+			}
+		}
+	}
+}
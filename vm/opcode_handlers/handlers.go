@@ -29,6 +29,7 @@ var handlers = map[vm.OpCode]vm.Handler{
 	vm.SHL:            &ShlOpCode{},
 	vm.SHR:            &ShrOpCode{},
 	vm.SAR:            &SarOpCode{},
+	vm.CLZ:            &ClzOpCode{},
 	vm.SHA3:           &Sha3OpCode{},
 	vm.ADDRESS:        &AddressOpCode{},
 	vm.BALANCE:        &BalanceOpCode{},
@@ -88,6 +89,27 @@ var handlers = map[vm.OpCode]vm.Handler{
 	vm.REVERT:         &RevertOpCode{},
 	vm.INVALID:        &InvalidOpCode{},
 	vm.SELFDESTRUCT:   &SelfDestructOpCode{},
+
+	// EOF-only instructions, undefined in legacy code
+	vm.DATALOAD:        &DataLoadOpCode{},
+	vm.DATALOADN:       &DataLoadNOpCode{},
+	vm.DATASIZE:        &DataSizeOpCode{},
+	vm.DATACOPY:        &DataCopyOpCode{},
+	vm.RJUMP:           &RJumpOpCode{},
+	vm.RJUMPI:          &RJumpiOpCode{},
+	vm.RJUMPV:          &RJumpvOpCode{},
+	vm.CALLF:           &CallFOpCode{},
+	vm.RETF:            &RetFOpCode{},
+	vm.JUMPF:           &JumpFOpCode{},
+	vm.DUPN:            &DupNOpCode{},
+	vm.SWAPN:           &SwapNOpCode{},
+	vm.EXCHANGE:        &ExchangeOpCode{},
+	vm.EOFCREATE:       &EOFCreateOpCode{},
+	vm.RETURNCONTRACT:  &ReturnContractOpCode{},
+	vm.RETURNDATALOAD:  &ReturnDataLoadOpCode{},
+	vm.EXTCALL:         &ExtCallOpCode{},
+	vm.EXTDELEGATECALL: &ExtDelegateCallOpCode{},
+	vm.EXTSTATICCALL:   &ExtStaticCallOpCode{},
 }
 
 func init() {
@@ -106,8 +128,13 @@ func init() {
 	for i := 1; i <= 16; i++ {
 		handlers[vm.OpCode(0x8f+i)] = &SwapOpCode{N: i}
 	}
+
+	buildInstructionSets()
+	vm.RegisterInstructionSets(GetHandlerForFork)
 }
 
+// GetHandler returns the handler of an opcode in vm.DefaultFork, which VMs without a
+// ChainConfig execute
 func GetHandler(b byte) vm.Handler {
-	return handlers[vm.OpCode(b)]
+	return instructionSets[vm.DefaultFork][b]
 }
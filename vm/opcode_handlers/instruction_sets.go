@@ -0,0 +1,82 @@
+package opcode_handlers
+
+import "github.com/daniellehrner/evmdbg/vm"
+
+// introducedIn lists the fork that added each opcode. Opcodes not listed exist since Frontier.
+// EOF instructions are not tied to a fork, they are gated by DebuggerVM.EnableEOF instead.
+var introducedIn = map[vm.OpCode]vm.Fork{
+	vm.DELEGATECALL:   vm.Homestead,
+	vm.REVERT:         vm.Byzantium,
+	vm.RETURNDATASIZE: vm.Byzantium,
+	vm.RETURNDATACOPY: vm.Byzantium,
+	vm.STATICCALL:     vm.Byzantium,
+	vm.SHL:            vm.Constantinople,
+	vm.SHR:            vm.Constantinople,
+	vm.SAR:            vm.Constantinople,
+	vm.EXTCODEHASH:    vm.Constantinople,
+	vm.CREATE2:        vm.Constantinople,
+	vm.CHAINID:        vm.Istanbul,
+	vm.SELFBALANCE:    vm.Istanbul,
+	vm.BASEFEE:        vm.London,
+	vm.PUSH0:          vm.Shanghai,
+	vm.TLOAD:          vm.Cancun,
+	vm.TSTORE:         vm.Cancun,
+	vm.MCOPY:          vm.Cancun,
+	vm.BLOBHASH:       vm.Cancun,
+	vm.BLOBBASEFEE:    vm.Cancun,
+	vm.CLZ:            vm.Osaka,
+}
+
+// forkVariant returns the handler of op in fork if its semantics differ from the latest fork
+func forkVariant(op vm.OpCode, fork vm.Fork) vm.Handler {
+	switch op {
+	case vm.SELFDESTRUCT:
+		if fork < vm.Cancun {
+			h := &SelfDestructOpCode{FullDeletion: true}
+			if fork < vm.London {
+				h.RefundGas = vm.SelfDestructRefundGas
+			}
+			return h
+		}
+	case vm.DIFFICULTY:
+		if fork < vm.Paris {
+			return &DifficultyOpCode{PreMerge: true}
+		}
+	}
+	return nil
+}
+
+// instructionSets holds the handlers available in each fork
+var instructionSets [vm.NumForks][256]vm.Handler
+
+// forkHandlerGetters holds a HandlerGetter for each instruction set
+var forkHandlerGetters [vm.NumForks]vm.HandlerGetter
+
+func buildInstructionSets() {
+	for f := 0; f < vm.NumForks; f++ {
+		for op, h := range handlers {
+			if introducedIn[op] > vm.Fork(f) {
+				continue
+			}
+			if variant := forkVariant(op, vm.Fork(f)); variant != nil {
+				h = variant
+			}
+			instructionSets[f][op] = h
+		}
+		set := &instructionSets[f]
+		forkHandlerGetters[f] = func(b byte) vm.Handler {
+			return set[b]
+		}
+	}
+}
+
+// GetHandlerForFork returns a HandlerGetter exposing only the opcodes available in fork.
+// Opcodes that do not exist yet have no handler and fail like undefined opcodes.
+func GetHandlerForFork(fork vm.Fork) vm.HandlerGetter {
+	return forkHandlerGetters[fork]
+}
+
+// OpCodeAvailable reports whether op is defined in fork
+func OpCodeAvailable(op vm.OpCode, fork vm.Fork) bool {
+	return instructionSets[fork][op] != nil
+}
@@ -0,0 +1,232 @@
+package opcode_handlers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+func TestMainnetForkActivation(t *testing.T) {
+	tests := []struct {
+		number, time uint64
+		fork         vm.Fork
+	}{
+		{0, 0, vm.Frontier},
+		{1_150_000, 0, vm.Homestead},
+		{4_369_999, 0, vm.SpuriousDragon},
+		{4_370_000, 0, vm.Byzantium},
+		{7_280_000, 0, vm.Petersburg},
+		{12_965_000, 1_628_166_822, vm.London},
+		{15_537_394, 1_663_224_179, vm.Paris},
+		{17_034_870, 1_681_338_455, vm.Shanghai},
+		{19_426_587, 1_710_338_135, vm.Cancun},
+		{22_431_084, 1_746_612_311, vm.Prague},
+		{23_935_694, 1_764_798_551, vm.Osaka},
+	}
+
+	for _, tt := range tests {
+		if got := vm.MainnetChainConfig.Fork(tt.number, tt.time); got != tt.fork {
+			t.Errorf("block %d at %d: expected %s, got %s", tt.number, tt.time, tt.fork, got)
+		}
+	}
+}
+
+func TestChainConfigPresets(t *testing.T) {
+	for _, id := range []uint64{1, 11_155_111, 560_048} {
+		c, ok := vm.ChainConfigByChainID(id)
+		if !ok || c.ChainID.Uint64() != id {
+			t.Fatalf("missing preset for chain %d", id)
+		}
+	}
+	if _, ok := vm.ChainConfigByChainID(1337); ok {
+		t.Fatal("unexpected preset for chain 1337")
+	}
+
+	if got := vm.HoodiChainConfig.Fork(0, 0); got != vm.Cancun {
+		t.Fatalf("expected Hoodi genesis to run Cancun, got %s", got)
+	}
+	if got := vm.ChainConfigForFork(vm.Berlin).Fork(1_000_000, 2_000_000_000); got != vm.Berlin {
+		t.Fatalf("expected Berlin, got %s", got)
+	}
+
+	for f := vm.Frontier; int(f) < vm.NumForks; f++ {
+		if parsed, ok := vm.ForkByName(f.String()); !ok || parsed != f {
+			t.Fatalf("fork name %s does not round trip", f)
+		}
+	}
+}
+
+func TestInstructionSetsPerFork(t *testing.T) {
+	tests := []struct {
+		op    vm.OpCode
+		added vm.Fork
+	}{
+		{vm.DELEGATECALL, vm.Homestead},
+		{vm.STATICCALL, vm.Byzantium},
+		{vm.SHL, vm.Constantinople},
+		{vm.CREATE2, vm.Constantinople},
+		{vm.SELFBALANCE, vm.Istanbul},
+		{vm.BASEFEE, vm.London},
+		{vm.PUSH0, vm.Shanghai},
+		{vm.MCOPY, vm.Cancun},
+		{vm.BLOBBASEFEE, vm.Cancun},
+	}
+
+	for _, tt := range tests {
+		if OpCodeAvailable(tt.op, tt.added-1) {
+			t.Errorf("opcode 0x%x should not exist before %s", byte(tt.op), tt.added)
+		}
+		if !OpCodeAvailable(tt.op, tt.added) || !OpCodeAvailable(tt.op, vm.Osaka) {
+			t.Errorf("opcode 0x%x should exist from %s on", byte(tt.op), tt.added)
+		}
+	}
+
+	for f := vm.Frontier; int(f) < vm.NumForks; f++ {
+		if !OpCodeAvailable(vm.ADD, f) || !OpCodeAvailable(vm.PUSH32, f) || !OpCodeAvailable(vm.SWAP16, f) {
+			t.Fatalf("Frontier opcodes missing in %s", f)
+		}
+	}
+}
+
+func TestPush0UndefinedBeforeShanghai(t *testing.T) {
+	code := []byte{vm.PUSH0}
+
+	d := vm.NewDebuggerVM(code, GetHandlerForFork(vm.London))
+	if err := d.Step(); err == nil || !strings.Contains(err.Error(), "unsupported opcode") {
+		t.Fatalf("expected unsupported opcode error, got %v", err)
+	}
+
+	d = vm.NewDebuggerVM(code, GetHandlerForFork(vm.Shanghai))
+	if err := d.Step(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSessionUsesForkOfBlock(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	contract := [20]byte{0xcc}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	sp.AddAccount(contract, []byte{vm.PUSH1, 0x01, vm.PUSH1, 0x00, vm.TSTORE}, nil)
+
+	run := func(block *vm.BlockContext) *vm.TransactionResult {
+		fork := vm.ForkAt(vm.MainnetChainConfig, block)
+		s := vm.NewSession(sp, block, GetHandlerForFork(fork), []vm.Transaction{{From: sender, To: &contract}})
+		s.VM.ChainConfig = vm.MainnetChainConfig
+		if err := s.Run(); err != nil {
+			t.Fatalf("session error: %v", err)
+		}
+		return &s.Results[0]
+	}
+
+	// TSTORE does not exist in a Shanghai block
+	if r := run(&vm.BlockContext{Number: 17_034_870, Timestamp: 1_681_338_455}); r.Err == nil {
+		t.Fatal("expected TSTORE to fail before Cancun")
+	}
+	if r := run(&vm.BlockContext{Number: 19_426_587, Timestamp: 1_710_338_135}); r.Failed() {
+		t.Fatalf("expected TSTORE to succeed in Cancun, got %v", r.Err)
+	}
+}
+
+func TestSetCodeTransactionRequiresPrague(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	key, eoa := testKey(1)
+
+	txs := []vm.Transaction{{From: sender, To: &eoa, AuthorizationList: []vm.SetCodeAuthorization{
+		signAuthorization(key, vm.SetCodeAuthorization{Address: [20]byte{0xde}}),
+	}}}
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1}, GetHandlerForFork(vm.Cancun), txs)
+	s.VM.ChainConfig = vm.ChainConfigForFork(vm.Cancun)
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	if s.Results[0].Err != vm.ErrSetCodeTxNotActive {
+		t.Fatalf("expected ErrSetCodeTxNotActive, got %v", s.Results[0].Err)
+	}
+}
+
+func TestConstantinopleOpCodesUndefinedBefore(t *testing.T) {
+	for _, code := range [][]byte{
+		{vm.PUSH1, 0x01, vm.PUSH1, 0x01, vm.SHL},
+		{vm.PUSH1, 0x01, vm.PUSH1, 0x01, vm.SHR},
+		{vm.PUSH1, 0x01, vm.PUSH1, 0x01, vm.SAR},
+		{vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.CREATE2},
+	} {
+		op := code[len(code)-1]
+
+		d := vm.NewDebuggerVM(code, GetHandlerForFork(vm.Byzantium))
+		if err := d.RunUntil(nil); err == nil || !strings.Contains(err.Error(), "unsupported opcode") {
+			t.Errorf("opcode 0x%x: expected unsupported opcode error in Byzantium, got %v", op, err)
+		}
+
+		d = vm.NewDebuggerVM(code, GetHandlerForFork(vm.Constantinople))
+		d.Context = &vm.ExecutionContext{}
+		if err := d.RunUntil(nil); err != nil && strings.Contains(err.Error(), "unsupported opcode") {
+			t.Errorf("opcode 0x%x: unexpected error in Constantinople: %v", op, err)
+		}
+	}
+}
+
+func TestClzUndefinedBeforeOsaka(t *testing.T) {
+	d := vm.NewDebuggerVM([]byte{vm.PUSH0, vm.CLZ}, GetHandlerForFork(vm.Prague))
+	if err := d.Step(); err != nil {
+		t.Fatalf("execution error: %v", err)
+	}
+	if err := d.Step(); err == nil {
+		t.Fatal("expected CLZ to be undefined under Prague rules")
+	}
+}
+
+func TestChainConfigSelectsInstructionSet(t *testing.T) {
+	code := []byte{vm.PUSH0, vm.CLZ}
+
+	// Without a configuration the VM runs DefaultFork, Prague
+	d := vm.NewDebuggerVM(code, GetHandler)
+	if err := d.RunUntil(nil); err == nil || !strings.Contains(err.Error(), "unsupported opcode") {
+		t.Errorf("expected CLZ to be undefined by default, got %v", err)
+	}
+
+	// The configured fork overrides the handlers the VM was created with
+	for _, fork := range []vm.Fork{vm.Cancun, vm.Prague, vm.Osaka} {
+		d = vm.NewDebuggerVM(code, GetHandlerForFork(vm.Osaka))
+		d.ChainConfig = vm.ChainConfigForFork(fork)
+		err := d.RunUntil(nil)
+		if undefined := err != nil && strings.Contains(err.Error(), "unsupported opcode"); undefined != (fork < vm.Osaka) {
+			t.Errorf("%s: unexpected result %v", fork, err)
+		}
+	}
+}
+
+func TestOsakaTransactionGasCap(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+
+	run := func(fork vm.Fork, gas uint64) *vm.TransactionResult {
+		s := vm.NewSession(sp, &vm.BlockContext{Number: 1}, GetHandlerForFork(fork),
+			[]vm.Transaction{{From: sender, To: &target, Gas: gas}})
+		s.VM.ChainConfig = vm.ChainConfigForFork(fork)
+		if err := s.Run(); err != nil {
+			t.Fatalf("session error: %v", err)
+		}
+		return &s.Results[0]
+	}
+
+	if r := run(vm.Osaka, vm.MaxTxGas+1); !errors.Is(r.Err, vm.ErrGasLimitTooHigh) {
+		t.Fatalf("expected ErrGasLimitTooHigh, got %v", r.Err)
+	}
+	if r := run(vm.Osaka, vm.MaxTxGas); r.Failed() {
+		t.Fatalf("expected a transaction at the cap to succeed, got %v", r.Err)
+	}
+	if r := run(vm.Prague, vm.MaxTxGas+1); r.Failed() {
+		t.Fatalf("expected no cap before Osaka, got %v", r.Err)
+	}
+}
@@ -0,0 +1,23 @@
+package opcode_handlers
+
+import (
+	"encoding/binary"
+
+	"github.com/daniellehrner/evmdbg/vm"
+)
+
+type JumpFOpCode struct{}
+
+func (*JumpFOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.JUMPF); err != nil {
+		return err
+	}
+
+	// Read the target section, the return stack is left untouched
+	imm, err := v.ReadCodeSlice(2)
+	if err != nil {
+		return err
+	}
+
+	return v.JumpToSection(int(binary.BigEndian.Uint16(imm)))
+}
@@ -44,7 +44,7 @@ func (op *LogNOpCode) Execute(v *vm.DebuggerVM) error {
 	// read data from memory at the specified offset and size
 	data := v.Memory().Read(int(offset.Uint64()), int(size.Uint64()))
 
-	v.Logs = append(v.Logs, vm.LogEntry{
+	v.AddLog(vm.LogEntry{
 		Address: v.Context.Address,
 		Topics:  topics,
 		Data:    data,
@@ -0,0 +1,32 @@
+package opcode_handlers
+
+import (
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// callPrecompile runs a precompiled contract called by the CALL family with the forwarded
+// gas, copies its output to memory and pushes the success flag. value is nil for calls that
+// do not carry value.
+func callPrecompile(v *vm.DebuggerVM, addr [20]byte, p vm.PrecompiledContract, callType vm.CallType, value *uint256.Int, gas uint64, argsOffset, argsSize, retOffset, retSize *uint256.Int) error {
+	var input []byte
+	if !argsSize.IsZero() {
+		input = v.Memory().Read(int(argsOffset.Uint64()), int(argsSize.Uint64()))
+	}
+
+	// The forwarded gas goes back to the caller, which is charged what the precompile uses
+	v.ReturnGas(gas, gas, nil)
+	output, err := v.RunPrecompile(addr, p, callType, input, value, gas)
+	if err != nil {
+		return v.Push(uint256.NewInt(0))
+	}
+
+	// Copy the output to memory, truncating it to the return area
+	if !retSize.IsZero() {
+		if size := retSize.Uint64(); uint64(len(output)) > size {
+			output = output[:size]
+		}
+		v.Memory().Write(int(retOffset.Uint64()), output)
+	}
+	return v.Push(uint256.NewInt(1))
+}
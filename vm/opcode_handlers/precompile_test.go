@@ -0,0 +1,271 @@
+package opcode_handlers
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/precompiles"
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// modExpInput computes 2**3 % 5 with single byte base, exponent and modulus
+var modExpInput = append(append(append(
+	bytes32WithValue(uint256.NewInt(1)),
+	bytes32WithValue(uint256.NewInt(1))...),
+	bytes32WithValue(uint256.NewInt(1))...),
+	0x02, 0x03, 0x05)
+
+// callPrecompileCode copies the call data to memory, CALLs the given address with it and
+// returns the first byte of the output, without PUSH0 to run before Shanghai
+func callPrecompileCode(addr byte) []byte {
+	return []byte{
+		vm.CALLDATASIZE, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.CALLDATACOPY,
+		vm.PUSH1, 0x01, vm.PUSH1, 0x80, vm.CALLDATASIZE, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, addr, vm.GAS, vm.CALL,
+		vm.PUSH1, 0x01, vm.PUSH1, 0x80, vm.RETURN,
+	}
+}
+
+func runPrecompileCall(t *testing.T, d *vm.DebuggerVM, input []byte) {
+	t.Helper()
+
+	d.StateProvider = state.NewMemoryState()
+	d.Context = &vm.ExecutionContext{Address: [20]byte{19: 0xaa}, Value: uint256.NewInt(0), CallData: input, Gas: 100000}
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			t.Fatalf("execution error: %v", err)
+		}
+	}
+}
+
+func TestPrecompilesForFork(t *testing.T) {
+	if _, ok := vm.PrecompilesForFork(vm.Frontier)[vm.PrecompileAddress(0x01)]; !ok {
+		t.Fatal("expected ECRECOVER since Frontier")
+	}
+	modexp := vm.PrecompileAddress(0x05)
+	if _, ok := vm.PrecompilesForFork(vm.Homestead)[modexp]; ok {
+		t.Fatal("expected no MODEXP before Byzantium")
+	}
+	if _, ok := vm.PrecompilesForFork(vm.Byzantium)[modexp]; !ok {
+		t.Fatal("expected MODEXP since Byzantium")
+	}
+	if m := vm.PrecompilesForFork(vm.Istanbul)[modexp].(*precompiles.ModExp); m.EIP2565 {
+		t.Fatal("expected EIP-198 MODEXP pricing before Berlin")
+	}
+	if m := vm.PrecompilesForFork(vm.Berlin)[modexp].(*precompiles.ModExp); !m.EIP2565 || m.EIP7823 {
+		t.Fatalf("expected EIP-2565 MODEXP without the Osaka bounds in Berlin, got %+v", m)
+	}
+	if m := vm.PrecompilesForFork(vm.Osaka)[modexp].(*precompiles.ModExp); !m.EIP7823 || !m.EIP7883 {
+		t.Fatalf("expected the Osaka MODEXP rules in Osaka, got %+v", m)
+	}
+	if p := vm.PrecompilesForFork(vm.Byzantium)[vm.PrecompileAddress(0x08)].(*precompiles.BN254Pairing); p.EIP1108 {
+		t.Fatal("expected Byzantium BN254 pricing before Istanbul")
+	}
+	if p := vm.PrecompilesForFork(vm.Istanbul)[vm.PrecompileAddress(0x08)].(*precompiles.BN254Pairing); !p.EIP1108 {
+		t.Fatal("expected EIP-1108 BN254 pricing since Istanbul")
+	}
+	if _, ok := vm.PrecompilesForFork(vm.Petersburg)[vm.PrecompileAddress(0x09)]; ok {
+		t.Fatal("expected no BLAKE2F before Istanbul")
+	}
+	if _, ok := vm.PrecompilesForFork(vm.Shanghai)[vm.PrecompileAddress(0x0a)]; ok {
+		t.Fatal("expected no point evaluation before Cancun")
+	}
+	if _, ok := vm.PrecompilesForFork(vm.Cancun)[vm.PrecompileAddress(0x0b)]; ok {
+		t.Fatal("expected no BLS12-381 precompiles before Prague")
+	}
+	if _, ok := vm.PrecompilesForFork(vm.Prague)[vm.PrecompileAddress(0x11)]; !ok {
+		t.Fatal("expected BLS12-381 precompiles since Prague")
+	}
+	if _, ok := vm.PrecompilesForFork(vm.Prague)[vm.PrecompileAddress(0x100)]; ok {
+		t.Fatal("expected no P256VERIFY before Osaka")
+	}
+	if _, ok := vm.PrecompilesForFork(vm.Osaka)[vm.PrecompileAddress(0x100)]; !ok {
+		t.Fatal("expected P256VERIFY since Osaka")
+	}
+}
+
+func TestCallModExpPrecompile(t *testing.T) {
+	d := vm.NewDebuggerVM(callPrecompileCode(0x05), GetHandler)
+	runPrecompileCall(t, d, modExpInput)
+	if !bytes.Equal(d.ReturnValue, []byte{0x03}) {
+		t.Fatalf("expected 2**3 %% 5 = 03, got %x", d.ReturnValue)
+	}
+
+	// Before Byzantium 0x05 is an empty account
+	d = vm.NewDebuggerVM(callPrecompileCode(0x05), GetHandler)
+	d.ChainConfig = vm.ChainConfigForFork(vm.Homestead)
+	runPrecompileCall(t, d, modExpInput)
+	if !bytes.Equal(d.ReturnValue, []byte{0x00}) {
+		t.Fatalf("expected no output before Byzantium, got %x", d.ReturnValue)
+	}
+}
+
+func TestCallIdentityPrecompile(t *testing.T) {
+	d := vm.NewDebuggerVM(callPrecompileCode(0x04), GetHandler)
+	runPrecompileCall(t, d, []byte{0x2a, 0x2b})
+	if !bytes.Equal(d.ReturnValue, []byte{0x2a}) {
+		t.Fatalf("expected the copied input 2a, got %x", d.ReturnValue)
+	}
+	if !bytes.Equal(d.ReturnData(), []byte{0x2a, 0x2b}) {
+		t.Fatalf("expected return data 2a2b, got %x", d.ReturnData())
+	}
+}
+
+func TestRegisterPrecompile(t *testing.T) {
+	d := vm.NewDebuggerVM(callPrecompileCode(0xee), GetHandler)
+	d.RegisterPrecompile([20]byte{19: 0xee}, reversePrecompile{})
+	if _, ok := d.ActivePrecompiles()[[20]byte{19: 0xee}]; !ok {
+		t.Fatal("expected the registered precompile to be active")
+	}
+	runPrecompileCall(t, d, []byte{0x01, 0x02})
+	if !bytes.Equal(d.ReturnValue, []byte{0x02}) {
+		t.Fatalf("expected the first byte of the reversed input, got %x", d.ReturnValue)
+	}
+
+	// Registrations replace the precompiles of the fork and can be removed
+	d.RegisterPrecompile(vm.PrecompileAddress(0x05), reversePrecompile{})
+	if p, _ := d.Precompile(vm.PrecompileAddress(0x05)); p != (reversePrecompile{}) {
+		t.Fatalf("expected the registered precompile at 0x05, got %T", p)
+	}
+	d.RegisterPrecompile(vm.PrecompileAddress(0x05), nil)
+	if p, _ := d.Precompile(vm.PrecompileAddress(0x05)); p == (reversePrecompile{}) {
+		t.Fatal("expected MODEXP at 0x05 after removing the registration")
+	}
+}
+
+func TestEOFExtCallPrecompile(t *testing.T) {
+	// EXTCALL 0x05 with the call data, then return the status and the first output word
+	code := []byte{
+		vm.CALLDATASIZE, vm.PUSH0, vm.PUSH0, vm.CALLDATACOPY,
+		vm.PUSH0, vm.CALLDATASIZE, vm.PUSH0, vm.PUSH1, 0x05, vm.EXTCALL,
+		vm.PUSH0, vm.MSTORE,
+		vm.PUSH0, vm.RETURNDATALOAD, vm.PUSH1, 0x20, vm.MSTORE,
+		vm.PUSH1, 0x40, vm.PUSH0, vm.RETURN,
+	}
+
+	d := vm.NewDebuggerVM(eofContainer(4, code, nil, nil), GetHandler)
+	d.StateProvider = state.NewMemoryState()
+	d.Context = &vm.ExecutionContext{Address: [20]byte{19: 0xaa}, Value: uint256.NewInt(0), CallData: modExpInput, Gas: 100000}
+	if err := d.EnableEOF(); err != nil {
+		t.Fatalf("EnableEOF error: %v", err)
+	}
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			t.Fatalf("execution error: %v", err)
+		}
+	}
+
+	expected := append(bytes32WithValue(uint256.NewInt(0)), 0x03)
+	expected = append(expected, make([]byte, 31)...)
+	if !bytes.Equal(d.ReturnValue, expected) {
+		t.Fatalf("expected success status and output %x, got %x", expected, d.ReturnValue)
+	}
+}
+
+func TestSessionWarmsPrecompiles(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	custom := [20]byte{0xcc}
+	txs := []vm.Transaction{{From: sender, To: &target, Value: uint256.NewInt(0), Gas: 100_000}}
+
+	for _, fork := range []vm.Fork{vm.Istanbul, vm.Berlin} {
+		s := vm.NewSession(sp, &vm.BlockContext{}, GetHandler, txs)
+		s.VM.ChainConfig = vm.ChainConfigForFork(fork)
+		s.VM.RegisterPrecompile(custom, reversePrecompile{})
+		if err := s.Run(); err != nil {
+			t.Fatalf("%s: session error: %v", fork, err)
+		}
+
+		warm := fork >= vm.Berlin
+		if s.VM.IsAddressWarm(vm.PrecompileAddress(0x05)) != warm || s.VM.IsAddressWarm(custom) != warm {
+			t.Fatalf("%s: expected precompiles warm %v", fork, warm)
+		}
+	}
+}
+
+func TestPrecompileCallsAreRecorded(t *testing.T) {
+	d := vm.NewDebuggerVM(callPrecompileCode(0x05), GetHandler)
+	runPrecompileCall(t, d, modExpInput)
+
+	calls := d.PrecompileCalls()
+	if len(calls) != 1 {
+		t.Fatalf("expected one precompile call, got %d", len(calls))
+	}
+	call := calls[0]
+	if call.Name != "MODEXP" || call.Address != vm.PrecompileAddress(0x05) || call.CallType != vm.CallTypeCall || call.Depth != 2 {
+		t.Fatalf("unexpected precompile frame %+v", call)
+	}
+	if call.Failed() || !bytes.Equal(call.Output, []byte{0x03}) || call.GasUsed != 200 {
+		t.Fatalf("expected output 03 for 200 gas, got %x for %d (%v)", call.Output, call.GasUsed, call.Err)
+	}
+
+	fields := map[string]string{}
+	for _, f := range call.Fields {
+		fields[f.Name] = f.Value
+	}
+	if fields["baseLen"] != "1" || fields["base"] != "0x02" || fields["exp"] != "0x03" || fields["mod"] != "0x05" {
+		t.Fatalf("unexpected decoded input %v", call.Fields)
+	}
+}
+
+func TestPrecompileRequiredGasWithoutMetering(t *testing.T) {
+	// A 2^34 byte base would be allocated by MODEXP, its cost exceeds the gas of the call
+	input := append(append(append(
+		bytes32WithValue(uint256.NewInt(1<<34)),
+		bytes32WithValue(uint256.NewInt(1))...),
+		bytes32WithValue(uint256.NewInt(1))...),
+		0x02, 0x03, 0x05)
+	d := vm.NewDebuggerVM(callPrecompileCode(0x05), GetHandler)
+	runPrecompileCall(t, d, input)
+
+	if call := d.PrecompileCalls()[0]; !errors.Is(call.Err, vm.ErrOutOfGas) {
+		t.Fatalf("expected MODEXP to run out of gas, got %+v", call)
+	}
+}
+
+func TestPrecompileCallFailureReason(t *testing.T) {
+	// STATICCALL ECRECOVER with 100 gas
+	code := []byte{vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0x01, vm.PUSH1, 0x64, vm.STATICCALL, vm.STOP}
+	d, err := runMetered(code, vm.LondonGasSchedule, 100000, state.NewMemoryState())
+	if err != nil {
+		t.Fatalf("execution error: %v", err)
+	}
+
+	calls := d.PrecompileCalls()
+	if len(calls) != 1 {
+		t.Fatalf("expected one precompile call, got %d", len(calls))
+	}
+	if call := calls[0]; call.Name != "ECRECOVER" || call.CallType != vm.CallTypeStaticCall || !errors.Is(call.Err, vm.ErrOutOfGas) || call.GasUsed != 100 {
+		t.Fatalf("expected ECRECOVER to run out of its 100 gas, got %+v", call)
+	}
+	if success, _ := d.Stack().Peek(0); !success.IsZero() {
+		t.Fatal("expected the call to fail")
+	}
+
+	// Contracts without a name are named after their address
+	if name := vm.PrecompileName([20]byte{19: 0xee}, reversePrecompile{}); name != "precompile 0x00000000000000000000000000000000000000ee" {
+		t.Fatalf("unexpected name %q", name)
+	}
+}
+
+func TestPrecompileOutOfGasIsCappedAtGasLeft(t *testing.T) {
+	// STATICCALL ECRECOVER with all the gas the stack can hold
+	code := []byte{vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0x01, vm.PUSH0, vm.NOT, vm.STATICCALL, vm.STOP}
+	d, err := runMetered(code, vm.LondonGasSchedule, 5000, state.NewMemoryState())
+	if err != nil {
+		t.Fatalf("execution error: %v", err)
+	}
+
+	// 16 for the pushes and 2600 for the cold STATICCALL leave 2384, of which all but one
+	// 64th is forwarded to ECRECOVER, which needs 3000
+	call := d.PrecompileCalls()[0]
+	if !errors.Is(call.Err, vm.ErrOutOfGas) || call.Gas != 2347 || call.GasUsed != 2347 {
+		t.Fatalf("expected ECRECOVER to run out of the 2347 gas forwarded, got %+v", call)
+	}
+	if d.GasUsed() != 4963 || d.Context.Gas != 37 {
+		t.Fatalf("expected 4963 gas used and 37 left, got %d used and %d left", d.GasUsed(), d.Context.Gas)
+	}
+}
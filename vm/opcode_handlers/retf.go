@@ -0,0 +1,14 @@
+package opcode_handlers
+
+import "github.com/daniellehrner/evmdbg/vm"
+
+type RetFOpCode struct{}
+
+func (*RetFOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.RETF); err != nil {
+		return err
+	}
+
+	// Continue after the CALLF that entered this section
+	return v.ReturnFromSection()
+}
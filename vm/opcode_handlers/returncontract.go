@@ -0,0 +1,40 @@
+package opcode_handlers
+
+import "github.com/daniellehrner/evmdbg/vm"
+
+type ReturnContractOpCode struct{}
+
+func (*ReturnContractOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.RETURNCONTRACT); err != nil {
+		return err
+	}
+
+	// RETURNCONTRACT requires two values on the stack: auxDataOffset, auxDataSize
+	if err := v.RequireStack(2); err != nil {
+		return err
+	}
+
+	// The immediate selects the subcontainer to deploy
+	imm, err := v.ReadCodeByte(0)
+	if err != nil {
+		return err
+	}
+	v.AdvancePC(1)
+
+	offset, size, err := v.Pop2()
+	if err != nil {
+		return err
+	}
+
+	// The deployed container is the subcontainer with the aux data appended to its data section
+	aux := v.Memory().Read(int(offset.Uint64()), int(size.Uint64()))
+	deployed, err := v.CurrentFrame().EOF.SubContainers[imm].WithAuxData(aux)
+	if err != nil {
+		return err
+	}
+
+	v.ReturnValue = deployed
+	v.Stopped = true
+
+	return nil
+}
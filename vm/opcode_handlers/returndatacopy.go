@@ -23,6 +23,16 @@ func (*ReturnDataCopyOpCode) Execute(v *vm.DebuggerVM) error {
 	start := offset.Uint64()
 	end := start + size.Uint64()
 
+	// EOF code reads beyond the end of the return data as zeroes
+	if frame := v.CurrentFrame(); frame != nil && frame.IsEOF() {
+		data := make([]byte, size.Uint64())
+		if start < uint64(len(returnData)) {
+			copy(data, returnData[start:])
+		}
+		v.Memory().Write(int(memOffset.Uint64()), data)
+		return nil
+	}
+
 	// Ensure the start and end indices are within bounds of the return data.
 	if end > uint64(len(returnData)) {
 		return fmt.Errorf("RETURNDATACOPY out of bounds: %d > %d", end, len(returnData))
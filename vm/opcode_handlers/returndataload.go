@@ -0,0 +1,33 @@
+package opcode_handlers
+
+import (
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+type ReturnDataLoadOpCode struct{}
+
+func (*ReturnDataLoadOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.RETURNDATALOAD); err != nil {
+		return err
+	}
+
+	// RETURNDATALOAD requires the offset on the stack
+	if err := v.RequireStack(1); err != nil {
+		return err
+	}
+
+	offset, err := v.Stack().Pop()
+	if err != nil {
+		return err
+	}
+
+	// Read 32 bytes of the return data, padded with zeroes
+	returnData := v.ReturnData()
+	word := make([]byte, 32)
+	if offset.IsUint64() && offset.Uint64() < uint64(len(returnData)) {
+		copy(word, returnData[offset.Uint64():])
+	}
+
+	return v.Push(new(uint256.Int).SetBytes(word))
+}
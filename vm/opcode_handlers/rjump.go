@@ -0,0 +1,26 @@
+package opcode_handlers
+
+import (
+	"encoding/binary"
+
+	"github.com/daniellehrner/evmdbg/vm"
+)
+
+type RJumpOpCode struct{}
+
+func (*RJumpOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.RJUMP); err != nil {
+		return err
+	}
+
+	// Read the signed 16-bit offset, relative to the end of the immediate
+	imm, err := v.ReadCodeSlice(2)
+	if err != nil {
+		return err
+	}
+	offset := int16(binary.BigEndian.Uint16(imm))
+
+	// Validation guarantees that the target is an instruction of this section
+	v.SetPC(uint64(int64(v.PC()) + 2 + int64(offset)))
+	return nil
+}
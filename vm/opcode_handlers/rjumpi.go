@@ -0,0 +1,39 @@
+package opcode_handlers
+
+import (
+	"encoding/binary"
+
+	"github.com/daniellehrner/evmdbg/vm"
+)
+
+type RJumpiOpCode struct{}
+
+func (*RJumpiOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.RJUMPI); err != nil {
+		return err
+	}
+
+	// RJUMPI requires the condition on the stack
+	if err := v.RequireStack(1); err != nil {
+		return err
+	}
+
+	imm, err := v.ReadCodeSlice(2)
+	if err != nil {
+		return err
+	}
+	offset := int16(binary.BigEndian.Uint16(imm))
+
+	cond, err := v.Stack().Pop()
+	if err != nil {
+		return err
+	}
+
+	// Only jump if the condition is non-zero, otherwise skip the immediate
+	next := int64(v.PC()) + 2
+	if !cond.IsZero() {
+		next += int64(offset)
+	}
+	v.SetPC(uint64(next))
+	return nil
+}
@@ -0,0 +1,45 @@
+package opcode_handlers
+
+import (
+	"encoding/binary"
+
+	"github.com/daniellehrner/evmdbg/vm"
+)
+
+type RJumpvOpCode struct{}
+
+func (*RJumpvOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.RJUMPV); err != nil {
+		return err
+	}
+
+	// RJUMPV requires the case index on the stack
+	if err := v.RequireStack(1); err != nil {
+		return err
+	}
+
+	// The immediate is max_index followed by max_index+1 signed 16-bit offsets
+	maxIndex, err := v.ReadCodeByte(0)
+	if err != nil {
+		return err
+	}
+	count := uint64(maxIndex) + 1
+	table, err := v.ReadCodeSlice(1 + 2*count)
+	if err != nil {
+		return err
+	}
+
+	index, err := v.Stack().Pop()
+	if err != nil {
+		return err
+	}
+
+	// Offsets are relative to the end of the immediate, an out of range index falls through
+	next := int64(v.PC()) + 1 + 2*int64(count)
+	if index.IsUint64() && index.Uint64() < count {
+		i := 1 + 2*index.Uint64()
+		next += int64(int16(binary.BigEndian.Uint16(table[i : i+2])))
+	}
+	v.SetPC(uint64(next))
+	return nil
+}
@@ -7,9 +7,19 @@ import (
 	"github.com/holiman/uint256"
 )
 
-type SelfDestructOpCode struct{}
+// SelfDestructOpCode implements SELFDESTRUCT. The zero value applies the EIP-6780 rules
+// active since Cancun.
+type SelfDestructOpCode struct {
+	// FullDeletion deletes the account even if it was not created in the current
+	// transaction, as before Cancun
+	FullDeletion bool
+
+	// RefundGas is refunded the first time an account is destroyed in a transaction
+	// (24000 before London, removed by EIP-3529)
+	RefundGas uint64
+}
 
-func (*SelfDestructOpCode) Execute(v *vm.DebuggerVM) error {
+func (op *SelfDestructOpCode) Execute(v *vm.DebuggerVM) error {
 	err := v.RequireContext()
 	if err != nil {
 		return fmt.Errorf("selfdestruct op code requires the execution context to be set")
@@ -51,8 +61,13 @@ func (*SelfDestructOpCode) Execute(v *vm.DebuggerVM) error {
 	// EIP-6780: Check if the contract was created in the same transaction
 	createdInTransaction := v.IsAccountCreatedInTransaction(currentAddr)
 
-	if createdInTransaction {
-		// Original SELFDESTRUCT behavior - delete the account completely
+	if v.MarkSelfDestructed(currentAddr) && op.RefundGas > 0 {
+		v.AddRefund(op.RefundGas)
+	}
+
+	if createdInTransaction || op.FullDeletion {
+		// Original SELFDESTRUCT behavior - the account is deleted at the end of the
+		// transaction, until then only its balance is gone
 
 		// Transfer balance to beneficiary (even if it's the same address, which burns ether)
 		if !currentBalance.IsZero() {
@@ -60,16 +75,13 @@ func (*SelfDestructOpCode) Execute(v *vm.DebuggerVM) error {
 				// Transfer to different address
 				beneficiaryBalance := v.StateProvider.GetBalance(beneficiary)
 				newBeneficiaryBalance := new(uint256.Int).Add(beneficiaryBalance, currentBalance)
-				v.StateProvider.SetBalance(beneficiary, newBeneficiaryBalance)
+				v.SetBalance(beneficiary, newBeneficiaryBalance)
 			}
 			// If beneficiary is same as current address, ether is burned (balance set to 0)
+			v.SetBalance(currentAddr, uint256.NewInt(0))
 		}
 
-		// Delete the account (code, storage, nonce, balance)
-		err = v.StateProvider.DeleteAccount(currentAddr)
-		if err != nil {
-			return fmt.Errorf("failed to delete account: %w", err)
-		}
+		v.ScheduleDeletion(currentAddr)
 	} else {
 		// Transfer balance to beneficiary
 		if !currentBalance.IsZero() && beneficiary != currentAddr {
@@ -77,10 +89,10 @@ func (*SelfDestructOpCode) Execute(v *vm.DebuggerVM) error {
 			// If same address, no net change in balance (ether is NOT burned)
 			beneficiaryBalance := v.StateProvider.GetBalance(beneficiary)
 			newBeneficiaryBalance := new(uint256.Int).Add(beneficiaryBalance, currentBalance)
-			v.StateProvider.SetBalance(beneficiary, newBeneficiaryBalance)
+			v.SetBalance(beneficiary, newBeneficiaryBalance)
 
 			// Set current account balance to 0
-			v.StateProvider.SetBalance(currentAddr, uint256.NewInt(0))
+			v.SetBalance(currentAddr, uint256.NewInt(0))
 		}
 	}
 
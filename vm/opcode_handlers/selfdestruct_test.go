@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/daniellehrner/evmdbg/state"
 	"github.com/daniellehrner/evmdbg/vm"
 	"github.com/holiman/uint256"
 )
@@ -194,7 +195,13 @@ func TestSelfDestructOpCode_CreatedInTransaction(t *testing.T) {
 		t.Error("Expected VM to be stopped after SELFDESTRUCT")
 	}
 
-	// Check that contract was deleted
+	// Check that contract is deleted at the end of the transaction
+	if mockState.IsDeleted(contractAddr) {
+		t.Error("Expected contract to be deleted only at the end of the transaction")
+	}
+	if err := v.DeleteScheduled(); err != nil {
+		t.Fatalf("Unexpected error deleting accounts: %v", err)
+	}
 	if !mockState.IsDeleted(contractAddr) {
 		t.Error("Expected contract to be deleted when created in same transaction")
 	}
@@ -304,7 +311,13 @@ func TestSelfDestructOpCode_SameBeneficiary_CreatedInTransaction(t *testing.T) {
 		}
 	}
 
-	// Check that contract was deleted (ether burned)
+	// Check that the ether was burned and the contract deleted
+	if got := mockState.GetBalance(contractAddr); !got.IsZero() {
+		t.Errorf("Expected contract balance 0, got %s", got)
+	}
+	if err := v.DeleteScheduled(); err != nil {
+		t.Fatalf("Unexpected error deleting accounts: %v", err)
+	}
 	if !mockState.IsDeleted(contractAddr) {
 		t.Error("Expected contract to be deleted, burning ether")
 	}
@@ -451,3 +464,145 @@ func TestSelfDestructOpCode_StackUnderflow(t *testing.T) {
 		t.Fatal("Expected stack underflow error, got nil")
 	}
 }
+
+func TestSelfDestructOpCode_PreCancunDeletesAccount(t *testing.T) {
+	contractAddr := [20]byte{0xaa, 0xbb, 0xcc}
+	beneficiaryAddr := [20]byte{0x11, 0x22, 0x33}
+
+	tests := []struct {
+		fork   vm.Fork
+		refund uint64
+	}{
+		{vm.Istanbul, vm.SelfDestructRefundGas},
+		{vm.Berlin, vm.SelfDestructRefundGas},
+		{vm.London, 0},
+		{vm.Shanghai, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fork.String(), func(t *testing.T) {
+			code := append(push20(beneficiaryAddr), vm.SELFDESTRUCT)
+			v := vm.NewDebuggerVM(code, GetHandlerForFork(tt.fork))
+
+			mockState := NewMockStateProviderForSelfDestruct()
+			v.StateProvider = mockState
+			mockState.AddAccount(contractAddr, []byte{0x60, 0x01}, uint256.NewInt(1000))
+			mockState.AddAccount(beneficiaryAddr, []byte{}, uint256.NewInt(500))
+
+			v.Context = &vm.ExecutionContext{
+				Address: contractAddr,
+				Block:   &vm.BlockContext{},
+			}
+
+			for !v.Stopped {
+				if err := v.Step(); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			// Before Cancun the account is deleted even if it was not created in this
+			// transaction, but only at its end
+			if mockState.IsDeleted(contractAddr) || len(mockState.GetCode(contractAddr)) == 0 {
+				t.Error("Expected contract to exist until the end of the transaction")
+			}
+			if got := mockState.GetBalance(contractAddr); !got.IsZero() {
+				t.Errorf("Expected contract balance 0, got %s", got)
+			}
+			if err := v.DeleteScheduled(); err != nil {
+				t.Fatalf("Unexpected error deleting accounts: %v", err)
+			}
+			if !mockState.IsDeleted(contractAddr) {
+				t.Error("Expected contract to be deleted")
+			}
+			if got := mockState.GetBalance(beneficiaryAddr); got.Uint64() != 1500 {
+				t.Errorf("Expected beneficiary balance 1500, got %s", got)
+			}
+			if got := v.Refund(); got != tt.refund {
+				t.Errorf("Expected refund %d, got %d", tt.refund, got)
+			}
+		})
+	}
+}
+
+func TestSelfDestructOpCode_RefundOncePerAccount(t *testing.T) {
+	contractAddr := [20]byte{0xaa, 0xbb, 0xcc}
+
+	v := vm.NewDebuggerVM([]byte{vm.ADDRESS, vm.SELFDESTRUCT}, GetHandlerForFork(vm.Berlin))
+	mockState := NewMockStateProviderForSelfDestruct()
+	v.StateProvider = mockState
+	mockState.AddAccount(contractAddr, []byte{0x60, 0x01}, uint256.NewInt(0))
+	v.Context = &vm.ExecutionContext{Address: contractAddr, Block: &vm.BlockContext{}}
+
+	// A second SELFDESTRUCT of the same account in the transaction earns no refund
+	v.MarkSelfDestructed(contractAddr)
+	if err := v.RunUntil(nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := v.Refund(); got != 0 {
+		t.Errorf("Expected no refund, got %d", got)
+	}
+}
+
+func TestRefundCap(t *testing.T) {
+	// EIP-3529 lowered the cap from half to a fifth of the gas used
+	if got := vm.CappedRefund(vm.Berlin, 100_000, 60_000); got != 50_000 {
+		t.Errorf("Expected Berlin refund 50000, got %d", got)
+	}
+	if got := vm.CappedRefund(vm.London, 100_000, 60_000); got != 20_000 {
+		t.Errorf("Expected London refund 20000, got %d", got)
+	}
+	if got := vm.CappedRefund(vm.London, 100_000, 24_000-5_000); got != 19_000 {
+		t.Errorf("Expected uncapped refund 19000, got %d", got)
+	}
+}
+
+func TestSelfDestructDeletesAtEndOfTransaction(t *testing.T) {
+	caller := [20]byte{0xaa}
+	callee := [20]byte{19: 0xbb}
+	beneficiary := [20]byte{0xcc}
+
+	for _, revert := range []bool{false, true} {
+		// CALL the callee, then SSTORE(0, EXTCODESIZE(callee)) and STOP or REVERT(0, 0)
+		code := []byte{
+			vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0xbb, vm.GAS, vm.CALL, vm.POP,
+			vm.PUSH1, 0xbb, vm.EXTCODESIZE, vm.PUSH0, vm.SSTORE,
+		}
+		if revert {
+			code = append(code, vm.PUSH0, vm.PUSH0, vm.REVERT)
+		} else {
+			code = append(code, vm.STOP)
+		}
+		calleeCode := append(push20(beneficiary), vm.SELFDESTRUCT)
+
+		sp := state.NewMemoryState()
+		sp.AddAccount([20]byte{0x01}, nil, uint256.NewInt(0))
+		sp.AddAccount(caller, code, uint256.NewInt(0))
+		sp.AddAccount(callee, calleeCode, uint256.NewInt(1000))
+
+		s := vm.NewSession(sp, nil, GetHandlerForFork(vm.Shanghai), []vm.Transaction{{From: [20]byte{0x01}, To: &caller, Gas: 100000}})
+		if err := s.Run(); err != nil {
+			t.Fatalf("session error: %v", err)
+		}
+		if result := s.Results[0]; result.Err != nil {
+			t.Fatalf("unexpected transaction error: %v", result.Err)
+		}
+
+		if revert {
+			// The reverted transaction keeps the callee
+			if !sp.AccountExists(callee) || sp.GetBalance(callee).Uint64() != 1000 {
+				t.Error("expected the callee to be kept by the reverted transaction")
+			}
+			continue
+		}
+		// The code of the callee is still there after the SELFDESTRUCT in the same transaction
+		if got := sp.GetStorage(caller, uint256.NewInt(0)); got.Uint64() != uint64(len(calleeCode)) {
+			t.Errorf("expected the code size %d within the transaction, got %s", len(calleeCode), got.Hex())
+		}
+		if sp.AccountExists(callee) {
+			t.Error("expected the callee to be deleted at the end of the transaction")
+		}
+		if got := sp.GetBalance(beneficiary); got.Uint64() != 1000 {
+			t.Errorf("expected beneficiary balance 1000, got %s", got)
+		}
+	}
+}
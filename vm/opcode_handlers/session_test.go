@@ -0,0 +1,259 @@
+package opcode_handlers
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// sessionRuntimeCode returns [SLOAD(0), TLOAD(0)] when called without calldata,
+// otherwise stores CALLDATALOAD(0) in slot 0 and sets transient slot 0 to 1
+var sessionRuntimeCode = []byte{
+	vm.CALLDATASIZE,
+	vm.PUSH1, 0x15,
+	vm.JUMPI,
+	vm.PUSH1, 0x00, vm.SLOAD,
+	vm.PUSH1, 0x00, vm.MSTORE,
+	vm.PUSH1, 0x00, vm.TLOAD,
+	vm.PUSH1, 0x20, vm.MSTORE, // PC 15
+	vm.PUSH1, 0x40, vm.PUSH1, 0x00, vm.RETURN,
+	vm.JUMPDEST, // PC 21
+	vm.PUSH1, 0x00, vm.CALLDATALOAD,
+	vm.PUSH1, 0x00, vm.SSTORE,
+	vm.PUSH1, 0x01, vm.PUSH1, 0x00, vm.TSTORE,
+	vm.STOP,
+}
+
+func sessionInitCode() []byte {
+	size := byte(len(sessionRuntimeCode))
+	code := []byte{
+		vm.PUSH1, size, vm.PUSH1, 0x0c, vm.PUSH1, 0x00, vm.CODECOPY,
+		vm.PUSH1, size, vm.PUSH1, 0x00, vm.RETURN,
+	}
+	return append(code, sessionRuntimeCode...)
+}
+
+func TestSessionDeployInitializeUse(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+
+	contract := vm.CreateAddress(sender, 0)
+	txs := []vm.Transaction{
+		{From: sender, Data: sessionInitCode()},
+		{From: sender, To: &contract, Data: bytes32WithValue(uint256.NewInt(0x42))},
+		{From: sender, To: &contract},
+	}
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1}, GetHandler, txs)
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	if len(s.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(s.Results))
+	}
+	for i, r := range s.Results {
+		if r.Failed() {
+			t.Fatalf("transaction %d failed: reverted=%v err=%v", i, r.Reverted, r.Err)
+		}
+	}
+
+	if s.Results[0].ContractAddress == nil || *s.Results[0].ContractAddress != contract {
+		t.Fatalf("expected contract address %x, got %v", contract, s.Results[0].ContractAddress)
+	}
+	if !bytes.Equal(sp.GetCode(contract), sessionRuntimeCode) {
+		t.Fatalf("expected deployed runtime code, got %x", sp.GetCode(contract))
+	}
+
+	// Slot 0 was written by the second transaction, the transient slot was cleared in between
+	expected := append(bytes32WithValue(uint256.NewInt(0x42)), make([]byte, 32)...)
+	if !bytes.Equal(s.Results[2].ReturnValue, expected) {
+		t.Fatalf("expected return value %x, got %x", expected, s.Results[2].ReturnValue)
+	}
+	if got := sp.GetNonce(sender); got != 3 {
+		t.Fatalf("expected sender nonce 3, got %d", got)
+	}
+}
+
+func TestSessionRunUntilInLaterTransaction(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+
+	contract := vm.CreateAddress(sender, 0)
+	txs := []vm.Transaction{
+		{From: sender, Data: sessionInitCode()},
+		{From: sender, To: &contract, Data: bytes32WithValue(uint256.NewInt(0x42))},
+		{From: sender, To: &contract},
+	}
+
+	s := vm.NewSession(sp, nil, GetHandler, txs)
+
+	// Pause in the third transaction right before the MSTORE of the TLOAD result
+	if err := s.RunUntil(2, map[uint64]struct{}{15: {}}); err != nil {
+		t.Fatalf("RunUntil error: %v", err)
+	}
+	if s.CurrentTransaction() != 2 {
+		t.Fatalf("expected to be in transaction 2, got %d", s.CurrentTransaction())
+	}
+	if len(s.Results) != 2 {
+		t.Fatalf("expected 2 finished transactions, got %d", len(s.Results))
+	}
+
+	tload, err := s.VM.Stack().Peek(1)
+	if err != nil {
+		t.Fatalf("stack error: %v", err)
+	}
+	if !tload.IsZero() {
+		t.Fatalf("expected transient storage to be cleared between transactions, got %s", tload.Hex())
+	}
+
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	if !s.Done() {
+		t.Fatal("expected session to be done")
+	}
+	if err := s.Step(); !errors.Is(err, vm.ErrSessionDone) {
+		t.Fatalf("expected ErrSessionDone, got %v", err)
+	}
+}
+
+func TestSessionRevertRollsBackState(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+
+	// SSTORE(0, 1) then REVERT(0, 0)
+	sp.AddAccount(target, []byte{
+		vm.PUSH1, 0x01, vm.PUSH1, 0x00, vm.SSTORE,
+		vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.REVERT,
+	}, uint256.NewInt(0))
+
+	txs := []vm.Transaction{{From: sender, To: &target, Value: uint256.NewInt(100)}}
+	s := vm.NewSession(sp, nil, GetHandler, txs)
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	if !s.Results[0].Reverted {
+		t.Fatal("expected transaction to revert")
+	}
+	if got := sp.GetStorage(target, uint256.NewInt(0)); !got.IsZero() {
+		t.Fatalf("expected storage write to be rolled back, got %s", got.Hex())
+	}
+	if got := sp.GetBalance(sender); got.Cmp(uint256.NewInt(1000)) != 0 {
+		t.Fatalf("expected value transfer to be rolled back, got %s", got)
+	}
+	if got := sp.GetNonce(sender); got != 1 {
+		t.Fatalf("expected nonce increment to survive the revert, got %d", got)
+	}
+}
+
+// snapshotCounter is a MemoryState tracking the number of open snapshots
+type snapshotCounter struct {
+	*state.MemoryState
+	open int
+}
+
+func (s *snapshotCounter) Snapshot() int {
+	id := s.MemoryState.Snapshot()
+	s.open = id + 1
+	return id
+}
+
+func (s *snapshotCounter) RevertToSnapshot(id int) {
+	s.MemoryState.RevertToSnapshot(id)
+	s.open = min(s.open, id)
+}
+
+func (s *snapshotCounter) DiscardSnapshot(id int) {
+	s.MemoryState.DiscardSnapshot(id)
+	s.open = min(s.open, id)
+}
+
+func TestSessionReleasesSnapshots(t *testing.T) {
+	sp := &snapshotCounter{MemoryState: state.NewMemoryState()}
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	reverter := [20]byte{0xcc}
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	sp.AddAccount(target, []byte{vm.PUSH1, 0x01, vm.PUSH1, 0x00, vm.SSTORE, vm.STOP}, uint256.NewInt(0))
+	sp.AddAccount(reverter, []byte{vm.PUSH0, vm.PUSH0, vm.REVERT}, uint256.NewInt(0))
+
+	txs := []vm.Transaction{{From: sender, To: &target}, {From: sender, To: &reverter}, {From: sender, To: &target}}
+	s := vm.NewSession(sp, nil, GetHandler, txs)
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	if sp.open != 0 {
+		t.Fatalf("expected all snapshots to be released, %d are open", sp.open)
+	}
+	if got := sp.GetStorage(target, uint256.NewInt(0)); got.Uint64() != 1 {
+		t.Fatalf("expected the storage write to be kept, got %s", got.Hex())
+	}
+}
+
+func TestSessionInsufficientBalance(t *testing.T) {
+	sp := state.NewMemoryState()
+	sender := [20]byte{0xaa}
+	target := [20]byte{0xbb}
+	sp.AddAccount(sender, nil, uint256.NewInt(10))
+
+	txs := []vm.Transaction{
+		{From: sender, To: &target, Value: uint256.NewInt(100)},
+		{From: sender, To: &target, Value: uint256.NewInt(5)},
+	}
+	s := vm.NewSession(sp, nil, GetHandler, txs)
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+
+	if !errors.Is(s.Results[0].Err, vm.ErrInsufficientBalance) {
+		t.Fatalf("expected ErrInsufficientBalance, got %v", s.Results[0].Err)
+	}
+	if s.Results[1].Failed() {
+		t.Fatalf("expected second transaction to succeed, got %v", s.Results[1].Err)
+	}
+	if got := sp.GetBalance(target); got.Cmp(uint256.NewInt(5)) != 0 {
+		t.Fatalf("expected target balance 5, got %s", got)
+	}
+}
+
+func TestNestedRevertDoesNotLeakIntoCaller(t *testing.T) {
+	sp := state.NewMemoryState()
+	caller := [20]byte{0xaa}
+	var callee [20]byte
+	callee[19] = 0xbb
+	sp.AddAccount(callee, []byte{vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.REVERT}, uint256.NewInt(0))
+
+	// CALL(gas=100, address=0xbb, value=0, args=0/0, ret=0/0)
+	code := []byte{
+		vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00, vm.PUSH1, 0x00,
+		vm.PUSH1, 0xbb, vm.PUSH1, 0x64, vm.CALL,
+	}
+
+	d := vm.NewDebuggerVM(code, GetHandler)
+	d.StateProvider = sp
+	d.Context = &vm.ExecutionContext{Address: caller, Value: uint256.NewInt(0)}
+
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			t.Fatalf("execution error: %v", err)
+		}
+	}
+
+	if d.Reverted {
+		t.Fatal("revert of the callee leaked into the caller")
+	}
+	result, _ := d.Stack().Pop()
+	if !result.IsZero() {
+		t.Fatalf("expected CALL to push 0 for a reverted callee, got %s", result)
+	}
+}
@@ -0,0 +1,38 @@
+package opcode_handlers
+
+import "github.com/daniellehrner/evmdbg/vm"
+
+// callSnapshot records what a failing call rolls back: the state, if the state provider
+// supports snapshots, the logs emitted so far and the access list
+type callSnapshot struct {
+	state      int
+	logs       int
+	accessList int
+}
+
+// snapshot records the state, the logs and the access list before a call
+func snapshot(v *vm.DebuggerVM) callSnapshot {
+	snap := callSnapshot{state: -1, logs: len(v.Logs), accessList: v.AccessListSnapshot()}
+	if s, ok := v.StateProvider.(vm.Snapshotter); ok {
+		snap.state = s.Snapshot()
+	}
+	return snap
+}
+
+// endSnapshot rolls the state, the logs and the access list back to the snapshot if the
+// call failed and releases the state snapshot otherwise
+func endSnapshot(v *vm.DebuggerVM, snap callSnapshot, failed bool) {
+	if failed {
+		v.Logs = v.Logs[:snap.logs]
+		v.RevertAccessList(snap.accessList)
+	}
+	s, ok := v.StateProvider.(vm.Snapshotter)
+	if !ok || snap.state < 0 {
+		return
+	}
+	if failed {
+		s.RevertToSnapshot(snap.state)
+	} else {
+		s.DiscardSnapshot(snap.state)
+	}
+}
@@ -0,0 +1,25 @@
+package opcode_handlers
+
+import "github.com/daniellehrner/evmdbg/vm"
+
+type SwapNOpCode struct{}
+
+func (*SwapNOpCode) Execute(v *vm.DebuggerVM) error {
+	if err := v.RequireEOF(vm.SWAPN); err != nil {
+		return err
+	}
+
+	// SWAPN swaps the top with the item at depth imm+1, so SWAPN 0 behaves like SWAP1
+	imm, err := v.ReadCodeByte(0)
+	if err != nil {
+		return err
+	}
+	v.AdvancePC(1)
+
+	n := int(imm) + 1
+	if err := v.RequireStack(n + 1); err != nil {
+		return err
+	}
+
+	return v.Stack().Swap(n)
+}
@@ -0,0 +1,198 @@
+package opcode_handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/daniellehrner/evmdbg/state"
+	"github.com/daniellehrner/evmdbg/vm"
+	"github.com/holiman/uint256"
+)
+
+// eventTracer records the tracer events as strings, addresses are shortened to their last byte
+type eventTracer struct {
+	vm.NoopTracer
+	steps  []vm.StepState
+	events []string
+	faults []error
+}
+
+func (t *eventTracer) OnStepStart(step *vm.StepState) {
+	t.steps = append(t.steps, *step)
+}
+
+func (t *eventTracer) OnFault(step *vm.StepState, err error) {
+	t.faults = append(t.faults, err)
+}
+
+func (t *eventTracer) OnEnter(f *vm.CallFrame) {
+	t.events = append(t.events, fmt.Sprintf("enter %d type=%d %02x->%02x input=%x value=%v", f.Depth, f.Type, f.From[19], f.To[19], f.Input, f.Value))
+}
+
+func (t *eventTracer) OnExit(f *vm.CallFrame, output []byte, gasUsed uint64, err error) {
+	t.events = append(t.events, fmt.Sprintf("exit %d output=%x err=%v", f.Depth, output, err))
+}
+
+func (t *eventTracer) OnStorageChange(addr [20]byte, slot, prev, value *uint256.Int) {
+	t.events = append(t.events, fmt.Sprintf("storage %x %v: %v->%v", addr[19], slot, prev, value))
+}
+
+func (t *eventTracer) OnTransientStorageChange(addr [20]byte, slot, prev, value *uint256.Int) {
+	t.events = append(t.events, fmt.Sprintf("transient %x %v: %v->%v", addr[19], slot, prev, value))
+}
+
+func (t *eventTracer) OnBalanceChange(addr [20]byte, prev, balance *uint256.Int) {
+	t.events = append(t.events, fmt.Sprintf("balance %x: %v->%v", addr[19], prev, balance))
+}
+
+func (t *eventTracer) OnNonceChange(addr [20]byte, prev, nonce uint64) {
+	t.events = append(t.events, fmt.Sprintf("nonce %x: %d->%d", addr[19], prev, nonce))
+}
+
+func (t *eventTracer) OnLog(log *vm.LogEntry) {
+	t.events = append(t.events, fmt.Sprintf("log %x topics=%d", log.Address[19], len(log.Topics)))
+}
+
+func TestTracerSteps(t *testing.T) {
+	d := vm.NewDebuggerVM([]byte{vm.PUSH1, 0x02, vm.PUSH1, 0x03, vm.ADD, vm.STOP}, GetHandler)
+	d.GasSchedule = vm.LondonGasSchedule
+	d.Context = &vm.ExecutionContext{Address: [20]byte{19: 0xaa}, Value: uint256.NewInt(0), Gas: 100}
+	tracer := &eventTracer{}
+	d.Tracer = tracer
+
+	for !d.Stopped {
+		if err := d.Step(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expected := []struct {
+		pc, gas, cost uint64
+		op            byte
+	}{
+		{0, 100, 3, vm.PUSH1},
+		{2, 97, 3, vm.PUSH1},
+		{4, 94, 3, vm.ADD},
+		{5, 91, 0, vm.STOP},
+	}
+	if len(tracer.steps) != len(expected) {
+		t.Fatalf("expected %d steps, got %d", len(expected), len(tracer.steps))
+	}
+	for i, e := range expected {
+		s := tracer.steps[i]
+		if s.PC != e.pc || s.Op != e.op || s.Gas != e.gas || s.Cost != e.cost || s.Depth != 1 {
+			t.Errorf("step %d: expected pc=%d op=0x%x gas=%d cost=%d depth=1, got pc=%d op=0x%x gas=%d cost=%d depth=%d",
+				i, e.pc, e.op, e.gas, e.cost, s.PC, s.Op, s.Gas, s.Cost, s.Depth)
+		}
+	}
+	if top, _ := tracer.steps[3].Stack.Peek(0); top.Uint64() != 5 {
+		t.Errorf("expected the sum on the stack before STOP, got %v", top)
+	}
+}
+
+func TestTracerFault(t *testing.T) {
+	d := vm.NewDebuggerVM([]byte{vm.PUSH1, 0x05, vm.JUMP}, GetHandler)
+	d.Context = &vm.ExecutionContext{Value: uint256.NewInt(0)}
+	tracer := &eventTracer{}
+	d.Tracer = tracer
+
+	var err error
+	for err == nil && !d.Stopped {
+		err = d.Step()
+	}
+	if !errors.Is(err, vm.ErrInvalidJump) {
+		t.Fatalf("expected ErrInvalidJump, got %v", err)
+	}
+	if len(tracer.faults) != 1 || !errors.Is(tracer.faults[0], vm.ErrInvalidJump) {
+		t.Fatalf("expected a single invalid jump fault, got %v", tracer.faults)
+	}
+	if last := tracer.steps[len(tracer.steps)-1]; last.Op != vm.JUMP || last.PC != 2 {
+		t.Errorf("expected the fault at JUMP, got op 0x%x at pc %d", last.Op, last.PC)
+	}
+}
+
+func TestTracerSessionEvents(t *testing.T) {
+	sender := [20]byte{19: 0xaa}
+	caller := [20]byte{19: 0xbb}
+	callee := [20]byte{19: 0xcc}
+
+	sp := state.NewMemoryState()
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	// Calls 0xcc, then the identity precompile with the byte 0x2a as input
+	sp.AddAccount(caller, []byte{
+		vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0xcc, vm.GAS, vm.CALL, vm.POP,
+		vm.PUSH1, 0x2a, vm.PUSH0, vm.MSTORE8,
+		vm.PUSH0, vm.PUSH0, vm.PUSH1, 0x01, vm.PUSH0, vm.PUSH1, 0x04, vm.GAS, vm.STATICCALL,
+		vm.STOP,
+	}, uint256.NewInt(0))
+	// Writes storage and transient storage and emits a log
+	sp.AddAccount(callee, []byte{
+		vm.PUSH1, 0x01, vm.PUSH0, vm.SSTORE,
+		vm.PUSH1, 0x02, vm.PUSH0, vm.TSTORE,
+		vm.PUSH0, vm.PUSH0, vm.LOG0,
+		vm.STOP,
+	}, uint256.NewInt(0))
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1}, GetHandler, []vm.Transaction{
+		{From: sender, To: &caller, Value: uint256.NewInt(5), Gas: 100000},
+	})
+	tracer := &eventTracer{}
+	s.VM.Tracer = tracer
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	if s.Results[0].Failed() {
+		t.Fatalf("transaction failed: %v", s.Results[0].Err)
+	}
+
+	expected := []string{
+		"nonce aa: 0->1",
+		"balance aa: 1000->995",
+		"balance bb: 0->5",
+		"enter 1 type=0 aa->bb input= value=5",
+		"enter 2 type=0 bb->cc input= value=0",
+		"storage cc 0: 0->1",
+		"transient cc 0: 0->2",
+		"log cc topics=0",
+		"exit 2 output= err=<nil>",
+		"enter 2 type=3 bb->04 input=2a value=<nil>",
+		"exit 2 output=2a err=<nil>",
+		"exit 1 output= err=<nil>",
+	}
+	if !slices.Equal(tracer.events, expected) {
+		t.Fatalf("unexpected events:\n got %q\nwant %q", tracer.events, expected)
+	}
+
+	// Steps of the nested frame are reported at depth 2
+	depths := make(map[int]int)
+	for _, step := range tracer.steps {
+		depths[step.Depth]++
+	}
+	if depths[1] != 20 || depths[2] != 10 {
+		t.Errorf("expected 20 steps at depth 1 and 10 at depth 2, got %v", depths)
+	}
+}
+
+func TestTracerPrecompileCallValue(t *testing.T) {
+	sender := [20]byte{19: 0xaa}
+	caller := [20]byte{19: 0xbb}
+
+	sp := state.NewMemoryState()
+	sp.AddAccount(sender, nil, uint256.NewInt(1000))
+	// CALLs the identity precompile with a value of 3
+	sp.AddAccount(caller, []byte{
+		vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH0, vm.PUSH1, 0x03, vm.PUSH1, 0x04, vm.GAS, vm.CALL, vm.STOP,
+	}, uint256.NewInt(10))
+
+	s := vm.NewSession(sp, &vm.BlockContext{Number: 1}, GetHandler, []vm.Transaction{{From: sender, To: &caller, Gas: 100000}})
+	tracer := &eventTracer{}
+	s.VM.Tracer = tracer
+	if err := s.Run(); err != nil {
+		t.Fatalf("session error: %v", err)
+	}
+	if !slices.Contains(tracer.events, "enter 2 type=0 bb->04 input= value=3") {
+		t.Fatalf("expected the precompile frame to carry the value, got %q", tracer.events)
+	}
+}
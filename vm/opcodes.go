@@ -29,6 +29,7 @@ const (
 	SHL            = 0x1b
 	SHR            = 0x1c
 	SAR            = 0x1d
+	CLZ            = 0x1e
 	SHA3           = 0x20
 	ADDRESS        = 0x30
 	BALANCE        = 0x31
@@ -51,6 +52,7 @@ const (
 	TIMESTAMP      = 0x42
 	NUMBER         = 0x43
 	DIFFICULTY     = 0x44
+	PREVRANDAO     = 0x44 // EIP-4399: DIFFICULTY since the merge
 	GASLIMIT       = 0x45
 	CHAINID        = 0x46
 	SELFBALANCE    = 0x47
@@ -142,14 +144,36 @@ const (
 	LOG2           = 0xa2
 	LOG3           = 0xa3
 	LOG4           = 0xa4
-	CREATE         = 0xf0
-	CALL           = 0xf1
-	CALLCODE       = 0xf2
-	RETURN         = 0xf3
-	DELEGATECALL   = 0xf4
-	CREATE2        = 0xf5
-	STATICCALL     = 0xfa
-	REVERT         = 0xfd
-	INVALID        = 0xfe
-	SELFDESTRUCT   = 0xff
+
+	// EOF-only instructions (EIP-3540 and related)
+	DATALOAD        = 0xd0
+	DATALOADN       = 0xd1
+	DATASIZE        = 0xd2
+	DATACOPY        = 0xd3
+	RJUMP           = 0xe0
+	RJUMPI          = 0xe1
+	RJUMPV          = 0xe2
+	CALLF           = 0xe3
+	RETF            = 0xe4
+	JUMPF           = 0xe5
+	DUPN            = 0xe6
+	SWAPN           = 0xe7
+	EXCHANGE        = 0xe8
+	EOFCREATE       = 0xec
+	RETURNCONTRACT  = 0xee
+	RETURNDATALOAD  = 0xf7
+	EXTCALL         = 0xf8
+	EXTDELEGATECALL = 0xf9
+	EXTSTATICCALL   = 0xfb
+
+	CREATE       = 0xf0
+	CALL         = 0xf1
+	CALLCODE     = 0xf2
+	RETURN       = 0xf3
+	DELEGATECALL = 0xf4
+	CREATE2      = 0xf5
+	STATICCALL   = 0xfa
+	REVERT       = 0xfd
+	INVALID      = 0xfe
+	SELFDESTRUCT = 0xff
 )
@@ -0,0 +1,233 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/daniellehrner/evmdbg/precompiles"
+	"github.com/holiman/uint256"
+)
+
+// PrecompiledContract is a contract implemented natively instead of in EVM bytecode
+type PrecompiledContract interface {
+	// RequiredGas returns the gas charged for running the contract with input
+	RequiredGas(input []byte) uint64
+	// Run executes the contract. An error fails the call and consumes all of its gas.
+	Run(input []byte) ([]byte, error)
+}
+
+// PrecompiledContracts maps addresses to precompiled contracts
+type PrecompiledContracts map[[20]byte]PrecompiledContract
+
+// PrecompileAddress returns the address of the precompile with the given number, e.g.
+// 0x01 for ECRECOVER
+func PrecompileAddress(n uint16) [20]byte {
+	var addr [20]byte
+	addr[18] = byte(n >> 8)
+	addr[19] = byte(n)
+	return addr
+}
+
+// forkPrecompiles holds the precompiled contracts active in each fork
+var forkPrecompiles [NumForks]PrecompiledContracts
+
+func init() {
+	for i := range forkPrecompiles {
+		forkPrecompiles[i] = newForkPrecompiles(Fork(i))
+	}
+}
+
+// newForkPrecompiles returns the precompiled contracts of the Ethereum mainnet in fork
+func newForkPrecompiles(fork Fork) PrecompiledContracts {
+	p := PrecompiledContracts{
+		PrecompileAddress(0x01): &precompiles.Ecrecover{},
+		PrecompileAddress(0x02): &precompiles.SHA256{},
+		PrecompileAddress(0x03): &precompiles.RIPEMD160{},
+		PrecompileAddress(0x04): &precompiles.Identity{},
+	}
+	if fork >= Byzantium {
+		// EIP-198, repriced in Berlin (EIP-2565) and bounded and repriced in Osaka (EIP-7823, EIP-7883)
+		p[PrecompileAddress(0x05)] = &precompiles.ModExp{
+			EIP2565: fork >= Berlin,
+			EIP7823: fork >= Osaka,
+			EIP7883: fork >= Osaka,
+		}
+		// EIP-196 and EIP-197, repriced in Istanbul (EIP-1108)
+		p[PrecompileAddress(0x06)] = &precompiles.BN254Add{EIP1108: fork >= Istanbul}
+		p[PrecompileAddress(0x07)] = &precompiles.BN254Mul{EIP1108: fork >= Istanbul}
+		p[PrecompileAddress(0x08)] = &precompiles.BN254Pairing{EIP1108: fork >= Istanbul}
+	}
+	if fork >= Istanbul {
+		// EIP-152
+		p[PrecompileAddress(0x09)] = &precompiles.Blake2F{}
+	}
+	if fork >= Cancun {
+		// EIP-4844
+		p[PrecompileAddress(0x0a)] = &precompiles.PointEvaluation{}
+	}
+	if fork >= Prague {
+		// EIP-2537
+		p[PrecompileAddress(0x0b)] = &precompiles.BLS12381G1Add{}
+		p[PrecompileAddress(0x0c)] = &precompiles.BLS12381G1MSM{}
+		p[PrecompileAddress(0x0d)] = &precompiles.BLS12381G2Add{}
+		p[PrecompileAddress(0x0e)] = &precompiles.BLS12381G2MSM{}
+		p[PrecompileAddress(0x0f)] = &precompiles.BLS12381Pairing{}
+		p[PrecompileAddress(0x10)] = &precompiles.BLS12381MapG1{}
+		p[PrecompileAddress(0x11)] = &precompiles.BLS12381MapG2{}
+	}
+	if fork >= Osaka {
+		// EIP-7951
+		p[PrecompileAddress(0x100)] = &precompiles.P256Verify{}
+	}
+	return p
+}
+
+// PrecompilesForFork returns the precompiled contracts of the Ethereum mainnet in fork.
+// The returned map is shared and must not be modified.
+func PrecompilesForFork(fork Fork) PrecompiledContracts {
+	if fork < 0 || int(fork) >= NumForks {
+		return nil
+	}
+	return forkPrecompiles[fork]
+}
+
+// RegisterPrecompile adds a precompiled contract at an arbitrary address of this VM, taking
+// precedence over the chain rules and the precompiles of the active fork. A nil contract
+// removes the registration.
+func (vm *DebuggerVM) RegisterPrecompile(addr [20]byte, p PrecompiledContract) {
+	if p == nil {
+		delete(vm.precompiles, addr)
+		return
+	}
+	if vm.precompiles == nil {
+		vm.precompiles = make(PrecompiledContracts)
+	}
+	vm.precompiles[addr] = p
+}
+
+// Precompile returns the precompiled contract at addr, if any. Registered contracts take
+// precedence over the chain rules, which take precedence over the active fork.
+func (vm *DebuggerVM) Precompile(addr [20]byte) (PrecompiledContract, bool) {
+	if p, ok := vm.precompiles[addr]; ok {
+		return p, true
+	}
+	if vm.ChainRules != nil {
+		if p, ok := vm.ChainRules.Precompiles[addr]; ok {
+			return p, true
+		}
+	}
+	p, ok := PrecompilesForFork(vm.Fork())[addr]
+	return p, ok
+}
+
+// ActivePrecompiles returns all precompiled contracts callable from this VM
+func (vm *DebuggerVM) ActivePrecompiles() PrecompiledContracts {
+	active := make(PrecompiledContracts)
+	for addr, p := range PrecompilesForFork(vm.Fork()) {
+		active[addr] = p
+	}
+	if vm.ChainRules != nil {
+		for addr, p := range vm.ChainRules.Precompiles {
+			active[addr] = p
+		}
+	}
+	for addr, p := range vm.precompiles {
+		active[addr] = p
+	}
+	return active
+}
+
+// PrecompileCall is a synthetic debugger frame describing a call to a precompiled
+// contract, which runs natively instead of stepping through code
+type PrecompileCall struct {
+	Address  [20]byte
+	Name     string
+	CallType CallType
+	Depth    int // call depth of the precompile frame, the outermost frame has depth 1
+
+	Input  []byte
+	Fields []precompiles.InputField // decoded input, nil if the contract does not decode it
+	Output []byte
+
+	Gas     uint64 // gas available to the call
+	GasUsed uint64 // gas charged: the required gas on success, all gas on failure
+	Err     error  // failure reason, nil on success
+}
+
+// Failed reports whether the precompile call failed
+func (c *PrecompileCall) Failed() bool {
+	return c.Err != nil
+}
+
+// PrecompileName returns the name of the precompiled contract p at addr. Contracts without a
+// Name method are named after their address.
+func PrecompileName(addr [20]byte, p PrecompiledContract) string {
+	if named, ok := p.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return fmt.Sprintf("precompile 0x%x", addr)
+}
+
+// PrecompileCalls returns the precompile calls of the current execution in call order
+func (vm *DebuggerVM) PrecompileCalls() []*PrecompileCall {
+	return vm.precompileCalls
+}
+
+// RunPrecompile executes the precompiled contract p at addr with the given input, value (nil
+// if the call carries none) and gas limit, stores its output as the return data of the call and records the call in
+// PrecompileCalls. A call with less gas than required fails with ErrOutOfGas even if gas is
+// not metered, which bounds the work of contracts priced by their input such as MODEXP.
+// While gas is metered, the required gas is charged to the executing frame.
+func (vm *DebuggerVM) RunPrecompile(addr [20]byte, p PrecompiledContract, callType CallType, input []byte, value *uint256.Int, gas uint64) ([]byte, error) {
+	call := &PrecompileCall{
+		Address:  addr,
+		Name:     PrecompileName(addr, p),
+		CallType: callType,
+		Depth:    vm.CallDepth() + 1,
+		Input:    input,
+		Gas:      gas,
+	}
+	if decoder, ok := p.(interface {
+		DecodeInput([]byte) []precompiles.InputField
+	}); ok {
+		call.Fields = decoder.DecodeInput(input)
+	}
+	vm.precompileCalls = append(vm.precompileCalls, call)
+	frame := vm.EnterFrame(callType, addr, input, value, gas)
+
+	vm.lastReturnData = nil
+	required := p.RequiredGas(input)
+	if required > gas || (vm.GasSchedule != nil && required > vm.Context.Gas) {
+		call.GasUsed, call.Err = gas, ErrOutOfGas
+		if vm.GasSchedule != nil {
+			call.GasUsed = vm.chargePrecompile(gas)
+		}
+		vm.ExitFrame(frame, nil, ErrOutOfGas)
+		return nil, ErrOutOfGas
+	}
+
+	output, err := p.Run(input)
+	if err != nil {
+		// A failing precompile consumes all of its gas
+		call.GasUsed, call.Err = gas, err
+		if vm.GasSchedule != nil {
+			call.GasUsed = vm.chargePrecompile(gas)
+		}
+		vm.ExitFrame(frame, nil, err)
+		return nil, err
+	}
+	call.GasUsed, call.Output = required, output
+	if vm.GasSchedule != nil {
+		vm.chargePrecompile(required)
+	}
+	vm.lastReturnData = output
+	vm.ExitFrame(frame, output, nil)
+	return output, nil
+}
+
+// chargePrecompile charges gas used by a precompile to the executing frame and returns the
+// charge, which is capped at the gas left
+func (vm *DebuggerVM) chargePrecompile(gas uint64) uint64 {
+	gas = min(gas, vm.Context.Gas)
+	_ = vm.UseGas(gas)
+	return gas
+}
@@ -0,0 +1,23 @@
+package vm
+
+// Gas refund constants
+const (
+	SelfDestructRefundGas  = 24000 // refund for destroying an account, removed in London (EIP-3529)
+	AuthorizationRefundGas = 12500 // refund for an EIP-7702 authorization of an existing account
+
+	RefundQuotient        = 2 // refunds are capped at gasUsed / RefundQuotient before London
+	RefundQuotientEIP3529 = 5 // refunds are capped at gasUsed / RefundQuotientEIP3529 since London
+)
+
+// MaxRefund returns the maximum refund for a transaction that used gasUsed gas under fork
+func MaxRefund(fork Fork, gasUsed uint64) uint64 {
+	if fork >= London {
+		return gasUsed / RefundQuotientEIP3529
+	}
+	return gasUsed / RefundQuotient
+}
+
+// CappedRefund applies the refund cap of fork to the refund counter
+func CappedRefund(fork Fork, gasUsed, refund uint64) uint64 {
+	return min(refund, MaxRefund(fork, gasUsed))
+}
@@ -0,0 +1,524 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/daniellehrner/evmdbg/rlp"
+	"github.com/holiman/uint256"
+	"golang.org/x/crypto/sha3"
+)
+
+// Errors
+var (
+	ErrSessionDone          = errors.New("all transactions of the session have been executed")
+	ErrInsufficientBalance  = errors.New("insufficient balance for transfer")
+	ErrNoStateProvider      = errors.New("session requires a state provider")
+	ErrInvalidTransactionID = errors.New("transaction index out of range")
+	ErrGasLimitTooHigh      = errors.New("transaction gas limit exceeds the cap")
+	ErrAccessListNotActive  = errors.New("access lists are not enabled before Berlin")
+	ErrStateUnavailable     = errors.New("state unavailable")
+	ErrMaxCodeSizeExceeded  = errors.New("max code size exceeded")
+	ErrCodeStoreOutOfGas    = errors.New("contract creation code storage out of gas")
+)
+
+// MaxTxGas is the transaction gas limit cap introduced in Osaka (EIP-7825)
+const MaxTxGas = 1 << 24
+
+// MaxCodeSize is the size limit of deployed code introduced in Spurious Dragon (EIP-170)
+const MaxCodeSize = 24576
+
+// Transaction is a message executed by a Session
+type Transaction struct {
+	From     [20]byte
+	To       *[20]byte // nil deploys a contract with Data as init code
+	Value    *uint256.Int
+	Data     []byte
+	Gas      uint64
+	GasPrice *uint256.Int
+
+	// AccessList holds the accounts and slots that start warm (EIP-2930)
+	AccessList []AccessTuple
+
+	// AuthorizationList makes this an EIP-7702 set code transaction
+	AuthorizationList []SetCodeAuthorization
+
+	// Type is the EIP-2718 transaction type. It only needs to be set for chain specific
+	// types handled by ChainRules, such as OP Stack deposits.
+	Type byte
+
+	// OP Stack deposit fields: SourceHash identifies the L1 deposit and Mint is credited to
+	// From before the transaction executes
+	SourceHash [32]byte
+	Mint       *uint256.Int
+	IsSystemTx bool
+
+	// Encoded is the transaction as included in the block, needed by chains that charge
+	// for data availability
+	Encoded []byte
+}
+
+// AccessTuple is an entry of the access list of a transaction
+type AccessTuple struct {
+	Address     [20]byte
+	StorageKeys [][32]byte
+}
+
+// TransactionResult is the outcome of a transaction executed by a Session
+type TransactionResult struct {
+	ReturnValue     []byte
+	Reverted        bool
+	Logs            []LogEntry // empty for failed and reverted transactions
+	Err             error      // exceptional halt or validation failure
+	ContractAddress *[20]byte  // set for contract creations
+
+	// Refund is the gas refund counter at the end of the transaction, before CappedRefund
+	// is applied. Failed transactions only keep the refund of their authorizations.
+	Refund uint64
+
+	// GasUsed is the gas used by the transaction after refunds, only set if the VM has a
+	// GasSchedule. Exceptional halts consume the whole gas limit.
+	GasUsed uint64
+
+	// AuthorizationErrors holds the reason each authorization was skipped, nil if it was applied
+	AuthorizationErrors []error
+
+	// Extra holds chain specific receipt fields set by ChainRules.AfterTransaction
+	Extra any
+}
+
+// Failed reports whether the transaction reverted or halted exceptionally
+func (r *TransactionResult) Failed() bool {
+	return r.Reverted || r.Err != nil
+}
+
+// Session executes an ordered list of transactions against shared state. Transient storage
+// and the EIP-6780 creation tracking are cleared at every transaction boundary, and each
+// transaction can be stepped through like a single DebuggerVM execution.
+//
+// If the StateProvider implements Snapshotter, state changes of failed transactions are
+// rolled back. A StateProvider that cannot serve a read may panic with an error wrapping
+// ErrStateUnavailable, which fails the transaction with that error.
+type Session struct {
+	VM           *DebuggerVM
+	Block        *BlockContext
+	Transactions []Transaction
+	Results      []TransactionResult
+
+	current  int
+	started  bool
+	snapshot int // -1 if the state provider does not support snapshots
+	created  *[20]byte
+	frame    *CallFrame // the traced outermost frame, nil until it is entered
+
+	authErrors []error
+	authRefund uint64 // the refund of authorizations, kept if the transaction fails
+
+	metered      bool // the transaction passed validation and is charged gas
+	intrinsicGas uint64
+}
+
+// NewSession creates a session executing txs in order against sp
+func NewSession(sp StateProvider, block *BlockContext, hg HandlerGetter, txs []Transaction) *Session {
+	v := NewDebuggerVM(nil, hg)
+	v.StateProvider = sp
+
+	return &Session{
+		VM:           v,
+		Block:        block,
+		Transactions: txs,
+	}
+}
+
+// Done reports whether all transactions have been executed
+func (s *Session) Done() bool {
+	return s.current >= len(s.Transactions)
+}
+
+// CurrentTransaction returns the index of the transaction being executed, or the index of
+// the next transaction if the current one has not started yet
+func (s *Session) CurrentTransaction() int {
+	return s.current
+}
+
+// Step executes a single instruction of the current transaction, starting it first if needed.
+// Failures of the transaction itself are recorded in its result and do not return an error.
+func (s *Session) Step() error {
+	if s.Done() {
+		return ErrSessionDone
+	}
+
+	if !s.started {
+		if err := recoverStateUnavailable(s.begin); err != nil {
+			s.finish(err)
+			return nil
+		}
+		if s.executionFinished() {
+			s.finish(nil)
+		}
+		return nil
+	}
+
+	if err := recoverStateUnavailable(s.VM.Step); err != nil {
+		s.finish(err)
+		return nil
+	}
+
+	if s.executionFinished() {
+		s.finish(nil)
+	}
+	return nil
+}
+
+// recoverStateUnavailable runs fn and returns the error of a panic wrapping
+// ErrStateUnavailable, other panics are not recovered
+func recoverStateUnavailable(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok && errors.Is(e, ErrStateUnavailable) {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+	return fn()
+}
+
+// Run executes all remaining transactions
+func (s *Session) Run() error {
+	for !s.Done() {
+		if err := s.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunUntil executes until transaction txIndex is about to execute an instruction at one of
+// the given program counters of its root frame. It returns ErrSessionDone if that
+// transaction finishes without reaching a breakpoint.
+func (s *Session) RunUntil(txIndex int, breakpoints map[uint64]struct{}) error {
+	if txIndex < 0 || txIndex >= len(s.Transactions) {
+		return ErrInvalidTransactionID
+	}
+
+	for !s.Done() && s.current <= txIndex {
+		if s.current == txIndex && s.started {
+			if _, ok := breakpoints[s.VM.PC()]; ok {
+				return nil
+			}
+		}
+		if err := s.Step(); err != nil {
+			return err
+		}
+	}
+	return ErrSessionDone
+}
+
+// begin prepares the VM for the current transaction: clears transaction scoped state,
+// increments the sender nonce, transfers value and loads the code to execute
+func (s *Session) begin() error {
+	s.started = true
+	s.created = nil
+	s.frame = nil
+	s.snapshot = -1
+	s.authErrors = nil
+	s.authRefund = 0
+	s.metered = false
+
+	v := s.VM
+	v.ClearTransientStorage()
+	v.ClearCreatedInTransaction()
+	v.ClearSelfDestructed()
+	v.ClearRefund()
+	v.ClearGasUsed()
+	v.ResetExecution(nil)
+
+	sp := v.StateProvider
+	if sp == nil {
+		return ErrNoStateProvider
+	}
+
+	if rules := v.ChainRules; rules != nil && rules.BeforeTransaction != nil {
+		if err := rules.BeforeTransaction(s, &s.Transactions[s.current]); err != nil {
+			return err
+		}
+	}
+
+	tx := s.Transactions[s.current]
+
+	value := tx.Value
+	if value == nil {
+		value = uint256.NewInt(0)
+	}
+
+	if sp.GetBalance(tx.From).Cmp(value) < 0 {
+		return ErrInsufficientBalance
+	}
+
+	fork := ForkAt(s.VM.ChainConfig, s.Block)
+	if fork >= Osaka && tx.Gas > MaxTxGas {
+		return fmt.Errorf("%w: %d > %d", ErrGasLimitTooHigh, tx.Gas, MaxTxGas)
+	}
+
+	if len(tx.AccessList) > 0 && fork < Berlin {
+		return ErrAccessListNotActive
+	}
+
+	if len(tx.AuthorizationList) > 0 {
+		if fork < Prague {
+			return ErrSetCodeTxNotActive
+		}
+		if tx.To == nil {
+			return ErrSetCodeTxCreate
+		}
+	}
+
+	gas := tx.Gas
+	if v.GasSchedule != nil {
+		s.intrinsicGas = v.GasSchedule.IntrinsicGas(&tx)
+		if gas < s.intrinsicGas {
+			return fmt.Errorf("%w: %d < %d", ErrIntrinsicGas, gas, s.intrinsicGas)
+		}
+		gas -= s.intrinsicGas
+		s.metered = true
+	}
+
+	// The nonce increment and the authorizations survive a failing transaction, so they
+	// happen before the snapshot
+	nonce := sp.GetNonce(tx.From)
+	v.SetNonce(tx.From, nonce+1)
+	s.applyAuthorizations(tx.AuthorizationList)
+
+	if snap, ok := sp.(Snapshotter); ok {
+		s.snapshot = snap.Snapshot()
+	}
+
+	var to [20]byte
+	var code, callData []byte
+	if tx.To == nil {
+		to = CreateAddress(tx.From, nonce)
+		if sp.AccountExists(to) && (sp.GetNonce(to) != 0 || len(sp.GetCode(to)) != 0) {
+			return fmt.Errorf("contract address collision at %x", to)
+		}
+		if err := v.CreateAccount(to, nil, sp.GetBalance(to)); err != nil {
+			return err
+		}
+		v.SetNonce(to, 1) // EIP-161: contracts start with nonce 1
+		v.MarkAccountCreatedInTransaction(to)
+		s.created = &to
+		code = tx.Data
+	} else {
+		to = *tx.To
+		code = v.ResolveCode(to)
+		callData = tx.Data
+	}
+
+	// Transfer value from sender to recipient
+	if !value.IsZero() {
+		v.SetBalance(tx.From, new(uint256.Int).Sub(sp.GetBalance(tx.From), value))
+		v.SetBalance(to, new(uint256.Int).Add(sp.GetBalance(to), value))
+	}
+
+	// With EOF enabled, create transactions carry an initcode container followed by
+	// its call data
+	if v.eofEnabled && HasEOFMagic(code) {
+		var c *EOFContainer
+		var err error
+		if tx.To == nil {
+			c, callData, err = SplitEOFInitcode(code)
+		} else {
+			c, err = ParseEOF(code, false)
+		}
+		if err != nil {
+			return err
+		}
+		v.ResetEOFExecution(c)
+	} else {
+		v.ResetExecution(code)
+	}
+
+	v.Context = &ExecutionContext{
+		Caller:   tx.From,
+		Address:  to,
+		Origin:   tx.From,
+		Value:    new(uint256.Int).Set(value),
+		CallData: callData,
+		GasPrice: tx.GasPrice,
+		Gas:      gas,
+		Balance:  sp.GetBalance(to),
+		Block:    s.Block,
+	}
+
+	callType := CallTypeCall
+	if tx.To == nil {
+		callType = CallTypeCreate
+	}
+	s.frame = v.enterFrame(1, callType, tx.From, to, tx.Data, value, tx.Gas)
+
+	// The sender, the recipient and, since Shanghai (EIP-3651), the coinbase start warm
+	v.WarmAddress(tx.From)
+	v.WarmAddress(to)
+	if s.Block != nil && fork >= Shanghai {
+		v.WarmAddress(s.Block.Coinbase)
+	}
+	// Precompiled contracts are always warm (EIP-2929)
+	if fork >= Berlin {
+		for addr := range v.ActivePrecompiles() {
+			v.WarmAddress(addr)
+		}
+	}
+	for _, tuple := range tx.AccessList {
+		v.WarmAddress(tuple.Address)
+		for _, key := range tuple.StorageKeys {
+			v.WarmSlot(tuple.Address, new(uint256.Int).SetBytes(key[:]))
+		}
+	}
+	return nil
+}
+
+// gasUsed returns the gas used by the finished transaction after refunds
+func (s *Session) gasUsed(result *TransactionResult) uint64 {
+	used := s.intrinsicGas + s.VM.GasUsed()
+	if result.Err != nil {
+		used = s.Transactions[s.current].Gas
+	}
+	return used - CappedRefund(ForkAt(s.VM.ChainConfig, s.Block), used, result.Refund)
+}
+
+// applyAuthorizations processes an EIP-7702 authorization list, recording why skipped entries were invalid
+func (s *Session) applyAuthorizations(list []SetCodeAuthorization) {
+	if len(list) == 0 {
+		return
+	}
+
+	var chainID *uint256.Int
+	if s.Block != nil {
+		chainID = s.Block.ChainID
+	}
+
+	v := s.VM
+	s.authErrors = make([]error, len(list))
+	for i := range list {
+		authority, designator, err := validateAuthorization(v.StateProvider, chainID, &list[i])
+		if authority != ([20]byte{}) {
+			// The authority is warm once it is recovered, even if the authorization is invalid
+			v.WarmAddress(authority)
+		}
+		if s.authErrors[i] = err; err != nil {
+			continue
+		}
+		if v.StateProvider.AccountExists(authority) {
+			// Only the authorization of a new account costs the full TxAuthorization
+			v.AddRefund(AuthorizationRefundGas)
+			s.authRefund += AuthorizationRefundGas
+		}
+		v.SetCode(authority, designator)
+		v.SetNonce(authority, list[i].Nonce+1)
+	}
+}
+
+func (s *Session) executionFinished() bool {
+	frame := s.VM.currentFrame()
+	return s.VM.Stopped || frame == nil || int(frame.PC) >= len(frame.Code)
+}
+
+// depositCode checks the size of the code returned by the initcode of a contract creation
+// and charges its deposit where gas is metered
+func (s *Session) depositCode() error {
+	v := s.VM
+	fork := ForkAt(v.ChainConfig, s.Block)
+	if fork >= SpuriousDragon && len(v.ReturnValue) > MaxCodeSize {
+		return ErrMaxCodeSizeExceeded
+	}
+	if !s.metered {
+		return nil
+	}
+	if err := v.UseGas(uint64(len(v.ReturnValue)) * v.GasSchedule.CodeDeposit); err != nil {
+		if fork >= Homestead {
+			return ErrCodeStoreOutOfGas
+		}
+		// Before Homestead the contract is created without code
+		v.ReturnValue = nil
+	}
+	return nil
+}
+
+// finish records the result of the current transaction and moves on to the next one
+func (s *Session) finish(err error) {
+	v := s.VM
+	if err == nil && !v.Reverted && s.created != nil {
+		err = s.depositCode()
+	}
+	result := TransactionResult{
+		ReturnValue: v.ReturnValue,
+		Reverted:    v.Reverted,
+		Err:         err,
+
+		AuthorizationErrors: s.authErrors,
+	}
+
+	// A failed transaction only keeps the refund of its authorizations
+	result.Refund = s.authRefund
+	if !result.Failed() {
+		result.Refund = v.Refund()
+	}
+
+	sp := v.StateProvider
+	if s.metered {
+		result.GasUsed = s.gasUsed(&result)
+	}
+	if snap, ok := sp.(Snapshotter); ok && s.snapshot >= 0 {
+		if result.Failed() {
+			snap.RevertToSnapshot(s.snapshot)
+		} else {
+			snap.DiscardSnapshot(s.snapshot)
+		}
+	}
+	if !result.Failed() {
+		// The logs of a failed transaction are dropped with its state changes
+		result.Logs = v.Logs
+		if s.created != nil {
+			v.SetCode(*s.created, v.ReturnValue)
+			result.ContractAddress = s.created
+		}
+		// Self-destructed accounts are deleted once the transaction succeeded
+		if err := v.DeleteScheduled(); err != nil {
+			result.Err = err
+		}
+	}
+
+	exitErr := err
+	if exitErr == nil && result.Reverted {
+		exitErr = ErrExecutionReverted
+	}
+	if s.frame != nil && v.Tracer != nil {
+		// The outermost frame reports the gas used by the transaction where it is metered
+		gasUsed := v.gasUsed - s.frame.gasUsed
+		if s.metered {
+			gasUsed = result.GasUsed
+		}
+		v.Tracer.OnExit(s.frame, v.ReturnValue, gasUsed, exitErr)
+	}
+
+	if rules := v.ChainRules; rules != nil && rules.AfterTransaction != nil {
+		rules.AfterTransaction(s, &s.Transactions[s.current], &result)
+	}
+
+	v.Stopped = true
+	s.Results = append(s.Results, result)
+	s.current++
+	s.started = false
+}
+
+// CreateAddress returns the address of a contract created by sender with the given nonce:
+// keccak256(rlp([sender, nonce]))[12:]
+func CreateAddress(sender [20]byte, nonce uint64) [20]byte {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(rlp.EncodeList(rlp.EncodeBytes(sender[:]), rlp.EncodeUint64(nonce)))
+
+	var addr [20]byte
+	copy(addr[:], hasher.Sum(nil)[12:])
+	return addr
+}
@@ -65,3 +65,13 @@ func (s *Stack) Swap(n int) error {
 	s.data[top], s.data[other] = s.data[other], s.data[top]
 	return nil
 }
+
+// Exchange swaps the items at depth n and n+m, where the top of the stack has depth 0
+func (s *Stack) Exchange(n, m int) error {
+	if n < 1 || m < 1 || n+m >= len(s.data) {
+		return fmt.Errorf("stack underflow on exchange(%d, %d): size=%d", n, m, len(s.data))
+	}
+	top := len(s.data) - 1
+	s.data[top-n], s.data[top-n-m] = s.data[top-n-m], s.data[top-n]
+	return nil
+}
@@ -0,0 +1,220 @@
+package vm
+
+import "github.com/holiman/uint256"
+
+// Tracer receives the events of an execution. Install it by setting DebuggerVM.Tracer and
+// embed NoopTracer to implement only the callbacks of interest.
+//
+// Stack and memory in StepState are the live structures of the frame. Tracers that keep
+// them beyond the callback have to copy them.
+type Tracer interface {
+	// OnStepStart is called before an instruction executes, after its gas was charged
+	OnStepStart(step *StepState)
+
+	// OnStepEnd is called after an instruction executed with the same StepState, err is the
+	// reason the instruction failed
+	OnStepEnd(step *StepState, err error)
+
+	// OnFault is called after OnStepEnd if an instruction halted exceptionally
+	OnFault(step *StepState, err error)
+
+	// OnEnter is called when a transaction, call or contract creation enters a new frame,
+	// including calls to precompiled contracts and accounts without code
+	OnEnter(frame *CallFrame)
+
+	// OnExit is called with the CallFrame of OnEnter when the frame returns. gasUsed
+	// includes the gas used by nested frames, for the outermost frame of a metered Session
+	// transaction it is the gas used by the transaction. err is ErrExecutionReverted if the
+	// frame reverted.
+	OnExit(frame *CallFrame, output []byte, gasUsed uint64, err error)
+
+	OnStorageChange(addr [20]byte, slot, prev, value *uint256.Int)
+	OnTransientStorageChange(addr [20]byte, slot, prev, value *uint256.Int)
+	OnBalanceChange(addr [20]byte, prev, balance *uint256.Int)
+	OnNonceChange(addr [20]byte, prev, nonce uint64)
+	OnCodeChange(addr [20]byte, prev, code []byte)
+	OnLog(log *LogEntry)
+}
+
+// StepState describes an instruction executed by DebuggerVM.Step
+type StepState struct {
+	PC      uint64
+	Op      byte
+	Gas     uint64 // gas left before the instruction
+	Cost    uint64 // gas cost of the instruction, zero if gas is not metered
+	Depth   int    // call depth, the outermost frame has depth 1
+	Refund  uint64 // refund counter before the instruction
+	Address [20]byte
+
+	Stack      *Stack
+	Memory     *Memory
+	ReturnData []byte // return data of the last call
+}
+
+// CallFrame describes a frame entered by a transaction, call or contract creation
+type CallFrame struct {
+	Type  CallType
+	From  [20]byte // the sender of the transaction or the executing account
+	To    [20]byte // the called or created account, the code address for DELEGATECALL and CALLCODE
+	Input []byte
+	Value *uint256.Int // nil if the call does not carry value
+	Gas   uint64       // the transaction gas limit for the outermost frame of a Session
+	Depth int          // call depth of the frame, the outermost frame has depth 1
+
+	gasUsed uint64 // vm.gasUsed when the frame was entered
+}
+
+// NoopTracer implements Tracer with callbacks doing nothing
+type NoopTracer struct{}
+
+func (NoopTracer) OnStepStart(*StepState)                                                      {}
+func (NoopTracer) OnStepEnd(*StepState, error)                                                 {}
+func (NoopTracer) OnFault(*StepState, error)                                                   {}
+func (NoopTracer) OnEnter(*CallFrame)                                                          {}
+func (NoopTracer) OnExit(*CallFrame, []byte, uint64, error)                                    {}
+func (NoopTracer) OnStorageChange([20]byte, *uint256.Int, *uint256.Int, *uint256.Int)          {}
+func (NoopTracer) OnTransientStorageChange([20]byte, *uint256.Int, *uint256.Int, *uint256.Int) {}
+func (NoopTracer) OnBalanceChange([20]byte, *uint256.Int, *uint256.Int)                        {}
+func (NoopTracer) OnNonceChange([20]byte, uint64, uint64)                                      {}
+func (NoopTracer) OnCodeChange([20]byte, []byte, []byte)                                       {}
+func (NoopTracer) OnLog(*LogEntry)                                                             {}
+
+// EnterFrame reports a frame entered by a call or contract creation of the executing
+// account to the tracer. The result is passed to ExitFrame once the frame returns, it is
+// nil without a tracer.
+func (vm *DebuggerVM) EnterFrame(typ CallType, to [20]byte, input []byte, value *uint256.Int, gas uint64) *CallFrame {
+	return vm.enterFrame(vm.CallDepth()+1, typ, vm.contextAddress(), to, input, value, gas)
+}
+
+func (vm *DebuggerVM) enterFrame(depth int, typ CallType, from, to [20]byte, input []byte, value *uint256.Int, gas uint64) *CallFrame {
+	if vm.Tracer == nil {
+		return nil
+	}
+	frame := &CallFrame{
+		Type:    typ,
+		From:    from,
+		To:      to,
+		Input:   input,
+		Gas:     gas,
+		Depth:   depth,
+		gasUsed: vm.gasUsed,
+	}
+	if value != nil {
+		frame.Value = new(uint256.Int).Set(value)
+	}
+	vm.Tracer.OnEnter(frame)
+	return frame
+}
+
+// ExitFrame reports the result of a frame returned by EnterFrame to the tracer
+func (vm *DebuggerVM) ExitFrame(frame *CallFrame, output []byte, err error) {
+	if vm.Tracer == nil || frame == nil {
+		return
+	}
+	vm.Tracer.OnExit(frame, output, vm.gasUsed-frame.gasUsed, err)
+}
+
+// newStepState captures the state before the instruction op at pc executes
+func (vm *DebuggerVM) newStepState(frame *MessageFrame, pc uint64, op byte) *StepState {
+	step := &StepState{
+		PC:         pc,
+		Op:         op,
+		Depth:      vm.CallDepth(),
+		Refund:     vm.refund,
+		Stack:      frame.Stack,
+		Memory:     frame.Memory,
+		ReturnData: vm.ReturnData(),
+	}
+	if vm.Context != nil {
+		step.Gas = vm.Context.Gas
+		step.Address = vm.Context.Address
+	}
+	return step
+}
+
+// contextAddress returns the executing account, the zero address without a context
+func (vm *DebuggerVM) contextAddress() [20]byte {
+	if vm.Context == nil {
+		return [20]byte{}
+	}
+	return vm.Context.Address
+}
+
+// SetBalance sets the balance of addr through the StateProvider and reports the change to
+// the tracer
+func (vm *DebuggerVM) SetBalance(addr [20]byte, balance *uint256.Int) {
+	if vm.Tracer != nil {
+		vm.Tracer.OnBalanceChange(addr, vm.StateProvider.GetBalance(addr), balance)
+	}
+	vm.StateProvider.SetBalance(addr, balance)
+}
+
+// SetNonce sets the nonce of addr through the StateProvider and reports the change to the
+// tracer
+func (vm *DebuggerVM) SetNonce(addr [20]byte, nonce uint64) {
+	if vm.Tracer != nil {
+		vm.Tracer.OnNonceChange(addr, vm.StateProvider.GetNonce(addr), nonce)
+	}
+	vm.StateProvider.SetNonce(addr, nonce)
+}
+
+// SetCode stores code at addr with SetAccountCode and reports the change to the tracer
+func (vm *DebuggerVM) SetCode(addr [20]byte, code []byte) {
+	if vm.Tracer != nil {
+		vm.Tracer.OnCodeChange(addr, vm.StateProvider.GetCode(addr), code)
+	}
+	SetAccountCode(vm.StateProvider, addr, code)
+}
+
+// CreateAccount creates an account through the StateProvider and reports the resulting code
+// and balance changes to the tracer
+func (vm *DebuggerVM) CreateAccount(addr [20]byte, code []byte, balance *uint256.Int) error {
+	sp := vm.StateProvider
+	if vm.Tracer == nil {
+		return sp.CreateAccount(addr, code, balance)
+	}
+
+	prevCode, prevBalance := sp.GetCode(addr), sp.GetBalance(addr)
+	if err := sp.CreateAccount(addr, code, balance); err != nil {
+		return err
+	}
+	if len(prevCode) != 0 || len(code) != 0 {
+		vm.Tracer.OnCodeChange(addr, prevCode, code)
+	}
+	if newBalance := sp.GetBalance(addr); !prevBalance.Eq(newBalance) {
+		vm.Tracer.OnBalanceChange(addr, prevBalance, newBalance)
+	}
+	return nil
+}
+
+// DeleteAccount deletes an account through the StateProvider and reports its balance, nonce
+// and code being cleared to the tracer
+func (vm *DebuggerVM) DeleteAccount(addr [20]byte) error {
+	sp := vm.StateProvider
+	if vm.Tracer == nil {
+		return sp.DeleteAccount(addr)
+	}
+
+	balance, nonce, code := sp.GetBalance(addr), sp.GetNonce(addr), sp.GetCode(addr)
+	if err := sp.DeleteAccount(addr); err != nil {
+		return err
+	}
+	if !balance.IsZero() {
+		vm.Tracer.OnBalanceChange(addr, balance, new(uint256.Int))
+	}
+	if nonce != 0 {
+		vm.Tracer.OnNonceChange(addr, nonce, 0)
+	}
+	if len(code) != 0 {
+		vm.Tracer.OnCodeChange(addr, code, nil)
+	}
+	return nil
+}
+
+// AddLog appends log to the logs of the execution and reports it to the tracer
+func (vm *DebuggerVM) AddLog(log LogEntry) {
+	vm.Logs = append(vm.Logs, log)
+	if vm.Tracer != nil {
+		vm.Tracer.OnLog(&vm.Logs[len(vm.Logs)-1])
+	}
+}
@@ -3,6 +3,7 @@ package vm
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/holiman/uint256"
 )
@@ -16,6 +17,7 @@ var (
 	ErrInvalidJump           = errors.New("invalid jump destination")
 	ErrCallDepthLimit        = errors.New("call depth limit exceeded")
 	ErrStaticCallStateChange = errors.New("state change operation in static call context")
+	ErrExecutionReverted     = errors.New("execution reverted")
 )
 
 type Handler interface {
@@ -23,6 +25,18 @@ type Handler interface {
 }
 type HandlerGetter func(b byte) Handler
 
+// InstructionSets returns the handlers of the instructions available in fork
+type InstructionSets func(fork Fork) HandlerGetter
+
+// defaultInstructionSets are the instruction sets of new VMs, registered by the package
+// implementing the instructions
+var defaultInstructionSets InstructionSets
+
+// RegisterInstructionSets sets the instruction sets new VMs use while a ChainConfig is set
+func RegisterInstructionSets(sets InstructionSets) {
+	defaultInstructionSets = sets
+}
+
 type DebuggerVM struct {
 	// Frame stack for call support
 	frames []MessageFrame
@@ -39,11 +53,46 @@ type DebuggerVM struct {
 	Context       *ExecutionContext
 	HandlerGetter HandlerGetter
 	StateProvider StateProvider
+	ChainConfig   *ChainConfig // selects the active fork, DefaultFork if nil
+	ChainRules    *ChainRules  // chain specific instructions, precompiles, gas and hooks
+	Tracer        Tracer       // receives the execution events, nil disables tracing
+
+	// InstructionSets replaces HandlerGetter with the handlers of the active fork while a
+	// ChainConfig is set, nil keeps HandlerGetter under every fork
+	InstructionSets InstructionSets
+
+	// Precompiled contracts registered with RegisterPrecompile
+	precompiles PrecompiledContracts
+
+	// Calls to precompiled contracts in the current execution
+	precompileCalls []*PrecompileCall
 
 	// Return data from last call
 	lastReturnData []byte
 
 	createdInTransaction map[[20]byte]bool
+	selfDestructed       map[[20]byte]bool
+
+	// Accounts deleted by SELFDESTRUCT at the end of the current transaction
+	pendingDeletions [][20]byte
+
+	// Gas refund counter of the current transaction
+	refund uint64
+
+	// GasSchedule enables gas metering: the cost of every instruction is charged from
+	// Context.Gas before it executes. Without a schedule no gas is charged.
+	GasSchedule *GasSchedule
+
+	// Gas charged in the current transaction
+	gasUsed uint64
+
+	// Gas forwarded by the call instruction being executed, set when it is charged
+	forwardedGas uint64
+
+	accessList      accessList
+	originalStorage map[[20]byte]map[uint256.Int]*uint256.Int
+
+	eofEnabled bool
 }
 
 type LogEntry struct {
@@ -71,6 +120,7 @@ type BlockContext struct {
 	Timestamp   uint64
 	Number      uint64
 	Difficulty  *uint256.Int
+	PrevRandao  *uint256.Int // EIP-4399: Returned by 0x44 instead of Difficulty since the merge
 	GasLimit    uint64
 	ChainID     *uint256.Int
 	BaseFee     *uint256.Int
@@ -91,8 +141,20 @@ const (
 	CallTypeCallCode
 	CallTypeDelegateCall
 	CallTypeStaticCall
+	CallTypeCreate
+	CallTypeCreate2
 )
 
+var callTypeNames = []string{"CALL", "CALLCODE", "DELEGATECALL", "STATICCALL", "CREATE", "CREATE2"}
+
+// String returns the name of the opcode making the call
+func (t CallType) String() string {
+	if t < 0 || int(t) >= len(callTypeNames) {
+		return "UNKNOWN"
+	}
+	return callTypeNames[t]
+}
+
 // MessageFrame represents a single execution frame
 type MessageFrame struct {
 	Code         []byte
@@ -104,6 +166,11 @@ type MessageFrame struct {
 	CallType     CallType
 	IsStatic     bool
 	CodeMetadata *CodeMetadata
+
+	// EOF execution state, EOF is nil for legacy code. Code holds the current code section.
+	EOF         *EOFContainer
+	Section     int
+	ReturnStack []EOFReturn
 }
 
 // CallContext contains information about a call
@@ -131,6 +198,20 @@ type StateProvider interface {
 	DeleteAccount(addr [20]byte) error
 }
 
+// CodeWriter is implemented by state providers that can replace the code of an existing
+// account without touching its storage, nonce or balance
+type CodeWriter interface {
+	SetCode(addr [20]byte, code []byte)
+}
+
+// Snapshotter is implemented by state providers that can roll back state changes. Every
+// snapshot is ended by either RevertToSnapshot or DiscardSnapshot, which keeps the changes.
+type Snapshotter interface {
+	Snapshot() int
+	RevertToSnapshot(id int)
+	DiscardSnapshot(id int)
+}
+
 func NewDebuggerVM(code []byte, hg HandlerGetter) *DebuggerVM {
 	stack := NewStack()
 	memory := NewMemory()
@@ -154,12 +235,35 @@ func NewDebuggerVM(code []byte, hg HandlerGetter) *DebuggerVM {
 		Storage:              make(map[string]*uint256.Int),
 		TransientStorage:     make(map[string]*uint256.Int),
 		HandlerGetter:        hg,
+		InstructionSets:      defaultInstructionSets,
 		createdInTransaction: make(map[[20]byte]bool),
+		selfDestructed:       make(map[[20]byte]bool),
+		accessList:           newAccessList(),
+		originalStorage:      make(map[[20]byte]map[uint256.Int]*uint256.Int),
 	}
 
 	return vm
 }
 
+// ResetExecution replaces the execution frames with a single root frame running code and
+// clears the results of the previous execution. Transaction scoped state such as transient
+// storage is left untouched.
+func (vm *DebuggerVM) ResetExecution(code []byte) {
+	vm.frames = []MessageFrame{{
+		Code:         code,
+		Stack:        NewStack(),
+		Memory:       NewMemory(),
+		CallType:     CallTypeCall,
+		CodeMetadata: scanCodeMetadata(code),
+	}}
+	vm.Stopped = false
+	vm.ReturnValue = nil
+	vm.Reverted = false
+	vm.Logs = nil
+	vm.lastReturnData = nil
+	vm.precompileCalls = nil
+}
+
 func (vm *DebuggerVM) Step() error {
 	frame := vm.currentFrame()
 	if frame == nil {
@@ -171,15 +275,37 @@ func (vm *DebuggerVM) Step() error {
 		return nil
 	}
 
-	op := frame.Code[frame.PC]
+	pc := frame.PC
+	op := frame.Code[pc]
 	frame.PC++
 
-	handler := vm.HandlerGetter(op)
+	var step *StepState
+	if vm.Tracer != nil {
+		step = vm.newStepState(frame, pc, op)
+	}
+	var cost uint64
+	var err error
+	handler := vm.handler(op)
 	if handler == nil {
-		return fmt.Errorf("unsupported opcode: 0x%x", op)
+		err = fmt.Errorf("unsupported opcode: 0x%x", op)
+	} else if vm.GasSchedule != nil {
+		cost, err = vm.chargeGas(op)
 	}
 
-	return handler.Execute(vm)
+	if step != nil {
+		step.Cost = cost
+		vm.Tracer.OnStepStart(step)
+	}
+	if err == nil {
+		err = handler.Execute(vm)
+	}
+	if step != nil {
+		vm.Tracer.OnStepEnd(step, err)
+		if err != nil {
+			vm.Tracer.OnFault(step, err)
+		}
+	}
+	return err
 }
 
 func (vm *DebuggerVM) RunUntil(breakpoints map[uint64]struct{}) error {
@@ -309,7 +435,13 @@ func (vm *DebuggerVM) Push(x *uint256.Int) error {
 	return frame.Stack.Push(x)
 }
 
+// ReadStorage reads a storage slot of the executing account. When a StateProvider and
+// execution context are set, the slot is read from the provider, otherwise from vm.Storage.
 func (vm *DebuggerVM) ReadStorage(slot *uint256.Int) *uint256.Int {
+	if vm.StateProvider != nil && vm.Context != nil {
+		return vm.StateProvider.GetStorage(vm.Context.Address, slot)
+	}
+
 	key := fmt.Sprintf("%064x", slot) // 32-byte hex string
 	val := vm.Storage[key]
 	if val == nil {
@@ -318,7 +450,17 @@ func (vm *DebuggerVM) ReadStorage(slot *uint256.Int) *uint256.Int {
 	return new(uint256.Int).Set(val)
 }
 
+// WriteStorage writes a storage slot of the executing account, see ReadStorage
 func (vm *DebuggerVM) WriteStorage(slot *uint256.Int, value *uint256.Int) {
+	if vm.Tracer != nil {
+		vm.Tracer.OnStorageChange(vm.contextAddress(), new(uint256.Int).Set(slot), vm.ReadStorage(slot), new(uint256.Int).Set(value))
+	}
+
+	if vm.StateProvider != nil && vm.Context != nil {
+		vm.StateProvider.SetStorage(vm.Context.Address, slot, value)
+		return
+	}
+
 	key := fmt.Sprintf("%064x", slot)
 	vm.Storage[key] = new(uint256.Int).Set(value)
 }
@@ -333,6 +475,10 @@ func (vm *DebuggerVM) ReadTransientStorage(slot *uint256.Int) *uint256.Int {
 }
 
 func (vm *DebuggerVM) WriteTransientStorage(slot *uint256.Int, value *uint256.Int) {
+	if vm.Tracer != nil {
+		vm.Tracer.OnTransientStorageChange(vm.contextAddress(), new(uint256.Int).Set(slot), vm.ReadTransientStorage(slot), new(uint256.Int).Set(value))
+	}
+
 	key := fmt.Sprintf("%064x", slot)
 	vm.TransientStorage[key] = new(uint256.Int).Set(value)
 }
@@ -356,16 +502,86 @@ func (vm *DebuggerVM) ClearCreatedInTransaction() {
 	vm.createdInTransaction = make(map[[20]byte]bool)
 }
 
+// MarkSelfDestructed records that addr executed SELFDESTRUCT in the current transaction and
+// reports whether this is the first time
+func (vm *DebuggerVM) MarkSelfDestructed(addr [20]byte) bool {
+	if vm.selfDestructed[addr] {
+		return false
+	}
+	vm.selfDestructed[addr] = true
+	return true
+}
+
+// HasSelfDestructed checks if addr executed SELFDESTRUCT in the current transaction
+func (vm *DebuggerVM) HasSelfDestructed(addr [20]byte) bool {
+	return vm.selfDestructed[addr]
+}
+
+// ClearSelfDestructed clears the SELFDESTRUCT tracking and the pending deletions (for new
+// transactions)
+func (vm *DebuggerVM) ClearSelfDestructed() {
+	vm.selfDestructed = make(map[[20]byte]bool)
+	vm.pendingDeletions = nil
+}
+
+// ScheduleDeletion records that addr is deleted at the end of the current transaction, its
+// code and storage stay readable until then
+func (vm *DebuggerVM) ScheduleDeletion(addr [20]byte) {
+	if !slices.Contains(vm.pendingDeletions, addr) {
+		vm.pendingDeletions = append(vm.pendingDeletions, addr)
+	}
+}
+
+// DeleteScheduled deletes the accounts scheduled for deletion in the current transaction
+func (vm *DebuggerVM) DeleteScheduled() error {
+	for _, addr := range vm.pendingDeletions {
+		if err := vm.DeleteAccount(addr); err != nil {
+			return fmt.Errorf("failed to delete account: %w", err)
+		}
+	}
+	vm.pendingDeletions = nil
+	return nil
+}
+
+// AddRefund adds gas to the refund counter
+func (vm *DebuggerVM) AddRefund(gas uint64) {
+	vm.refund += gas
+}
+
+// SubRefund removes gas from the refund counter, stopping at zero
+func (vm *DebuggerVM) SubRefund(gas uint64) {
+	if gas > vm.refund {
+		vm.refund = 0
+		return
+	}
+	vm.refund -= gas
+}
+
+// Refund returns the refund counter of the current transaction, before the cap is applied
+func (vm *DebuggerVM) Refund() uint64 {
+	return vm.refund
+}
+
+// ClearRefund resets the refund counter (for new transactions)
+func (vm *DebuggerVM) ClearRefund() {
+	vm.refund = 0
+}
+
 func (vm *DebuggerVM) PushBytes(data []byte) error {
 	bi := new(uint256.Int).SetBytes(data)
 	return vm.Push(bi)
 }
 
+// UseGas charges amount from the gas of the current execution context
 func (vm *DebuggerVM) UseGas(amount uint64) error {
+	if vm.Context == nil {
+		return fmt.Errorf("gas metering requires the execution context to be set")
+	}
 	if vm.Context.Gas < amount {
-		return fmt.Errorf("out of gas")
+		return ErrOutOfGas
 	}
 	vm.Context.Gas -= amount
+	vm.gasUsed += amount
 	return nil
 }
 
@@ -471,6 +687,11 @@ func (vm *DebuggerVM) ReturnData() []byte {
 	return vm.lastReturnData
 }
 
+// ClearReturnData empties the return data buffer before a call
+func (vm *DebuggerVM) ClearReturnData() {
+	vm.lastReturnData = nil
+}
+
 // ReturnDataSize returns the size of return data from the last call
 func (vm *DebuggerVM) ReturnDataSize() *uint256.Int {
 	return uint256.NewInt(uint64(len(vm.lastReturnData)))
@@ -478,6 +699,9 @@ func (vm *DebuggerVM) ReturnDataSize() *uint256.Int {
 
 // PushFrame adds a new execution frame (public method for opcodes)
 func (vm *DebuggerVM) PushFrame(frame MessageFrame) error {
+	if err := vm.loadEOFFrame(&frame); err != nil {
+		return err
+	}
 	return vm.pushFrame(frame)
 }
 
@@ -542,15 +766,26 @@ func (vm *DebuggerVM) SetPC(pc uint64) {
 	}
 }
 
-// ExecuteCall executes the current frame until completion or revert
+// ExecuteCall executes the current frame until completion or revert. The output of the
+// frame is stored in its ReturnData, and ErrExecutionReverted is returned if it reverted.
+// The caller's Stopped, ReturnValue and Reverted state is preserved.
 func (vm *DebuggerVM) ExecuteCall() error {
-	// Save the stopped state and reset it for the call execution
+	// Save the caller's result state and reset it for the call execution
 	originalStopped := vm.Stopped
+	originalReturnValue := vm.ReturnValue
+	originalReverted := vm.Reverted
 	vm.Stopped = false
+	vm.ReturnValue = nil
+	vm.Reverted = false
+
+	defer func() {
+		vm.Stopped = originalStopped
+		vm.ReturnValue = originalReturnValue
+		vm.Reverted = originalReverted
+	}()
 
 	frame := vm.currentFrame()
 	if frame == nil {
-		vm.Stopped = originalStopped
 		return fmt.Errorf("no execution frame")
 	}
 
@@ -558,32 +793,30 @@ func (vm *DebuggerVM) ExecuteCall() error {
 	for !vm.Stopped && int(frame.PC) < len(frame.Code) {
 		// Check if we're at a valid PC
 		if _, ok := frame.CodeMetadata.ValidPC[frame.PC]; !ok {
-			vm.Stopped = originalStopped
 			return fmt.Errorf("invalid PC: 0x%x (likely inside PUSH immediate)", frame.PC)
 		}
 
 		err := vm.Step()
 		if err != nil {
-			// Restore original stopped state
-			vm.Stopped = originalStopped
 			return err
 		}
 
 		// Refresh frame reference after step (in case of frame changes)
 		frame = vm.currentFrame()
 		if frame == nil {
-			vm.Stopped = originalStopped
 			return fmt.Errorf("execution frame disappeared")
 		}
 
 		// Check if execution completed normally
 		if frame.PC >= uint64(len(frame.Code)) {
-			vm.Stopped = true
 			break
 		}
 	}
 
-	// Restore original stopped state
-	vm.Stopped = originalStopped
+	// Hand the output of RETURN or REVERT to the caller through the frame's return data
+	frame.ReturnData = vm.ReturnValue
+	if vm.Reverted {
+		return ErrExecutionReverted
+	}
 	return nil
 }